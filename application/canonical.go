@@ -0,0 +1,118 @@
+package application
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// CanonicalOptions selects the optional steps of URL canonicalization.
+// The other steps are always applied, since they never change where a URL leads.
+type CanonicalOptions struct {
+	// SortQuery sorts the query parameters by name. Values of a repeated parameter keep their order.
+	SortQuery bool
+	// StripParams lists query parameters to remove, such as tracking parameters.
+	// Names are matched ignoring case, and "*" matches any characters, e.g. "utm_*".
+	StripParams []string
+	// StripFragment removes the fragment. It is off by default, since some pages route on their fragment.
+	StripFragment bool
+}
+
+// WithCanonicalOptions enables the optional canonicalization steps for every URL stored by the URLService.
+func WithCanonicalOptions(opts CanonicalOptions) Option {
+	return func(s *URLService) {
+		s.canonical = opts
+	}
+}
+
+// Canonicalize returns the canonical form of a URL, so that spellings of the same URL hash and deduplicate alike.
+// It fails unless the URL is absolute and uses http or https, in any case. It trims surrounding spaces, lowercases the scheme and host, converts international host names to punycode,
+// strips the default port of the scheme and drops an empty query or fragment,
+// then applies the optional steps selected in opts.
+func Canonicalize(rawURL string, opts CanonicalOptions) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid URL %q: missing http or https - example: https://www.google.com", rawURL)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid URL %q: missing host", rawURL)
+	}
+
+	host, port := u.Hostname(), u.Port()
+	host = strings.ToLower(host)
+	if net.ParseIP(host) == nil {
+		host, err = idna.Lookup.ToASCII(host)
+		if err != nil {
+			return "", fmt.Errorf("invalid host in URL %q: %w", rawURL, err)
+		}
+	}
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		// IPv6 addresses keep their brackets
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	if u.RawQuery != "" && (opts.SortQuery || len(opts.StripParams) > 0) {
+		u.RawQuery = canonicalQuery(u.RawQuery, opts)
+	}
+	u.ForceQuery = false
+
+	if opts.StripFragment {
+		u.Fragment, u.RawFragment = "", ""
+	}
+	return u.String(), nil
+}
+
+// canonicalQuery removes the stripped parameters from a raw query and sorts it if requested.
+// Parameters are handled in their raw form, so that their encoding is kept as it was sent.
+func canonicalQuery(rawQuery string, opts CanonicalOptions) string {
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(param, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		if isStrippedParam(name, opts.StripParams) {
+			continue
+		}
+		params = append(params, param)
+	}
+
+	if opts.SortQuery {
+		sort.SliceStable(params, func(i, j int) bool {
+			nameI, _, _ := strings.Cut(params[i], "=")
+			nameJ, _, _ := strings.Cut(params[j], "=")
+			return nameI < nameJ
+		})
+	}
+	return strings.Join(params, "&")
+}
+
+// isStrippedParam reports whether a query parameter matches one of the patterns, ignoring case.
+func isStrippedParam(name string, patterns []string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(strings.ToLower(pattern), name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCanonicalize tests that spellings of the same URL get the same canonical form
+func TestCanonicalize(t *testing.T) {
+	tracking := CanonicalOptions{SortQuery: true, StripParams: []string{"utm_*", "fbclid"}}
+
+	tests := []struct {
+		name    string
+		input   string
+		opts    CanonicalOptions
+		want    string
+		wantErr bool
+	}{
+		{name: "already canonical", input: "https://example.com/a?b=1&a=2", want: "https://example.com/a?b=1&a=2"},
+		{name: "uppercase scheme and host", input: "HTTPS://Example.COM/Path", want: "https://example.com/Path"},
+		{name: "default https port", input: "https://example.com:443/a", want: "https://example.com/a"},
+		{name: "default http port", input: "http://example.com:80/a", want: "http://example.com/a"},
+		{name: "other port kept", input: "https://example.com:8443/a", want: "https://example.com:8443/a"},
+		{name: "surrounding spaces", input: "  https://example.com/a \n", want: "https://example.com/a"},
+		{name: "international host", input: "https://Bücher.example/a", want: "https://xn--bcher-kva.example/a"},
+		{name: "IPv6 host", input: "http://[::1]:80/a", want: "http://[::1]/a"},
+		{name: "empty fragment and query", input: "https://example.com/a?#", want: "https://example.com/a"},
+		{name: "fragment kept by default", input: "https://example.com/a#top", want: "https://example.com/a#top"},
+		{name: "fragment stripped", input: "https://example.com/a#top", opts: CanonicalOptions{StripFragment: true}, want: "https://example.com/a"},
+		{name: "query sorted", input: "https://Example.com/a?b=1&a=2", opts: CanonicalOptions{SortQuery: true}, want: "https://example.com/a?a=2&b=1"},
+		{name: "repeated parameter keeps its order", input: "https://example.com/?b=2&a=1&b=1", opts: CanonicalOptions{SortQuery: true}, want: "https://example.com/?a=1&b=2&b=1"},
+		{name: "tracking parameters stripped", input: "https://example.com/a?utm_source=x&id=7&UTM_Medium=y&fbclid=z", opts: tracking, want: "https://example.com/a?id=7"},
+		{name: "only tracking parameters", input: "https://example.com/a?utm_source=x", opts: tracking, want: "https://example.com/a"},
+		{name: "encoding kept", input: "https://example.com/a?q=a%20b&p=x+y", opts: tracking, want: "https://example.com/a?p=x+y&q=a%20b"},
+		{name: "missing host", input: "https:///a", wantErr: true},
+		{name: "missing scheme", input: "example.com/a", wantErr: true},
+		{name: "other scheme", input: "javascript://example.com/%0Aalert(1)", wantErr: true},
+		{name: "unparsable", input: "https://exa mple.com/%zz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonicalize(tt.input, tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// The spellings from the same link all hash alike once the query is sorted
+	first, _ := Canonicalize("https://Example.com/a?b=1&a=2", tracking)
+	second, _ := Canonicalize("https://example.com:443/a?a=2&b=1", tracking)
+	third, _ := Canonicalize("https://example.com/a?a=2&b=1 ", tracking)
+	assert.Equal(t, first, second)
+	assert.Equal(t, first, third)
+}
@@ -0,0 +1,221 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// domainLabelPattern matches a label of a host name, once lowercased.
+var domainLabelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// WithDomains registers custom domains in the given repository, and verifies them with the TXT records of the resolver.
+// The repository is shared by every workspace; each URLService only sees the domains of its own.
+func WithDomains(repo domain.DomainRepository, resolver domain.TXTResolver) Option {
+	return func(s *URLService) {
+		s.domains = repo
+		s.resolver = resolver
+	}
+}
+
+// WithReservedDomains forbids registering the given domains, such as the shared host of the service and the domains
+// of the workspace configuration, which custom domains must never take over. Ports are ignored.
+func WithReservedDomains(names ...string) Option {
+	return func(s *URLService) {
+		s.reserved = make(map[string]bool, len(names))
+		for _, name := range names {
+			if host, _, err := net.SplitHostPort(name); err == nil {
+				name = host
+			}
+			if name != "" {
+				s.reserved[strings.TrimSuffix(strings.ToLower(name), ".")] = true
+			}
+		}
+	}
+}
+
+// AddDomain claims a custom domain for the workspace, unverified, with a new verification token.
+// The claim expires after domain.DomainClaimExpiry, and does not keep other workspaces from claiming the domain:
+// the first to verify it owns it. The domain is lowercased; it fails with domain.ErrInvalidDomain if it is not
+// a host name or is reserved, and with domain.ErrDomainTaken if the workspace already has it.
+func (s *URLService) AddDomain(ctx context.Context, name string) (*domain.CustomDomain, error) {
+	if s.domains == nil {
+		return nil, fmt.Errorf("%w: custom domains are not enabled", domain.ErrInvalidDomain)
+	}
+	name, err := normalizeDomain(name)
+	if err != nil {
+		return nil, err
+	}
+	if s.reserved[name] {
+		return nil, fmt.Errorf("%w: %q is reserved for the service", domain.ErrInvalidDomain, name)
+	}
+	if _, err := s.GetDomain(ctx, name); err == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrDomainTaken, name)
+	} else if !errors.Is(err, domain.ErrDomainNotFound) {
+		return nil, err
+	}
+
+	token := randomHex(16)
+	now := time.Now()
+	expiresAt := now.Add(domain.DomainClaimExpiry)
+	customDomain := domain.CustomDomain{
+		Domain:            name,
+		Workspace:         s.workspace.ID,
+		Token:             token,
+		VerificationHost:  domain.DomainVerificationPrefix + name,
+		VerificationValue: domain.DomainVerificationValue + token,
+		CreatedAt:         now,
+		ExpiresAt:         &expiresAt,
+	}
+	if err := s.domains.SaveDomain(ctx, customDomain); err != nil {
+		return nil, fmt.Errorf("failed to store domain: %w", err)
+	}
+	return &customDomain, nil
+}
+
+// GetDomain retrieves a custom domain of the workspace: one it has verified, or else its claim that has not expired.
+// It fails with domain.ErrDomainNotFound otherwise, including when another workspace owns the domain.
+func (s *URLService) GetDomain(ctx context.Context, name string) (*domain.CustomDomain, error) {
+	if s.domains == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrDomainNotFound, name)
+	}
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	customDomain, err := s.domains.FindDomain(ctx, name)
+	if err == nil && customDomain.Workspace == s.workspace.ID {
+		return customDomain, nil
+	} else if err != nil && !errors.Is(err, domain.ErrDomainNotFound) {
+		return nil, err
+	}
+	claim, err := s.domains.FindClaim(ctx, s.workspace.ID, name)
+	if err != nil {
+		return nil, err
+	}
+	if claimExpired(*claim, time.Now()) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrDomainNotFound, name)
+	}
+	return claim, nil
+}
+
+// ListDomains retrieves the custom domains of the workspace, verified or claimed, sorted by name.
+// Expired claims are left out.
+func (s *URLService) ListDomains(ctx context.Context) ([]domain.CustomDomain, error) {
+	if s.domains == nil {
+		return []domain.CustomDomain{}, nil
+	}
+	all, err := s.domains.ListDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.domains.ListClaims(ctx, s.workspace.ID)
+	if err != nil {
+		return nil, err
+	}
+	domains := make([]domain.CustomDomain, 0, len(claims))
+	for _, customDomain := range all {
+		if customDomain.Workspace == s.workspace.ID {
+			domains = append(domains, customDomain)
+		}
+	}
+	now := time.Now()
+	for _, claim := range claims {
+		if !claimExpired(claim, now) {
+			domains = append(domains, claim)
+		}
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Domain < domains[j].Domain })
+	return domains, nil
+}
+
+// DeleteDomain removes a custom domain of the workspace. The redirects of a verified domain stop at once,
+// and shortened URLs already handed out with the domain no longer work.
+func (s *URLService) DeleteDomain(ctx context.Context, name string) error {
+	customDomain, err := s.GetDomain(ctx, name)
+	if err != nil {
+		return err
+	}
+	if customDomain.Verified {
+		return s.domains.DeleteDomain(ctx, customDomain.Domain)
+	}
+	return s.domains.DeleteClaim(ctx, s.workspace.ID, customDomain.Domain)
+}
+
+// VerifyDomain checks the TXT records of a custom domain of the workspace for its verification value,
+// and marks the domain verified when one holds it. The workspace then owns the domain, taking it over from any workspace
+// that verified it before, since it now controls its DNS. A verified domain stays verified.
+// It fails with domain.ErrDomainNotVerified when no record holds the value, or the records cannot be looked up.
+func (s *URLService) VerifyDomain(ctx context.Context, name string) (*domain.CustomDomain, error) {
+	customDomain, err := s.GetDomain(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if customDomain.Verified {
+		return customDomain, nil
+	}
+
+	records, err := s.resolver.LookupTXT(ctx, customDomain.VerificationHost)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to look up the TXT records of %s: %v", domain.ErrDomainNotVerified, customDomain.VerificationHost, err)
+	}
+	for _, record := range records {
+		if strings.TrimSpace(record) == customDomain.VerificationValue {
+			now := time.Now()
+			customDomain.Verified, customDomain.VerifiedAt, customDomain.ExpiresAt = true, &now, nil
+			if err := s.domains.SaveDomain(ctx, *customDomain); err != nil {
+				return nil, fmt.Errorf("failed to store domain: %w", err)
+			}
+			return customDomain, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no TXT record of %s holds %q", domain.ErrDomainNotVerified, customDomain.VerificationHost, customDomain.VerificationValue)
+}
+
+// claimExpired reports whether an unverified custom domain has expired.
+func claimExpired(claim domain.CustomDomain, now time.Time) bool {
+	return claim.ExpiresAt != nil && !now.Before(*claim.ExpiresAt)
+}
+
+// PublicDomain returns the domain of the workspace's shortened URLs: the domain of the workspace configuration if it has one,
+// otherwise its custom domain verified first. It returns an empty string when the workspace has neither, which only
+// the default workspace can, and its shortened URLs use the shared host.
+func (s *URLService) PublicDomain(ctx context.Context) (string, error) {
+	if s.workspace.Domain != "" {
+		return s.workspace.Domain, nil
+	}
+	domains, err := s.ListDomains(ctx)
+	if err != nil {
+		return "", err
+	}
+	var first *domain.CustomDomain
+	for i, customDomain := range domains {
+		if customDomain.Verified && (first == nil || customDomain.VerifiedAt.Before(*first.VerifiedAt)) {
+			first = &domains[i]
+		}
+	}
+	if first == nil {
+		return "", nil
+	}
+	return first.Domain, nil
+}
+
+// normalizeDomain lowercases a domain and removes its trailing dot.
+// It fails with domain.ErrInvalidDomain unless the domain is a host name of at least two labels, without a port.
+func normalizeDomain(name string) (string, error) {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	labels := strings.Split(name, ".")
+	if len(name) > 253 || len(labels) < 2 {
+		return "", fmt.Errorf("%w: %q is not a host name such as go.example.com", domain.ErrInvalidDomain, name)
+	}
+	for _, label := range labels {
+		if !domainLabelPattern.MatchString(label) {
+			return "", fmt.Errorf("%w: %q is not a host name such as go.example.com", domain.ErrInvalidDomain, name)
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,205 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// MaxGroupNameLength is the length of the name of a group, in characters.
+const MaxGroupNameLength = 100
+
+// groupIDPattern matches the ID of a group, once lowercased.
+var groupIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// WithGroups stores link groups in the given repository, so that links can be managed by group.
+func WithGroups(repo domain.GroupRepository) Option {
+	return func(s *URLService) {
+		s.groups = repo
+	}
+}
+
+// SaveGroup creates a group, or updates the name and description of an existing one.
+// The ID is lowercased, and the name defaults to the ID.
+func (s *URLService) SaveGroup(ctx context.Context, id string, req domain.SaveGroupRequest) (*domain.Group, error) {
+	if s.groups == nil {
+		return nil, fmt.Errorf("%w: groups are not enabled", domain.ErrInvalidGroup)
+	}
+	id = normalizeGroupID(id)
+	if !groupIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("%w: invalid group ID %q - use up to 64 letters, digits, \"_\", \"-\" and \".\"", domain.ErrInvalidGroup, id)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = id
+	}
+	if len([]rune(name)) > MaxGroupNameLength {
+		return nil, fmt.Errorf("%w: the name is longer than %d characters", domain.ErrInvalidGroup, MaxGroupNameLength)
+	}
+
+	now := time.Now()
+	group := domain.Group{ID: id, Name: name, Description: strings.TrimSpace(req.Description), CreatedAt: now, UpdatedAt: now}
+	existing, err := s.groups.FindGroup(ctx, id)
+	if err == nil {
+		group.CreatedAt = existing.CreatedAt
+	} else if !errors.Is(err, domain.ErrGroupNotFound) {
+		return nil, fmt.Errorf("failed to find group: %w", err)
+	}
+	if err := s.groups.SaveGroup(ctx, group); err != nil {
+		return nil, fmt.Errorf("failed to store group: %w", err)
+	}
+	return &group, nil
+}
+
+// GetGroup retrieves a group by its ID, and fails with domain.ErrGroupNotFound if it does not exist.
+func (s *URLService) GetGroup(ctx context.Context, id string) (*domain.Group, error) {
+	if s.groups == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrGroupNotFound, id)
+	}
+	return s.groups.FindGroup(ctx, normalizeGroupID(id))
+}
+
+// ListGroups retrieves all groups, sorted by ID.
+func (s *URLService) ListGroups(ctx context.Context) ([]domain.Group, error) {
+	if s.groups == nil {
+		return []domain.Group{}, nil
+	}
+	groups, err := s.groups.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	return groups, nil
+}
+
+// DeleteGroup removes a group. Its links are kept, and removed from the group first.
+func (s *URLService) DeleteGroup(ctx context.Context, id string) error {
+	links, err := s.GroupLinks(ctx, id)
+	if err != nil {
+		return err
+	}
+	ungrouped := ""
+	for _, link := range links {
+		_, err := s.UpdateURL(ctx, link.ShortCode, domain.UpdateURLRequest{Group: &ungrouped})
+		if err != nil && !errors.Is(err, domain.ErrURLNotFound) {
+			return err
+		}
+	}
+	return s.groups.DeleteGroup(ctx, normalizeGroupID(id))
+}
+
+// GroupLinks retrieves the live links of a group, sorted by expiry like ListURLs.
+// It fails with domain.ErrGroupNotFound if the group does not exist.
+func (s *URLService) GroupLinks(ctx context.Context, id string) ([]domain.URL, error) {
+	group, err := s.GetGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	links, err := s.groupIndex.FindByGroup(ctx, group.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the links of the group: %w", err)
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].Expiry.Before(links[j].Expiry) })
+	return links, nil
+}
+
+// GetGroupStats adds up the clicks on the live links of a group, in total and per country.
+// Links that expired no longer count.
+func (s *URLService) GetGroupStats(ctx context.Context, id string) (*domain.GroupStats, error) {
+	group, err := s.GetGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	links, err := s.GroupLinks(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &domain.GroupStats{Group: *group, Links: len(links), PerLink: []domain.GroupLinkStats{}}
+	for _, link := range links {
+		clicks, err := s.clicks.GetClicks(ctx, link.ShortCode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get clicks: %w", err)
+		}
+		countries, err := s.clicks.GetClickCountries(ctx, link.ShortCode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get clicks per country: %w", err)
+		}
+		for country, count := range countries {
+			if stats.Countries == nil {
+				stats.Countries = make(map[string]int64)
+			}
+			stats.Countries[country] += count
+		}
+		stats.Clicks += clicks
+		stats.PerLink = append(stats.PerLink, domain.GroupLinkStats{ShortCode: link.ShortCode, OriginalURL: link.OriginalURL, Expiry: link.Expiry, Clicks: clicks})
+	}
+	return stats, nil
+}
+
+// ExpireGroup makes every link of a group expire at once, and returns the number of links expired.
+// The links are reported as expired by the next SweepExpired, like links that reached their expiry.
+func (s *URLService) ExpireGroup(ctx context.Context, id string) (int, error) {
+	links, err := s.GroupLinks(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	for _, link := range links {
+		err := s.repo.Expire(ctx, link.ShortCode)
+		if errors.Is(err, domain.ErrURLNotFound) {
+			continue
+		} else if err != nil {
+			return expired, fmt.Errorf("failed to expire %s: %w", link.ShortCode, err)
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// RenewGroup moves the expiry of every link of a group to a new time, and returns the number of links renewed.
+func (s *URLService) RenewGroup(ctx context.Context, id string, expiry time.Time) (int, error) {
+	if !expiry.After(time.Now()) {
+		return 0, fmt.Errorf("invalid expiry %s: must be in the future", expiry.Format(time.RFC3339))
+	}
+	links, err := s.GroupLinks(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	renewed := 0
+	for _, link := range links {
+		_, err := s.RenewURL(ctx, link.ShortCode, expiry)
+		if errors.Is(err, domain.ErrURLNotFound) {
+			continue
+		} else if err != nil {
+			return renewed, err
+		}
+		renewed++
+	}
+	return renewed, nil
+}
+
+// checkGroup normalizes the group a link is assigned to, and fails with domain.ErrGroupNotFound if it does not exist.
+// An empty group leaves the link out of any group.
+func (s *URLService) checkGroup(ctx context.Context, id string) (string, error) {
+	id = normalizeGroupID(id)
+	if id == "" {
+		return "", nil
+	}
+	group, err := s.GetGroup(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return group.ID, nil
+}
+
+// normalizeGroupID trims and lowercases the ID of a group.
+func normalizeGroupID(id string) string {
+	return strings.ToLower(strings.TrimSpace(id))
+}
@@ -0,0 +1,21 @@
+package application
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// newID generates a random 128-bit identifier encoded as a hex string.
+func newID() string {
+	return randomHex(16)
+}
+
+// randomHex returns n random bytes encoded as a hex string.
+// It panics if the system's secure random number generator fails, as there is no safe fallback.
+func randomHex(n int) string {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,80 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// PageMetadataWorker fetches the metadata of the target pages of new links in the background
+// and stores it with the links, so that creating a link never waits for its target.
+// Links are queued in memory: when the queue is full, or the process stops, their pages are not fetched.
+type PageMetadataWorker struct {
+	repo    domain.URLRepository
+	fetcher domain.PageFetcher
+	queue   chan domain.URL
+}
+
+// NewPageMetadataWorker creates a new instance of PageMetadataWorker that holds at most queueSize waiting links.
+func NewPageMetadataWorker(repo domain.URLRepository, fetcher domain.PageFetcher, queueSize int) *PageMetadataWorker {
+	return &PageMetadataWorker{repo: repo, fetcher: fetcher, queue: make(chan domain.URL, queueSize)}
+}
+
+// WithPageMetadataWorker makes the URLService queue every new link, and every link whose original URL changed,
+// to have the metadata of its target page fetched by the worker.
+func WithPageMetadataWorker(worker *PageMetadataWorker) Option {
+	return func(s *URLService) {
+		s.pages = worker
+	}
+}
+
+// Enqueue queues a link to have its target page fetched, without blocking.
+// It reports whether the link was queued; it is not when the queue is full.
+func (w *PageMetadataWorker) Enqueue(url domain.URL) bool {
+	select {
+	case w.queue <- url:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run fetches the pages of the queued links with the given number of concurrent workers,
+// until the context is cancelled.
+func (w *PageMetadataWorker) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case url := <-w.queue:
+					w.process(ctx, url)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// process fetches the target page of a link and stores its metadata.
+// Failures are only logged: the link works without its page metadata.
+func (w *PageMetadataWorker) process(ctx context.Context, url domain.URL) {
+	page, err := w.fetcher.Fetch(ctx, url.OriginalURL)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error fetching the page metadata of %s: %v", url.ShortCode, err)
+		}
+		return
+	}
+	err = w.repo.SetPageMetadata(ctx, url.ShortCode, url.OriginalURL, *page)
+	if err != nil && !errors.Is(err, domain.ErrURLNotFound) {
+		log.Printf("Error storing the page metadata of %s: %v", url.ShortCode, err)
+	}
+}
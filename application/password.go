@@ -0,0 +1,82 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxPasswordLength is the longest password accepted for a link, in bytes. bcrypt ignores anything longer.
+const MaxPasswordLength = 72
+
+// passwordThrottle limits the wrong passwords a client can enter for a link.
+type passwordThrottle struct {
+	counter     domain.AttemptCounter
+	maxFailures int64
+	window      time.Duration
+}
+
+// WithPasswordThrottle makes UnlockURL refuse a client's attempts at a link's password with domain.ErrTooManyAttempts
+// once it entered maxFailures wrong passwords, until the window that started with its first wrong password is over.
+// Without it, attempts are only slowed down by the cost of bcrypt.
+func WithPasswordThrottle(counter domain.AttemptCounter, maxFailures int, window time.Duration) Option {
+	return func(s *URLService) {
+		s.throttle = &passwordThrottle{counter: counter, maxFailures: int64(maxFailures), window: window}
+	}
+}
+
+// HashPassword returns the bcrypt hash stored for the password of a link.
+func HashPassword(password string) (string, error) {
+	if len(password) > MaxPasswordLength {
+		return "", fmt.Errorf("password is longer than %d bytes", MaxPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// UnlockURL checks the password entered by a client for the given short code, and returns the URL when it matches.
+// Public links are returned whatever the password. It fails with domain.ErrWrongPassword when the password does not match,
+// and with domain.ErrTooManyAttempts when the client entered too many wrong ones; client identifies it, e.g. by address.
+// Every attempt is counted as a failure before the password is checked, so that attempts sent at the same time cannot
+// all pass the throttle, and the count is reset when the password matches. Unlocking does not count a click.
+func (s *URLService) UnlockURL(ctx context.Context, shortCode, password, client string) (*domain.URL, error) {
+	url, err := s.findURL(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if !url.Protected() {
+		return url, nil
+	}
+
+	key := fmt.Sprintf("password:%s:%s", shortCode, client)
+	if s.throttle != nil {
+		failures, err := s.throttle.counter.AddFailure(ctx, key, s.throttle.window)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count password attempts: %w", err)
+		}
+		if failures > s.throttle.maxFailures {
+			return nil, domain.ErrTooManyAttempts
+		}
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return nil, domain.ErrWrongPassword
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to check password: %w", err)
+	}
+
+	if s.throttle != nil {
+		if err := s.throttle.counter.Reset(ctx, key); err != nil {
+			return nil, fmt.Errorf("failed to reset password attempts: %w", err)
+		}
+	}
+	return url, nil
+}
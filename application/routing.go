@@ -0,0 +1,140 @@
+package application
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// MaxRoutingRules is the number of routing rules a link can have. Every redirect evaluates them in order.
+const MaxRoutingRules = 20
+
+// MaxVariants is the number of variants a link can split its traffic between, and MaxVariantWeight the weight of each.
+const (
+	MaxVariants      = 10
+	MaxVariantWeight = 1000
+)
+
+// knownOS and knownDevices are the values routing rules can match on.
+var (
+	knownOS = map[string]bool{
+		domain.OSIOS: true, domain.OSAndroid: true, domain.OSWindows: true, domain.OSMacOS: true,
+		domain.OSLinux: true, domain.OSChromeOS: true, domain.OSOther: true,
+	}
+	knownDevices = map[string]bool{domain.DeviceMobile: true, domain.DeviceTablet: true, domain.DeviceDesktop: true}
+)
+
+// forwardQueryPolicies are the policies for forwarding query parameters.
+var forwardQueryPolicies = map[string]bool{
+	domain.ForwardQueryPreserve: true, domain.ForwardQueryOverride: true, domain.ForwardQueryAppend: true,
+}
+
+// redirectTypes are the redirect types a link can have.
+var redirectTypes = map[string]bool{
+	domain.RedirectMovedPermanently: true, domain.RedirectFound: true, domain.RedirectTemporary: true,
+	domain.RedirectPermanent: true, domain.RedirectInterstitial: true,
+}
+
+// countryPattern matches an ISO 3166-1 alpha-2 country code, once uppercased.
+var countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// normalizeRules lowercases the criteria of routing rules, uppercases their countries, and canonicalizes their destinations like original URLs.
+// It fails with domain.ErrInvalidRule for a rule with an unknown criterion, without any criterion, or with a destination
+// that is not an absolute http or https URL, and when there are more than MaxRoutingRules.
+func (s *URLService) normalizeRules(rules []domain.RoutingRule) ([]domain.RoutingRule, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	if len(rules) > MaxRoutingRules {
+		return nil, fmt.Errorf("%w: more than %d rules", domain.ErrInvalidRule, MaxRoutingRules)
+	}
+	normalized := make([]domain.RoutingRule, len(rules))
+	for i, rule := range rules {
+		rule.OS = strings.ToLower(strings.TrimSpace(rule.OS))
+		rule.Device = strings.ToLower(strings.TrimSpace(rule.Device))
+		countries := make([]string, 0, len(rule.Countries))
+		for _, country := range rule.Countries {
+			country = strings.ToUpper(strings.TrimSpace(country))
+			if !countryPattern.MatchString(country) {
+				return nil, fmt.Errorf("%w: rule %d has invalid country %q - use ISO 3166-1 alpha-2 codes like DE", domain.ErrInvalidRule, i+1, country)
+			}
+			countries = append(countries, country)
+		}
+		rule.Countries = nil
+		if len(countries) > 0 {
+			rule.Countries = countries
+		}
+		switch {
+		case rule.OS == "" && rule.Device == "" && rule.Bot == nil && rule.Countries == nil:
+			return nil, fmt.Errorf("%w: rule %d matches every visitor - set os, device, bot or countries", domain.ErrInvalidRule, i+1)
+		case rule.OS != "" && !knownOS[rule.OS]:
+			return nil, fmt.Errorf("%w: rule %d has unknown os %q", domain.ErrInvalidRule, i+1, rule.OS)
+		case rule.Device != "" && !knownDevices[rule.Device]:
+			return nil, fmt.Errorf("%w: rule %d has unknown device %q", domain.ErrInvalidRule, i+1, rule.Device)
+		}
+		destination, err := Canonicalize(rule.URL, s.canonical)
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %d: %v", domain.ErrInvalidRule, i+1, err)
+		}
+		rule.URL = destination
+		normalized[i] = rule
+	}
+	return normalized, nil
+}
+
+// normalizeVariants canonicalizes the destinations of the variants of a link like original URLs.
+// It fails with domain.ErrInvalidVariants unless there are 2 to MaxVariants variants with distinct absolute http or https URLs,
+// weights from 0 to MaxVariantWeight, and at least one weight above 0.
+func (s *URLService) normalizeVariants(variants []domain.Variant) ([]domain.Variant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	if len(variants) < 2 || len(variants) > MaxVariants {
+		return nil, fmt.Errorf("%w: a link has 2 to %d variants", domain.ErrInvalidVariants, MaxVariants)
+	}
+	normalized := make([]domain.Variant, len(variants))
+	seen := make(map[string]bool, len(variants))
+	total := 0
+	for i, variant := range variants {
+		if variant.Weight < 0 || variant.Weight > MaxVariantWeight {
+			return nil, fmt.Errorf("%w: variant %d has a weight outside 0 to %d", domain.ErrInvalidVariants, i+1, MaxVariantWeight)
+		}
+		destination, err := Canonicalize(variant.URL, s.canonical)
+		if err != nil {
+			return nil, fmt.Errorf("%w: variant %d: %v", domain.ErrInvalidVariants, i+1, err)
+		}
+		// The clicks of the variants are counted by URL
+		if seen[destination] {
+			return nil, fmt.Errorf("%w: variant %d repeats %s", domain.ErrInvalidVariants, i+1, destination)
+		}
+		seen[destination] = true
+		total += variant.Weight
+		normalized[i] = domain.Variant{URL: destination, Weight: variant.Weight}
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%w: every variant has a weight of 0", domain.ErrInvalidVariants)
+	}
+	return normalized, nil
+}
+
+// normalizeForwardQuery lowercases a query forwarding policy, and fails with domain.ErrInvalidPassthrough if it is not a known one.
+// An empty policy stays empty, since it does not forward the query parameters.
+func normalizeForwardQuery(policy string) (string, error) {
+	policy = strings.ToLower(strings.TrimSpace(policy))
+	if policy != "" && !forwardQueryPolicies[policy] {
+		return "", fmt.Errorf("%w: unknown query forwarding %q - use preserve, override or append", domain.ErrInvalidPassthrough, policy)
+	}
+	return policy, nil
+}
+
+// NormalizeRedirectType lowercases a redirect type, and fails with domain.ErrInvalidRedirectType if it is not a known one.
+// An empty type stays empty, since it stands for the default of the server.
+func NormalizeRedirectType(redirectType string) (string, error) {
+	redirectType = strings.ToLower(strings.TrimSpace(redirectType))
+	if redirectType != "" && !redirectTypes[redirectType] {
+		return "", fmt.Errorf("%w: %q - use 301, 302, 307, 308 or interstitial", domain.ErrInvalidRedirectType, redirectType)
+	}
+	return redirectType, nil
+}
@@ -3,8 +3,14 @@ package application
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"log"
 	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/terenzio/URL-Shortening-Service/domain"
 )
@@ -13,40 +19,629 @@ import (
 // It is used to encode the SHA-256 hash of the URL into a short code.
 const base62Characters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
 
+// DefaultExpiry is how long a shortened URL lives when no valid expiry is given.
+const DefaultExpiry = 30 * 24 * time.Hour
+
 type URLService struct {
-	repo domain.URLRepository
+	repo         domain.URLRepository
+	clicks       domain.ClickCounter
+	groupIndex   domain.GroupIndex
+	destinations domain.DestinationIndex
+	publisher    domain.EventPublisher
+	deduplicate  bool
+	canonical    CanonicalOptions
+	pages        *PageMetadataWorker
+	throttle     *passwordThrottle
+	utmPresets   domain.UTMPresetRepository
+	groups       domain.GroupRepository
+	workspace    domain.Workspace
+	domains      domain.DomainRepository
+	resolver     domain.TXTResolver
+	reserved     map[string]bool
+}
+
+// Option configures optional behaviour of the URLService.
+type Option func(*URLService)
+
+// WithEventPublisher makes the URLService publish link lifecycle events to the given publisher.
+func WithEventPublisher(publisher domain.EventPublisher) Option {
+	return func(s *URLService) {
+		s.publisher = publisher
+	}
+}
+
+// WithDeduplication makes CreateURL return the owner's existing live link when the same original URL is shortened again.
+func WithDeduplication() Option {
+	return func(s *URLService) {
+		s.deduplicate = true
+	}
+}
+
+// WithClickCounter counts the clicks of the links with the given counter, instead of the URL repository.
+func WithClickCounter(clicks domain.ClickCounter) Option {
+	return func(s *URLService) {
+		s.clicks = clicks
+	}
+}
+
+// WithGroupIndex finds the links of a group with the given index, instead of the URL repository.
+func WithGroupIndex(index domain.GroupIndex) Option {
+	return func(s *URLService) {
+		s.groupIndex = index
+	}
+}
+
+// WithDestinationIndex finds the links to deduplicate with the given index, instead of the URL repository.
+func WithDestinationIndex(index domain.DestinationIndex) Option {
+	return func(s *URLService) {
+		s.destinations = index
+	}
 }
 
 // NewURLService creates a new instance of URLService.
-func NewURLService(repo domain.URLRepository) *URLService {
-	return &URLService{repo: repo}
+// The clicks and the indexes of the links are kept by the URL repository when it implements domain.ClickCounter,
+// domain.GroupIndex and domain.DestinationIndex, as the Redis repository does; a repository wrapped in decorators
+// that only store the links needs WithClickCounter, WithGroupIndex and WithDestinationIndex.
+// It panics when the clicks cannot be counted, or the index needed by WithGroups or WithDeduplication is missing,
+// since every redirect, group or deduplicated link would otherwise fail.
+func NewURLService(repo domain.URLRepository, opts ...Option) *URLService {
+	s := &URLService{repo: repo}
+	s.clicks, _ = repo.(domain.ClickCounter)
+	s.groupIndex, _ = repo.(domain.GroupIndex)
+	s.destinations, _ = repo.(domain.DestinationIndex)
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.clicks == nil {
+		panic("application: the URL repository does not count clicks; use WithClickCounter")
+	}
+	if s.groups != nil && s.groupIndex == nil {
+		panic("application: the URL repository does not index groups; use WithGroupIndex")
+	}
+	if s.deduplicate && s.destinations == nil {
+		panic("application: the URL repository does not index destinations; use WithDestinationIndex")
+	}
+	return s
+}
+
+// AdjustExpiry returns the expiry time to use for a new shortened URL.
+// If the expiry time is not set, or is not in the future, it defaults to DefaultExpiry from now.
+func AdjustExpiry(expiry time.Time) time.Time {
+	now := time.Now()
+	if expiry.IsZero() || !expiry.After(now) {
+		return now.Add(DefaultExpiry)
+	}
+	return expiry
+}
+
+// CreateURL creates and stores a new shortened URL for the request, built with newURL.
+// The custom short code is used if it is set, and fails with domain.ErrShortCodeTaken if it is not unique;
+// otherwise a short code is generated.
+// With deduplication, a request without a custom short code or ForceNew returns the owner's existing live link
+// to the same original URL instead, with its own expiry.
+// Restricted links are never deduplicated. Neither are links added to a group.
+// New links fail with domain.ErrQuotaExceeded once the workspace holds its maximum number of links.
+func (s *URLService) CreateURL(ctx context.Context, req domain.AddURLRequest) (*domain.URL, error) {
+	url, err := s.newURL(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Restricted links are never shared, since the existing link would not have the requested restrictions
+	if s.deduplicate && url.ShortCode == "" && !req.ForceNew && !restricted(url) && url.Group == "" {
+		existing, err := s.destinations.FindByDestination(ctx, req.Owner, url.OriginalURL)
+		if err == nil && !restricted(*existing) {
+			return existing, nil
+		} else if err != nil && !errors.Is(err, domain.ErrURLNotFound) {
+			return nil, fmt.Errorf("failed to find URL by destination: %w", err)
+		}
+	}
+
+	if remaining, err := s.remainingLinks(ctx); err != nil {
+		return nil, err
+	} else if remaining == 0 {
+		return nil, s.quotaError()
+	}
+
+	// The uniqueness checks are only a fast pre-check: another instance may take the short code before it is written,
+	// which StoreURL detects. A generated short code is then generated again.
+	if url.ShortCode != "" {
+		if !s.repo.IsUnique(ctx, url.ShortCode) {
+			return nil, fmt.Errorf("%w: %s", domain.ErrShortCodeTaken, url.ShortCode)
+		}
+		if _, err := s.StoreURL(ctx, url); err != nil {
+			return nil, err
+		}
+	} else {
+		for sequence := 1; ; sequence++ {
+			url.ShortCode, sequence = s.uniqueShortCode(ctx, url.OriginalURL, sequence)
+			_, err := s.StoreURL(ctx, url)
+			if err == nil {
+				break
+			} else if !errors.Is(err, domain.ErrShortCodeTaken) {
+				return nil, err
+			}
+		}
+	}
+	// The first link to a destination stays the one returned to repeat submissions
+	if s.deduplicate && !restricted(url) && url.Group == "" {
+		if err := s.destinations.IndexDestination(ctx, req.Owner, url); err != nil {
+			log.Printf("Error indexing the destination of %s: %v", url.ShortCode, err)
+		}
+	}
+	return &url, nil
+}
+
+// newURL builds the link requested by an add request, without a generated short code, and without storing it.
+// The metadata, password and click limit must be within their bounds, failing with domain.ErrInvalidLink otherwise,
+// and the custom short code must pass CheckShortCode. The original URL is canonicalized and fails with domain.ErrInvalidURL
+// if it cannot be, or does not use http or https; the UTM parameters of the request are added to it afterwards with withUTM.
+// The expiry is adjusted with AdjustExpiry, the schedule, routing rules, variants, forwarding and redirect type are validated,
+// the group must exist, and the password is hashed.
+func (s *URLService) newURL(ctx context.Context, req domain.AddURLRequest) (domain.URL, error) {
+	if err := checkAddRequest(req); err != nil {
+		return domain.URL{}, err
+	}
+	if req.CustomShortCode != "" {
+		if err := CheckShortCode(req.CustomShortCode); err != nil {
+			return domain.URL{}, err
+		}
+	}
+	utm, err := s.requestUTM(ctx, req)
+	if err != nil {
+		return domain.URL{}, err
+	}
+	now := time.Now()
+	url := domain.URL{
+		ShortCode:   req.CustomShortCode,
+		OriginalURL: req.OriginalURL,
+		Expiry:      AdjustExpiry(req.Expiry),
+		Title:       strings.TrimSpace(req.Title),
+		Description: strings.TrimSpace(req.Description),
+		Tags:        NormalizeTags(req.Tags),
+		Notes:       req.Notes,
+		CreatedBy:   strings.TrimSpace(req.CreatedBy),
+		MaxClicks:   req.MaxClicks,
+		ActivatesAt: activationTime(req.ActivatesAt, now),
+		ForwardPath: req.ForwardPath,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.canonicalize(&url); err != nil {
+		return domain.URL{}, err
+	}
+	if url.OriginalURL, err = s.withUTM(url.OriginalURL, utm); err != nil {
+		return domain.URL{}, err
+	}
+	if err := checkSchedule(url); err != nil {
+		return domain.URL{}, err
+	}
+	rules, err := s.normalizeRules(req.Rules)
+	if err != nil {
+		return domain.URL{}, err
+	}
+	url.Rules = rules
+	variants, err := s.normalizeVariants(req.Variants)
+	if err != nil {
+		return domain.URL{}, err
+	}
+	url.Variants = variants
+	if url.ForwardQuery, err = normalizeForwardQuery(req.ForwardQuery); err != nil {
+		return domain.URL{}, err
+	}
+	if url.RedirectType, err = NormalizeRedirectType(req.RedirectType); err != nil {
+		return domain.URL{}, err
+	}
+	if url.Group, err = s.checkGroup(ctx, req.Group); err != nil {
+		return domain.URL{}, err
+	}
+	if req.Password != "" {
+		hash, err := HashPassword(req.Password)
+		if err != nil {
+			return domain.URL{}, err
+		}
+		url.PasswordHash = hash
+	}
+	return url, nil
+}
+
+// restricted reports whether a link is password protected, limited in clicks, scheduled, routed, split, forwarding
+// or redirecting in its own way, which keeps it out of deduplication.
+func restricted(url domain.URL) bool {
+	return url.Protected() || url.MaxClicks > 0 || !url.ActivatesAt.IsZero() || len(url.Rules) > 0 || len(url.Variants) > 0 ||
+		url.Forwards() || url.RedirectType != ""
+}
+
+// checkSchedule fails with domain.ErrInvalidSchedule if the link would activate at or after its expiry.
+func checkSchedule(url domain.URL) error {
+	if !url.ActivatesAt.IsZero() && !url.ActivatesAt.Before(url.Expiry) {
+		return fmt.Errorf("%w: %s", domain.ErrInvalidSchedule, url.ActivatesAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// activationTime returns the activation time to store for a link: times that are not in the future are dropped,
+// since the link is active at once.
+func activationTime(activatesAt, now time.Time) time.Time {
+	if !activatesAt.After(now) {
+		return time.Time{}
+	}
+	return activatesAt
+}
+
+// fetchPage queues a link without page metadata to have its target page fetched, if a worker is configured.
+func (s *URLService) fetchPage(url domain.URL) {
+	if s.pages == nil || url.Page != nil {
+		return
+	}
+	if !s.pages.Enqueue(url) {
+		log.Printf("Page metadata queue is full, not fetching the page of %s", url.ShortCode)
+	}
 }
 
 // ShortenURL generates a unique short code for the given URL and stores it in the repository.
 func (s *URLService) ShortenURL(ctx context.Context, originalURL string) (string, error) {
-	sequence := 1
-	var shortCode string
-	for {
-		// Generate a unique short code
-		shortCode = generateShortCode(originalURL, sequence)
-		// Check if the short code is unique
+	shortCode, _ := s.uniqueShortCode(ctx, originalURL, 1)
+	return shortCode, nil
+}
+
+// uniqueShortCode generates short codes for the given URL from the given sequence number on,
+// and returns the first one the repository considers unique with its sequence number.
+func (s *URLService) uniqueShortCode(ctx context.Context, originalURL string, sequence int) (string, int) {
+	for ; ; sequence++ {
+		shortCode := generateShortCode(originalURL, sequence)
 		if s.repo.IsUnique(ctx, shortCode) {
-			break
+			return shortCode, sequence
 		}
-		sequence++
 	}
-
-	return shortCode, nil
 }
 
-// StoreURL stores the given URL in the repository.
+// StoreURL stores the given URL in the repository as a new link.
+// It fails with domain.ErrShortCodeTaken if the short code exists, and never overwrites it.
 func (s *URLService) StoreURL(ctx context.Context, url domain.URL) (string, error) {
-	if err := s.repo.Store(ctx, url); err != nil {
+	if err := s.repo.StoreNew(ctx, url); err != nil {
 		return "", fmt.Errorf("failed to store URL: %w", err)
 	}
+	s.publish(ctx, domain.EventLinkCreated, url)
+	s.fetchPage(url)
 	return "", nil
 }
 
+// StoreURLs stores several URLs in the repository at once.
+// URLs without a short code get a generated one, and every original URL is canonicalized;
+// both are written back into the slice.
+// It returns one error per URL (nil when stored), so that a failing URL does not affect the others.
+func (s *URLService) StoreURLs(ctx context.Context, urls []domain.URL) ([]error, error) {
+	errs := make([]error, len(urls))
+	now := time.Now()
+	for i := range urls {
+		if urls[i].ShortCode != "" {
+			errs[i] = CheckShortCode(urls[i].ShortCode)
+		}
+		if errs[i] == nil {
+			errs[i] = s.canonicalize(&urls[i])
+		}
+		urls[i].Tags = NormalizeTags(urls[i].Tags)
+		urls[i].ActivatesAt = activationTime(urls[i].ActivatesAt, now)
+		if errs[i] == nil {
+			errs[i] = checkSchedule(urls[i])
+		}
+		if errs[i] == nil {
+			urls[i].Rules, errs[i] = s.normalizeRules(urls[i].Rules)
+		}
+		if errs[i] == nil {
+			urls[i].Variants, errs[i] = s.normalizeVariants(urls[i].Variants)
+		}
+		if errs[i] == nil {
+			urls[i].ForwardQuery, errs[i] = normalizeForwardQuery(urls[i].ForwardQuery)
+		}
+		if errs[i] == nil {
+			urls[i].RedirectType, errs[i] = NormalizeRedirectType(urls[i].RedirectType)
+		}
+		if errs[i] == nil {
+			urls[i].Group, errs[i] = s.checkGroup(ctx, urls[i].Group)
+		}
+		if urls[i].CreatedAt.IsZero() {
+			urls[i].CreatedAt, urls[i].UpdatedAt = now, now
+		}
+	}
+	return s.storeURLs(ctx, urls, errs)
+}
+
+// CreateURLs creates and stores a shortened URL for each request, built like those of CreateURL but never deduplicated.
+// It returns the links, and one error per request (nil when stored), so that a failing request does not affect the others.
+func (s *URLService) CreateURLs(ctx context.Context, reqs []domain.AddURLRequest) ([]domain.URL, []error, error) {
+	urls := make([]domain.URL, len(reqs))
+	errs := make([]error, len(reqs))
+	for i, req := range reqs {
+		urls[i], errs[i] = s.newURL(ctx, req)
+	}
+	errs, err := s.storeURLs(ctx, urls, errs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return urls, errs, nil
+}
+
+// storeURLs stores the URLs without an error in one batch, and records the error of each URL that fails.
+// URLs without a short code get a generated one, written back into the slice.
+func (s *URLService) storeURLs(ctx context.Context, urls []domain.URL, errs []error) ([]error, error) {
+	// Claim the custom short codes first, so that generated ones cannot take them.
+	claimed := make(map[string]bool, len(urls))
+	for i, url := range urls {
+		if url.ShortCode == "" || errs[i] != nil {
+			continue
+		}
+		if claimed[url.ShortCode] {
+			errs[i] = fmt.Errorf("%w: %s", domain.ErrShortCodeTaken, url.ShortCode)
+			continue
+		}
+		claimed[url.ShortCode] = true
+	}
+
+	// Generate the missing short codes, skipping the ones already used in this batch,
+	// since the same original URL may appear more than once.
+	for i := range urls {
+		if urls[i].ShortCode != "" || errs[i] != nil {
+			continue
+		}
+		for sequence := 1; ; sequence++ {
+			shortCode := generateShortCode(urls[i].OriginalURL, sequence)
+			if !claimed[shortCode] && s.repo.IsUnique(ctx, shortCode) {
+				urls[i].ShortCode = shortCode
+				claimed[shortCode] = true
+				break
+			}
+		}
+	}
+
+	// Store every URL that is still valid in one batch.
+	var pending []domain.URL
+	var pendingIndexes []int
+	for i, url := range urls {
+		if errs[i] == nil {
+			pending = append(pending, url)
+			pendingIndexes = append(pendingIndexes, i)
+		}
+	}
+	if len(pending) == 0 {
+		return errs, nil
+	}
+	// Links beyond the quota of the workspace are refused, in order
+	remaining, err := s.remainingLinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if remaining >= 0 && int64(len(pending)) > remaining {
+		for _, i := range pendingIndexes[remaining:] {
+			errs[i] = s.quotaError()
+		}
+		pending, pendingIndexes = pending[:remaining], pendingIndexes[:remaining]
+		if len(pending) == 0 {
+			return errs, nil
+		}
+	}
+	storeErrs, err := s.repo.StoreBatch(ctx, pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store URLs: %w", err)
+	}
+	for j, i := range pendingIndexes {
+		if storeErrs[j] != nil {
+			errs[i] = storeErrs[j]
+			continue
+		}
+		s.publish(ctx, domain.EventLinkCreated, urls[i])
+		s.fetchPage(urls[i])
+	}
+
+	return errs, nil
+}
+
+// UpdateURL changes the original URL, the expiry and/or the metadata of an existing short code.
+// Fields left out of the request keep their current value. Fields out of bounds fail with domain.ErrInvalidLink,
+// like an expiry that is not in the future.
+func (s *URLService) UpdateURL(ctx context.Context, shortCode string, req domain.UpdateURLRequest) (*domain.URL, error) {
+	if err := checkUpdateRequest(req, time.Now()); err != nil {
+		return nil, err
+	}
+	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find URL by short code: %w", err)
+	}
+
+	previousURL := url.OriginalURL
+	if req.OriginalURL != "" {
+		url.OriginalURL, url.InputURL = req.OriginalURL, ""
+		if err := s.canonicalize(url); err != nil {
+			return nil, err
+		}
+	}
+	pageChanged := url.OriginalURL != previousURL
+	if pageChanged {
+		// The metadata of the previous target page no longer describes the link
+		url.Page = nil
+	}
+	if !req.Expiry.IsZero() {
+		url.Expiry = req.Expiry
+	}
+	if req.Title != nil {
+		url.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Description != nil {
+		url.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Tags != nil {
+		url.Tags = NormalizeTags(req.Tags)
+	}
+	if req.Notes != nil {
+		url.Notes = *req.Notes
+	}
+	if req.MaxClicks != nil {
+		url.MaxClicks = *req.MaxClicks
+	}
+	if req.ActivatesAt != nil {
+		url.ActivatesAt = activationTime(*req.ActivatesAt, time.Now())
+	}
+	if err := checkSchedule(*url); err != nil {
+		return nil, err
+	}
+	if req.Rules != nil {
+		if url.Rules, err = s.normalizeRules(req.Rules); err != nil {
+			return nil, err
+		}
+	}
+	if req.Variants != nil {
+		if url.Variants, err = s.normalizeVariants(req.Variants); err != nil {
+			return nil, err
+		}
+	}
+	if req.ForwardQuery != nil {
+		if url.ForwardQuery, err = normalizeForwardQuery(*req.ForwardQuery); err != nil {
+			return nil, err
+		}
+	}
+	if req.ForwardPath != nil {
+		url.ForwardPath = *req.ForwardPath
+	}
+	if req.RedirectType != nil {
+		if url.RedirectType, err = NormalizeRedirectType(*req.RedirectType); err != nil {
+			return nil, err
+		}
+	}
+	if req.Group != nil {
+		if url.Group, err = s.checkGroup(ctx, *req.Group); err != nil {
+			return nil, err
+		}
+	}
+	if req.Password != nil {
+		url.PasswordHash = ""
+		if *req.Password != "" {
+			hash, err := HashPassword(*req.Password)
+			if err != nil {
+				return nil, err
+			}
+			url.PasswordHash = hash
+		}
+	}
+	url.UpdatedAt = time.Now()
+
+	if err := s.repo.Store(ctx, *url); err != nil {
+		return nil, fmt.Errorf("failed to store URL: %w", err)
+	}
+	s.publish(ctx, domain.EventLinkUpdated, *url)
+	if pageChanged {
+		s.fetchPage(*url)
+	}
+	return url, nil
+}
+
+// DeleteURL removes the given short code from the repository.
+func (s *URLService) DeleteURL(ctx context.Context, shortCode string) error {
+	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	if err != nil {
+		return fmt.Errorf("failed to find URL by short code: %w", err)
+	}
+	if err := s.repo.Delete(ctx, shortCode); err != nil {
+		return fmt.Errorf("failed to delete URL: %w", err)
+	}
+	s.publish(ctx, domain.EventLinkDeleted, *url)
+	return nil
+}
+
+// SweepExpired publishes an expired event for every short code that expired since the last sweep.
+// It returns the number of expired short codes found.
+func (s *URLService) SweepExpired(ctx context.Context) (int, error) {
+	shortCodes, err := s.repo.PopExpired(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to find expired URLs: %w", err)
+	}
+	for _, shortCode := range shortCodes {
+		s.publish(ctx, domain.EventLinkExpired, domain.URL{ShortCode: shortCode})
+	}
+	return len(shortCodes), nil
+}
+
+// RunExpirySweeper calls SweepExpired at the given interval until the context is cancelled.
+func (s *URLService) RunExpirySweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.SweepExpired(ctx); err != nil {
+				log.Printf("Error sweeping expired URLs: %v", err)
+			}
+		}
+	}
+}
+
+// NormalizeTags trims and lowercases tags, and drops empty and repeated ones. It keeps the order of the first occurrences.
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// normalizeTag trims and lowercases a tag, so that tags match regardless of how they were typed.
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// canonicalize replaces the original URL with its canonical form, keeping the submitted one as the input URL if they differ.
+func (s *URLService) canonicalize(url *domain.URL) error {
+	canonical, err := Canonicalize(url.OriginalURL, s.canonical)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidURL, err)
+	}
+	if canonical != url.OriginalURL && url.InputURL == "" {
+		url.InputURL = url.OriginalURL
+	}
+	url.OriginalURL = canonical
+	return nil
+}
+
+// publish sends a link lifecycle event to the configured publisher, if any.
+// Publishing errors are logged rather than returned, so that a failing subscriber never breaks link management.
+func (s *URLService) publish(ctx context.Context, eventType domain.EventType, url domain.URL) {
+	if s.publisher == nil {
+		return
+	}
+	event := domain.Event{
+		ID:          newID(),
+		Type:        eventType,
+		ShortCode:   url.ShortCode,
+		OriginalURL: url.OriginalURL,
+		Expiry:      url.Expiry,
+		OccurredAt:  time.Now(),
+	}
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		log.Printf("Error publishing %s event for %s: %v", eventType, url.ShortCode, err)
+	}
+}
+
+// shortCodePattern matches the characters allowed in a custom short code.
+var shortCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// CheckShortCode fails with domain.ErrInvalidShortCode unless the custom short code only uses letters, digits, "_" and "-",
+// and is at most 64 characters long. Short codes are part of the redirect path, which gives "/" and a trailing "+" a meaning
+// of their own, so anything else could not be reached.
+func CheckShortCode(shortCode string) error {
+	if !shortCodePattern.MatchString(shortCode) {
+		return fmt.Errorf("%w: %q - only letters, digits, \"_\" and \"-\" are allowed, up to 64 characters", domain.ErrInvalidShortCode, shortCode)
+	}
+	return nil
+}
+
 // generateShortCode creates a unique short code for the given URL.
 // It uses a sequence number to handle hash collisions and ensure uniqueness.
 // The function generates a SHA-256 hash of the URL and encodes it using Base62.
@@ -105,13 +700,175 @@ func (s *URLService) FetchAllURLs(ctx context.Context) ([]domain.URL, error) {
 	return s.repo.FetchAll(ctx)
 }
 
+// EachURL calls fn for every URL in the repository, one at a time.
+func (s *URLService) EachURL(ctx context.Context, fn func(domain.URL) error) error {
+	return s.repo.Iterate(ctx, fn)
+}
+
+// GetURL retrieves the URL for the given short code without counting a click.
+func (s *URLService) GetURL(ctx context.Context, shortCode string) (*domain.URL, error) {
+	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find URL by short code: %w", err)
+	}
+	return url, nil
+}
+
+// ListURLs retrieves the URLs that pass the filter. The URLs are sorted by expiry, soonest first.
+func (s *URLService) ListURLs(ctx context.Context, filter domain.URLFilter) ([]domain.URL, error) {
+	filter.Tag = normalizeTag(filter.Tag)
+	var urls []domain.URL
+	err := s.repo.Iterate(ctx, func(url domain.URL) error {
+		if filter.Matches(url) {
+			urls = append(urls, url)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(urls, func(i, j int) bool { return urls[i].Expiry.Before(urls[j].Expiry) })
+	return urls, nil
+}
+
+// RenewURL moves the expiry of the given short code to a new time.
+func (s *URLService) RenewURL(ctx context.Context, shortCode string, expiry time.Time) (*domain.URL, error) {
+	if !expiry.After(time.Now()) {
+		return nil, fmt.Errorf("invalid expiry %s: must be in the future", expiry.Format(time.RFC3339))
+	}
+	return s.UpdateURL(ctx, shortCode, domain.UpdateURLRequest{Expiry: expiry})
+}
+
+// GetStats retrieves the usage statistics of the given short code.
+func (s *URLService) GetStats(ctx context.Context, shortCode string) (*domain.URLStats, error) {
+	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find URL by short code: %w", err)
+	}
+	clicks, err := s.clicks.GetClicks(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clicks: %w", err)
+	}
+	countries, err := s.clicks.GetClickCountries(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clicks per country: %w", err)
+	}
+	stats := &domain.URLStats{ShortCode: url.ShortCode, OriginalURL: url.OriginalURL, Expiry: url.Expiry, Clicks: clicks, Countries: countries}
+	if len(url.Variants) > 0 {
+		variantClicks, err := s.clicks.GetClickVariants(ctx, shortCode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get clicks per variant: %w", err)
+		}
+		for _, variant := range url.Variants {
+			stats.Variants = append(stats.Variants, domain.VariantStats{URL: variant.URL, Weight: variant.Weight, Clicks: variantClicks[variant.URL]})
+		}
+	}
+	if url.MaxClicks > 0 {
+		remaining := remainingClicks(*url, clicks)
+		stats.MaxClicks, stats.RemainingClicks = url.MaxClicks, &remaining
+	}
+	return stats, nil
+}
+
+// RemainingClicks returns the number of times a link with a maximum number of clicks can still be followed.
+// It returns 0 for unlimited links.
+func (s *URLService) RemainingClicks(ctx context.Context, url domain.URL) (int64, error) {
+	if url.MaxClicks <= 0 {
+		return 0, nil
+	}
+	clicks, err := s.clicks.GetClicks(ctx, url.ShortCode)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get clicks: %w", err)
+	}
+	return remainingClicks(url, clicks), nil
+}
+
+// remainingClicks returns the clicks left on a limited link, which is 0 when its limit was lowered below the clicks already made.
+func remainingClicks(url domain.URL, clicks int64) int64 {
+	if clicks >= url.MaxClicks {
+		return 0
+	}
+	return url.MaxClicks - clicks
+}
+
 // GetOriginalURL retrieves the original URL for the given short code from the repository.
+// Every call counts as a click on the short code. It fails with domain.ErrPasswordRequired for protected links,
+// which are followed with ResolveURL and FollowURL once the visitor has entered the password.
+// It knows nothing about the visitor, so it ignores routing rules, and every call gets the same variant of a link with variants.
 func (s *URLService) GetOriginalURL(ctx context.Context, shortCode string) (string, error) {
+	url, err := s.findURL(ctx, shortCode)
+	if err != nil {
+		return "", err
+	}
+	if url.Protected() {
+		return "", fmt.Errorf("%w: %s", domain.ErrPasswordRequired, shortCode)
+	}
+	return s.FollowURL(ctx, *url, domain.Visitor{})
+}
+
+// ResolveURL retrieves the URL the given short code leads to, through the same lookup as GetOriginalURL,
+// for callers that check who may follow it. Unlike GetOriginalURL, it does not count as a click,
+// and it returns protected links too.
+func (s *URLService) ResolveURL(ctx context.Context, shortCode string) (*domain.URL, error) {
+	return s.findURL(ctx, shortCode)
+}
+
+// FollowURL counts a click on a URL returned by ResolveURL and returns the destination of the visitor:
+// the URL of the first routing rule the visitor matches, the variant assigned to the visitor, or the original URL.
+// A link with a maximum number of clicks fails with domain.ErrClickLimitReached once it was followed that many times,
+// and a link fails with domain.ErrLinkPending before its activation time.
+func (s *URLService) FollowURL(ctx context.Context, url domain.URL, visitor domain.Visitor) (string, error) {
+	if url.Pending(time.Now()) {
+		return "", fmt.Errorf("%w: %s", domain.ErrLinkPending, url.ShortCode)
+	}
+	destination, variant := url.Destination(visitor)
+
+	// A limited link is only followed once its click is counted, atomically, so that concurrent clicks cannot exceed the limit
+	if url.MaxClicks > 0 {
+		clicks, err := s.clicks.IncrementClicksUpTo(ctx, url.ShortCode, url.MaxClicks)
+		if err != nil {
+			return "", fmt.Errorf("failed to count click: %w", err)
+		}
+		s.clicked(ctx, url, visitor, variant, clicks)
+		return destination, nil
+	}
+
+	// A failure to count must not stop the redirect of an unlimited link
+	clicks, err := s.clicks.IncrementClicks(ctx, url.ShortCode)
+	if err != nil {
+		log.Printf("Error counting click for %s: %v", url.ShortCode, err)
+		return destination, nil
+	}
+	s.clicked(ctx, url, visitor, variant, clicks)
+	return destination, nil
+}
+
+// clicked counts the click per country of the visitor, when it is known, and per variant, when the visitor was sent to one.
+// It also lets subscribers know the first time a link is followed.
+// These counts are only analytics, so a failure to count is logged.
+func (s *URLService) clicked(ctx context.Context, url domain.URL, visitor domain.Visitor, variant int, clicks int64) {
+	if visitor.Country != "" {
+		if err := s.clicks.CountClickCountry(ctx, url.ShortCode, visitor.Country); err != nil {
+			log.Printf("Error counting the country of a click for %s: %v", url.ShortCode, err)
+		}
+	}
+	if variant >= 0 {
+		if err := s.clicks.CountClickVariant(ctx, url.ShortCode, url.Variants[variant].URL); err != nil {
+			log.Printf("Error counting the variant of a click for %s: %v", url.ShortCode, err)
+		}
+	}
+	if clicks == 1 {
+		s.publish(ctx, domain.EventLinkFirstClicked, url)
+	}
+}
+
+// findURL looks up the URL that a short code leads to.
+func (s *URLService) findURL(ctx context.Context, shortCode string) (*domain.URL, error) {
 	url, err := s.repo.FindByShortCode(ctx, shortCode)
 	if err != nil {
-		return "", fmt.Errorf("failed to find URL by short code: %w", err)
+		return nil, fmt.Errorf("failed to find URL by short code: %w", err)
 	}
-	return url.OriginalURL, nil
+	return url, nil
 }
 
 // IsUniqueShortCode checks if the given short code is unique by calling the repository.
@@ -0,0 +1,57 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// storageOnly is a URL repository that only stores the links, like the cache and Bloom filter decorators.
+type storageOnly struct {
+	domain.URLRepository
+}
+
+// clickCounter counts no clicks.
+type clickCounter struct {
+	domain.ClickCounter
+}
+
+// groupRepository stores no groups.
+type groupRepository struct {
+	domain.GroupRepository
+}
+
+// groupIndex finds no links.
+type groupIndex struct{}
+
+func (groupIndex) FindByGroup(ctx context.Context, group string) ([]domain.URL, error) {
+	return nil, nil
+}
+
+// TestNewURLService tests that a service without the click counter, or the index of a feature it uses, cannot be created
+func TestNewURLService(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		panics bool
+	}{
+		{name: "no click counter", panics: true},
+		{name: "click counter", opts: []Option{WithClickCounter(clickCounter{})}},
+		{name: "groups without index", opts: []Option{WithClickCounter(clickCounter{}), WithGroups(groupRepository{})}, panics: true},
+		{name: "groups with index", opts: []Option{WithClickCounter(clickCounter{}), WithGroups(groupRepository{}), WithGroupIndex(groupIndex{})}},
+		{name: "deduplication without index", opts: []Option{WithClickCounter(clickCounter{}), WithDeduplication()}, panics: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			create := func() { NewURLService(storageOnly{}, tt.opts...) }
+			if tt.panics {
+				assert.Panics(t, create)
+			} else {
+				assert.NotPanics(t, create)
+			}
+		})
+	}
+}
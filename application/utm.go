@@ -0,0 +1,192 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// MaxUTMLength is the length of a UTM parameter, in characters.
+const MaxUTMLength = 100
+
+// utmPresetNamePattern matches the name of a UTM preset, once lowercased.
+var utmPresetNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// WithUTMPresets stores UTM presets in the given repository, so that links can be tagged by the name of a preset.
+func WithUTMPresets(repo domain.UTMPresetRepository) Option {
+	return func(s *URLService) {
+		s.utmPresets = repo
+	}
+}
+
+// SaveUTMPreset validates and stores a UTM preset, replacing any preset with the same name.
+// The name is lowercased, and the parameters are normalized like those of a link.
+func (s *URLService) SaveUTMPreset(ctx context.Context, name string, utm domain.UTM) (*domain.UTMPreset, error) {
+	if s.utmPresets == nil {
+		return nil, fmt.Errorf("%w: UTM presets are not enabled", domain.ErrInvalidUTM)
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !utmPresetNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: invalid preset name %q - use up to 64 letters, digits, \"_\", \"-\" and \".\"", domain.ErrInvalidUTM, name)
+	}
+	utm, err := normalizeUTM(utm)
+	if err != nil {
+		return nil, err
+	}
+
+	preset := domain.UTMPreset{Name: name, UTM: utm, UpdatedAt: time.Now()}
+	if err := s.utmPresets.SavePreset(ctx, preset); err != nil {
+		return nil, fmt.Errorf("failed to store UTM preset: %w", err)
+	}
+	return &preset, nil
+}
+
+// GetUTMPreset retrieves a UTM preset by its name, and fails with domain.ErrUTMPresetNotFound if it does not exist.
+func (s *URLService) GetUTMPreset(ctx context.Context, name string) (*domain.UTMPreset, error) {
+	if s.utmPresets == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrUTMPresetNotFound, name)
+	}
+	return s.utmPresets.FindPreset(ctx, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// ListUTMPresets retrieves all UTM presets, sorted by name.
+func (s *URLService) ListUTMPresets(ctx context.Context) ([]domain.UTMPreset, error) {
+	if s.utmPresets == nil {
+		return []domain.UTMPreset{}, nil
+	}
+	presets, err := s.utmPresets.ListPresets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	return presets, nil
+}
+
+// DeleteUTMPreset removes a UTM preset. Links already tagged with it keep their parameters.
+func (s *URLService) DeleteUTMPreset(ctx context.Context, name string) error {
+	if s.utmPresets == nil {
+		return fmt.Errorf("%w: %s", domain.ErrUTMPresetNotFound, name)
+	}
+	return s.utmPresets.DeletePreset(ctx, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// requestUTM returns the UTM parameters of a request, those set in the request taking precedence over those of its preset.
+// It returns zero parameters when the request has none, fails with domain.ErrInvalidUTM for invalid parameters,
+// or when source, medium and campaign are not all set, and with domain.ErrUTMPresetNotFound for an unknown preset.
+func (s *URLService) requestUTM(ctx context.Context, req domain.AddURLRequest) (domain.UTM, error) {
+	var utm domain.UTM
+	if req.UTM != nil {
+		utm = *req.UTM
+	}
+	if req.UTMPreset != "" {
+		preset, err := s.GetUTMPreset(ctx, req.UTMPreset)
+		if err != nil {
+			return domain.UTM{}, err
+		}
+		utm = utm.Merge(preset.UTM)
+	}
+	if utm.IsZero() {
+		return utm, nil
+	}
+
+	utm, err := normalizeUTM(utm)
+	if err != nil {
+		return domain.UTM{}, err
+	}
+	if utm.Source == "" || utm.Medium == "" || utm.Campaign == "" {
+		return domain.UTM{}, fmt.Errorf("%w: source, medium and campaign are required", domain.ErrInvalidUTM)
+	}
+	return utm, nil
+}
+
+// withUTM returns a canonical original URL with UTM parameters added to its query, replacing any UTM parameter
+// of the same name already in it. It runs after canonicalization, so that parameters stripped from submitted URLs,
+// such as "utm_*", never remove those set on purpose; the query is still sorted if canonicalization sorts it.
+func (s *URLService) withUTM(originalURL string, utm domain.UTM) (string, error) {
+	if utm.IsZero() {
+		return originalURL, nil
+	}
+	target, err := url.Parse(originalURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", domain.ErrInvalidURL, err)
+	}
+	target.RawQuery = mergeUTM(target.RawQuery, utm)
+	if s.canonical.SortQuery {
+		target.RawQuery = canonicalQuery(target.RawQuery, CanonicalOptions{SortQuery: true})
+	}
+	return target.String(), nil
+}
+
+// normalizeUTM trims UTM parameters and lowercases source, medium and campaign, so that reports do not split
+// a campaign by the way it was typed. Term and content keep their case, since they often hold keywords.
+// It fails with domain.ErrInvalidUTM for parameters that are too long or contain control characters.
+func normalizeUTM(utm domain.UTM) (domain.UTM, error) {
+	fields := []struct {
+		name  string
+		value *string
+		lower bool
+	}{
+		{"source", &utm.Source, true},
+		{"medium", &utm.Medium, true},
+		{"campaign", &utm.Campaign, true},
+		{"term", &utm.Term, false},
+		{"content", &utm.Content, false},
+	}
+	for _, field := range fields {
+		value := strings.TrimSpace(*field.value)
+		if field.lower {
+			value = strings.ToLower(value)
+		}
+		if len([]rune(value)) > MaxUTMLength {
+			return utm, fmt.Errorf("%w: %s is longer than %d characters", domain.ErrInvalidUTM, field.name, MaxUTMLength)
+		}
+		if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+			return utm, fmt.Errorf("%w: %s contains control characters", domain.ErrInvalidUTM, field.name)
+		}
+		*field.value = value
+	}
+	return utm, nil
+}
+
+// mergeUTM returns a raw query with the UTM parameters set, after the other parameters.
+// Existing parameters with the name of a parameter that is set are dropped; the others are kept as they are.
+func mergeUTM(rawQuery string, utm domain.UTM) string {
+	params := []struct{ key, value string }{
+		{"utm_source", utm.Source},
+		{"utm_medium", utm.Medium},
+		{"utm_campaign", utm.Campaign},
+		{"utm_term", utm.Term},
+		{"utm_content", utm.Content},
+	}
+	set := make(map[string]bool, len(params))
+	for _, param := range params {
+		if param.value != "" {
+			set[param.key] = true
+		}
+	}
+
+	var pairs []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		rawKey, _, _ := strings.Cut(pair, "=")
+		if key, err := url.QueryUnescape(rawKey); err == nil && set[key] {
+			continue
+		}
+		pairs = append(pairs, pair)
+	}
+	for _, param := range params {
+		if param.value != "" {
+			pairs = append(pairs, param.key+"="+url.QueryEscape(param.value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
@@ -0,0 +1,102 @@
+package application
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// Limits on the metadata of a link, which is stored with it and returned by every listing.
+const (
+	MaxTitleLength       = 200
+	MaxDescriptionLength = 1000
+	MaxNotesLength       = 4000
+	MaxCreatedByLength   = 100
+	MaxTags              = 20
+)
+
+// tagPattern matches a tag: letters, digits, "_", "-" and ".", at most 32 characters. Tags are stored lowercased.
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,32}$`)
+
+// checkAddRequest fails with domain.ErrInvalidLink if the metadata, password or maximum number of clicks
+// of a link to add are out of bounds.
+func checkAddRequest(req domain.AddURLRequest) error {
+	if err := checkMetadata(req.Title, req.Description, req.Tags, req.Notes, req.CreatedBy); err != nil {
+		return err
+	}
+	if err := checkPassword(req.Password); err != nil {
+		return err
+	}
+	return checkMaxClicks(req.MaxClicks)
+}
+
+// checkUpdateRequest fails with domain.ErrInvalidLink if the expiry, metadata, password or maximum number of clicks
+// set by an update are out of bounds.
+func checkUpdateRequest(req domain.UpdateURLRequest, now time.Time) error {
+	if !req.Expiry.IsZero() && !req.Expiry.After(now) {
+		return fmt.Errorf("%w: expiry must be in the future", domain.ErrInvalidLink)
+	}
+	if err := checkMetadata(derefString(req.Title), derefString(req.Description), req.Tags, derefString(req.Notes), ""); err != nil {
+		return err
+	}
+	if err := checkPassword(derefString(req.Password)); err != nil {
+		return err
+	}
+	if req.MaxClicks != nil {
+		return checkMaxClicks(*req.MaxClicks)
+	}
+	return nil
+}
+
+// checkMetadata fails with domain.ErrInvalidLink if the title, description, tags, notes or creator of a link
+// are longer than their limits, or if a tag does not match tagPattern.
+func checkMetadata(title, description string, tags []string, notes, createdBy string) error {
+	switch {
+	case utf8.RuneCountInString(strings.TrimSpace(title)) > MaxTitleLength:
+		return fmt.Errorf("%w: title is longer than %d characters", domain.ErrInvalidLink, MaxTitleLength)
+	case utf8.RuneCountInString(strings.TrimSpace(description)) > MaxDescriptionLength:
+		return fmt.Errorf("%w: description is longer than %d characters", domain.ErrInvalidLink, MaxDescriptionLength)
+	case utf8.RuneCountInString(notes) > MaxNotesLength:
+		return fmt.Errorf("%w: notes are longer than %d characters", domain.ErrInvalidLink, MaxNotesLength)
+	case utf8.RuneCountInString(strings.TrimSpace(createdBy)) > MaxCreatedByLength:
+		return fmt.Errorf("%w: created_by is longer than %d characters", domain.ErrInvalidLink, MaxCreatedByLength)
+	case len(tags) > MaxTags:
+		return fmt.Errorf("%w: more than %d tags", domain.ErrInvalidLink, MaxTags)
+	}
+	for _, tag := range tags {
+		if !tagPattern.MatchString(strings.TrimSpace(tag)) {
+			return fmt.Errorf("%w: invalid tag %q - tags use letters, digits, \"_\", \"-\" and \".\", up to 32 characters", domain.ErrInvalidLink, tag)
+		}
+	}
+	return nil
+}
+
+// checkPassword fails with domain.ErrInvalidLink if the password of a link is longer than MaxPasswordLength.
+// The password may be empty for public links.
+func checkPassword(password string) error {
+	if len(password) > MaxPasswordLength {
+		return fmt.Errorf("%w: password is longer than %d bytes", domain.ErrInvalidLink, MaxPasswordLength)
+	}
+	return nil
+}
+
+// checkMaxClicks fails with domain.ErrInvalidLink if the maximum number of clicks of a link is negative.
+// A maximum of 0 does not limit the clicks.
+func checkMaxClicks(maxClicks int64) error {
+	if maxClicks < 0 {
+		return fmt.Errorf("%w: max_clicks must not be negative", domain.ErrInvalidLink)
+	}
+	return nil
+}
+
+// derefString returns the string s points to, or an empty string if s is nil.
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,92 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// WebhookService manages webhook subscribers and fans link lifecycle events out to them.
+// It implements domain.EventPublisher, so it can be handed to the URLService directly.
+type WebhookService struct {
+	repo domain.WebhookRepository
+}
+
+// NewWebhookService creates a new instance of WebhookService.
+func NewWebhookService(repo domain.WebhookRepository) *WebhookService {
+	return &WebhookService{repo: repo}
+}
+
+// RegisterSubscriber validates and stores a new webhook subscriber.
+// A signing secret is generated when the request does not provide one.
+func (s *WebhookService) RegisterSubscriber(ctx context.Context, req domain.AddWebhookRequest) (*domain.WebhookSubscriber, error) {
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("invalid webhook url %q: must be an absolute http or https URL", req.URL)
+	}
+	for _, eventType := range req.Events {
+		if !eventType.IsValid() {
+			return nil, fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		secret = randomHex(32)
+	}
+
+	subscriber := domain.WebhookSubscriber{
+		ID:        newID(),
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    req.Events,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.SaveSubscriber(ctx, subscriber); err != nil {
+		return nil, fmt.Errorf("failed to store webhook subscriber: %w", err)
+	}
+	return &subscriber, nil
+}
+
+// ListSubscribers retrieves all webhook subscribers.
+// The signing secrets are left out, as they are only shown once at registration.
+func (s *WebhookService) ListSubscribers(ctx context.Context) ([]domain.WebhookSubscriber, error) {
+	subscribers, err := s.repo.ListSubscribers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range subscribers {
+		subscribers[i].Secret = ""
+	}
+	return subscribers, nil
+}
+
+// DeleteSubscriber removes a webhook subscriber.
+func (s *WebhookService) DeleteSubscriber(ctx context.Context, id string) error {
+	return s.repo.DeleteSubscriber(ctx, id)
+}
+
+// Publish queues a delivery of the event for every subscriber whose filter accepts it.
+func (s *WebhookService) Publish(ctx context.Context, event domain.Event) error {
+	subscribers, err := s.repo.ListSubscribers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list webhook subscribers: %w", err)
+	}
+	for _, subscriber := range subscribers {
+		if !subscriber.Accepts(event.Type) {
+			continue
+		}
+		delivery := domain.WebhookDelivery{
+			ID:           newID(),
+			SubscriberID: subscriber.ID,
+			Event:        event,
+		}
+		if err := s.repo.Enqueue(ctx, delivery); err != nil {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// workspaceIDPattern matches the ID of a workspace, which is also part of its Redis keys.
+var workspaceIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// WithWorkspace makes the URLService manage the links of a workspace and enforce its quota.
+// The repositories of the service must be those of the workspace.
+func WithWorkspace(workspace domain.Workspace) Option {
+	return func(s *URLService) {
+		s.workspace = workspace
+	}
+}
+
+// Workspace returns the workspace the URLService manages, which has an empty ID for the default workspace.
+func (s *URLService) Workspace() domain.Workspace {
+	return s.workspace
+}
+
+// WorkspaceUsage returns the quota of the workspace and the number of links it holds.
+func (s *URLService) WorkspaceUsage(ctx context.Context) (*domain.WorkspaceUsage, error) {
+	links, err := s.repo.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count URLs: %w", err)
+	}
+	return &domain.WorkspaceUsage{
+		ID:       s.workspace.ID,
+		Name:     s.workspace.Name,
+		Domain:   s.workspace.Domain,
+		Links:    links,
+		MaxLinks: s.workspace.Quota.MaxLinks,
+	}, nil
+}
+
+// remainingLinks returns the number of links the workspace can still add, or -1 when it is unlimited.
+// The quota is checked before links are stored, so links added at the same time can exceed it slightly.
+func (s *URLService) remainingLinks(ctx context.Context) (int64, error) {
+	if s.workspace.Quota.MaxLinks <= 0 {
+		return -1, nil
+	}
+	links, err := s.repo.Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count URLs: %w", err)
+	}
+	if links >= s.workspace.Quota.MaxLinks {
+		return 0, nil
+	}
+	return s.workspace.Quota.MaxLinks - links, nil
+}
+
+// quotaError returns the error of a link refused because the workspace has used up its quota.
+func (s *URLService) quotaError() error {
+	return fmt.Errorf("%w: the workspace can hold at most %d links", domain.ErrQuotaExceeded, s.workspace.Quota.MaxLinks)
+}
+
+// CheckWorkspaces validates the configuration of the workspaces besides the default one.
+// Every workspace needs a unique ID, at least one API key and a domain; API keys and domains may not be shared.
+// Without a domain, the shortened URLs of a workspace would use the shared host, whose redirects go to the default workspace.
+// Domains are lowercased, since the Host header of redirects is compared to them.
+func CheckWorkspaces(workspaces []domain.Workspace, defaultKeys []string) error {
+	keys := make(map[string]string)
+	for _, key := range defaultKeys {
+		keys[key] = "the default workspace"
+	}
+	ids := make(map[string]bool)
+	domains := make(map[string]string)
+	for i := range workspaces {
+		workspace := &workspaces[i]
+		if !workspaceIDPattern.MatchString(workspace.ID) {
+			return fmt.Errorf("invalid workspace ID %q - use up to 32 lowercase letters, digits, \"_\" and \"-\"", workspace.ID)
+		}
+		if ids[workspace.ID] {
+			return fmt.Errorf("workspace %q is defined twice", workspace.ID)
+		}
+		ids[workspace.ID] = true
+
+		if len(workspace.APIKeys) == 0 {
+			return fmt.Errorf("workspace %q has no API key", workspace.ID)
+		}
+		for _, key := range workspace.APIKeys {
+			if key == "" {
+				return fmt.Errorf("workspace %q has an empty API key", workspace.ID)
+			}
+			if owner, ok := keys[key]; ok {
+				return fmt.Errorf("workspace %q shares an API key with %s", workspace.ID, owner)
+			}
+			keys[key] = fmt.Sprintf("workspace %q", workspace.ID)
+		}
+
+		workspace.Domain = strings.ToLower(strings.TrimSpace(workspace.Domain))
+		if workspace.Domain == "" {
+			return fmt.Errorf("workspace %q has no domain - redirects on the shared host go to the default workspace", workspace.ID)
+		}
+		if other, ok := domains[workspace.Domain]; ok {
+			return fmt.Errorf("workspaces %q and %q share the domain %s", other, workspace.ID, workspace.Domain)
+		}
+		domains[workspace.Domain] = workspace.ID
+		if workspace.Quota.MaxLinks < 0 {
+			return fmt.Errorf("workspace %q has a negative quota", workspace.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// TestCheckWorkspaces tests the validation of the workspaces configuration
+func TestCheckWorkspaces(t *testing.T) {
+	acme := func() domain.Workspace {
+		return domain.Workspace{ID: "acme", APIKeys: []string{"acme-key"}, Domain: " Go.Acme.com ", Quota: domain.Quota{MaxLinks: 10}}
+	}
+
+	tests := []struct {
+		name       string
+		workspaces []domain.Workspace
+		wantErr    bool
+	}{
+		{name: "no workspaces"},
+		{name: "valid workspaces", workspaces: []domain.Workspace{acme(), {ID: "beta_2", APIKeys: []string{"beta-key"}, Domain: "go.beta.com"}}},
+		{name: "no domain", workspaces: []domain.Workspace{acme(), {ID: "beta", APIKeys: []string{"beta-key"}}}, wantErr: true},
+		{name: "invalid ID", workspaces: []domain.Workspace{{ID: "Acme:1", APIKeys: []string{"key"}}}, wantErr: true},
+		{name: "duplicate ID", workspaces: []domain.Workspace{acme(), {ID: "acme", APIKeys: []string{"other-key"}}}, wantErr: true},
+		{name: "no API key", workspaces: []domain.Workspace{{ID: "beta"}}, wantErr: true},
+		{name: "empty API key", workspaces: []domain.Workspace{{ID: "beta", APIKeys: []string{""}}}, wantErr: true},
+		{name: "key of the default workspace", workspaces: []domain.Workspace{{ID: "beta", APIKeys: []string{"default-key"}}}, wantErr: true},
+		{name: "shared key", workspaces: []domain.Workspace{acme(), {ID: "beta", APIKeys: []string{"acme-key"}}}, wantErr: true},
+		{name: "shared domain", workspaces: []domain.Workspace{acme(), {ID: "beta", APIKeys: []string{"beta-key"}, Domain: "go.acme.com"}}, wantErr: true},
+		{name: "negative quota", workspaces: []domain.Workspace{{ID: "beta", APIKeys: []string{"beta-key"}, Domain: "go.beta.com", Quota: domain.Quota{MaxLinks: -1}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckWorkspaces(tt.workspaces, []string{"default-key"})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			for _, workspace := range tt.workspaces {
+				if workspace.ID == "acme" {
+					assert.Equal(t, "go.acme.com", workspace.Domain)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/application"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+	"github.com/terenzio/URL-Shortening-Service/infrastructure/config"
+	urlHandler "github.com/terenzio/URL-Shortening-Service/infrastructure/http"
+	redisRepo "github.com/terenzio/URL-Shortening-Service/infrastructure/redis"
+)
+
+// backend is where the link commands send their operations:
+// either straight to the repository through the URLService, or to a running server through the HTTP API.
+type backend interface {
+	Create(ctx context.Context, req domain.AddURLRequest) (*domain.URL, error)
+	Get(ctx context.Context, shortCode string) (*domain.URL, error)
+	List(ctx context.Context, filter domain.URLFilter) ([]domain.URL, error)
+	Delete(ctx context.Context, shortCode string) error
+	Renew(ctx context.Context, shortCode string, expiry time.Time) (*domain.URL, error)
+	Stats(ctx context.Context, shortCode string) (*domain.URLStats, error)
+}
+
+// backendFlags holds the flags shared by every link command.
+type backendFlags struct {
+	apiURL    *string
+	apiKey    *string
+	workspace *string
+	format    *string
+}
+
+// addBackendFlags registers the flags shared by every link command.
+func addBackendFlags(flags *flag.FlagSet) backendFlags {
+	return backendFlags{
+		apiURL:    flags.String("api", os.Getenv("SHORTENER_API_URL"), "base URL of the HTTP API, e.g. http://localhost:9000; Redis is used directly when empty"),
+		apiKey:    flags.String("api-key", os.Getenv("SHORTENER_API_KEY"), "API key sent to the HTTP API"),
+		workspace: addWorkspaceFlag(flags),
+		format:    flags.String("format", "table", "output format: table or json"),
+	}
+}
+
+// newBackend returns the HTTP API backend when an API URL is set, and the Redis backend otherwise.
+func (f backendFlags) newBackend(ctx context.Context) (backend, error) {
+	if *f.format != "table" && *f.format != "json" {
+		return nil, fmt.Errorf("invalid -format %q: must be table or json", *f.format)
+	}
+	if *f.apiURL != "" {
+		return &apiBackend{
+			baseURL: strings.TrimSuffix(*f.apiURL, "/") + "/api/v1",
+			apiKey:  *f.apiKey,
+			client:  &http.Client{Timeout: 30 * time.Second},
+		}, nil
+	}
+
+	rdb, err := newRedisClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The API key chooses the workspace of the HTTP API; here the links are read and written in its keyspace,
+	// within its quota
+	workspace, err := findWorkspace(*f.workspace)
+	if err != nil {
+		return nil, err
+	}
+	keyspace := redisRepo.InWorkspace(workspace.ID)
+	links := redisRepo.NewURLRepository(rdb, keyspace)
+	// Publish the lifecycle events like the server does, so webhook subscribers also hear about changes made here
+	webhookService := application.NewWebhookService(redisRepo.NewWebhookRepository(rdb, keyspace))
+	// Store the URLs in the same canonical form as the server
+	cfg := config.Load()
+	opts := []application.Option{
+		application.WithWorkspace(workspace),
+		application.WithEventPublisher(webhookService),
+		application.WithCanonicalOptions(application.CanonicalOptions{
+			SortQuery:     cfg.CanonicalSortQuery,
+			StripParams:   cfg.CanonicalStripParams,
+			StripFragment: cfg.CanonicalStripFragment,
+		}),
+		application.WithUTMPresets(redisRepo.NewUTMPresetRepository(rdb, keyspace)),
+		application.WithGroups(redisRepo.NewGroupRepository(rdb, keyspace)),
+		application.WithClickCounter(links),
+		application.WithGroupIndex(links),
+		application.WithDestinationIndex(links),
+	}
+	if cfg.Deduplicate {
+		opts = append(opts, application.WithDeduplication())
+	}
+	service := application.NewURLService(wrapRepository(links, rdb, keyspace), opts...)
+	return &serviceBackend{service: service}, nil
+}
+
+// serviceBackend runs the link commands against the repository, through the same URLService as the server.
+type serviceBackend struct {
+	service *application.URLService
+}
+
+func (b *serviceBackend) Create(ctx context.Context, req domain.AddURLRequest) (*domain.URL, error) {
+	return b.service.CreateURL(ctx, req)
+}
+
+func (b *serviceBackend) Get(ctx context.Context, shortCode string) (*domain.URL, error) {
+	return b.service.GetURL(ctx, shortCode)
+}
+
+func (b *serviceBackend) List(ctx context.Context, filter domain.URLFilter) ([]domain.URL, error) {
+	return b.service.ListURLs(ctx, filter)
+}
+
+func (b *serviceBackend) Delete(ctx context.Context, shortCode string) error {
+	return b.service.DeleteURL(ctx, shortCode)
+}
+
+func (b *serviceBackend) Renew(ctx context.Context, shortCode string, expiry time.Time) (*domain.URL, error) {
+	return b.service.RenewURL(ctx, shortCode, expiry)
+}
+
+func (b *serviceBackend) Stats(ctx context.Context, shortCode string) (*domain.URLStats, error) {
+	return b.service.GetStats(ctx, shortCode)
+}
+
+// apiBackend runs the link commands against a running server through its HTTP API.
+type apiBackend struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func (b *apiBackend) Create(ctx context.Context, req domain.AddURLRequest) (*domain.URL, error) {
+	var resp domain.AddSuccessResponse
+	if err := b.do(ctx, http.MethodPost, "/url/add", req, &resp); err != nil {
+		return nil, err
+	}
+	// The short code is the last path segment of the shortened URL
+	// The response does not repeat the metadata, which the server stores as sent apart from the tag normalization
+	return &domain.URL{
+		ShortCode:   path.Base(resp.ShortenedURL),
+		OriginalURL: resp.OriginalURL,
+		InputURL:    resp.InputURL,
+		Expiry:      resp.Expiry,
+		Title:       strings.TrimSpace(req.Title),
+		Description: strings.TrimSpace(req.Description),
+		Tags:        application.NormalizeTags(req.Tags),
+		Notes:       req.Notes,
+		CreatedBy:   strings.TrimSpace(req.CreatedBy),
+	}, nil
+}
+
+func (b *apiBackend) Get(ctx context.Context, shortCode string) (*domain.URL, error) {
+	var mapping domain.URLMapping
+	if err := b.do(ctx, http.MethodGet, "/url/"+url.PathEscape(shortCode), nil, &mapping); err != nil {
+		return nil, err
+	}
+	link := mapping.URL()
+	return &link, nil
+}
+
+func (b *apiBackend) List(ctx context.Context, filter domain.URLFilter) ([]domain.URL, error) {
+	query := url.Values{}
+	if !filter.ExpiringBefore.IsZero() {
+		query.Set("expiring_before", filter.ExpiringBefore.Format(time.RFC3339))
+	}
+	if filter.Tag != "" {
+		query.Set("tag", filter.Tag)
+	}
+	if filter.Pending != nil {
+		query.Set("pending", strconv.FormatBool(*filter.Pending))
+	}
+	endpoint := "/url/display"
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	var mappings []domain.URLMapping
+	if err := b.do(ctx, http.MethodGet, endpoint, nil, &mappings); err != nil {
+		return nil, err
+	}
+	urls := make([]domain.URL, len(mappings))
+	for i, mapping := range mappings {
+		urls[i] = mapping.URL()
+	}
+	return urls, nil
+}
+
+func (b *apiBackend) Delete(ctx context.Context, shortCode string) error {
+	return b.do(ctx, http.MethodDelete, "/url/"+url.PathEscape(shortCode), nil, nil)
+}
+
+func (b *apiBackend) Renew(ctx context.Context, shortCode string, expiry time.Time) (*domain.URL, error) {
+	var mapping domain.URLMapping
+	if err := b.do(ctx, http.MethodPut, "/url/"+url.PathEscape(shortCode), domain.UpdateURLRequest{Expiry: expiry}, &mapping); err != nil {
+		return nil, err
+	}
+	link := mapping.URL()
+	return &link, nil
+}
+
+func (b *apiBackend) Stats(ctx context.Context, shortCode string) (*domain.URLStats, error) {
+	var stats domain.URLStats
+	if err := b.do(ctx, http.MethodGet, "/url/"+url.PathEscape(shortCode)+"/stats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// do sends a request to the HTTP API and decodes the JSON response into out, if out is not nil.
+// Responses with a status other than 2xx are returned as errors carrying the server's message.
+func (b *apiBackend) do(ctx context.Context, method, endpoint string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+endpoint, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.apiKey != "" {
+		req.Header.Set(urlHandler.APIKeyHeader, b.apiKey)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var message struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(payload, &message) == nil && message.Message != "" {
+			return fmt.Errorf("%s: %s", resp.Status, message.Message)
+		}
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(payload)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return errors.New("unexpected response from the API: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/application"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+	urlHandler "github.com/terenzio/URL-Shortening-Service/infrastructure/http"
+	redisRepo "github.com/terenzio/URL-Shortening-Service/infrastructure/redis"
+)
+
+// TestBackends runs the same link operations against the Redis and the HTTP API backends.
+func TestBackends(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Setup a mini Redis server shared by the service and the API server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	service := application.NewURLService(redisRepo.NewURLRepository(rdb))
+
+	// Serve the management routes behind an API key, like main.go does
+	handler := urlHandler.NewHandler(service)
+	router := gin.New()
+	urlPage := router.Group("/api/v1/url", urlHandler.APIKeyAuth([]string{"k3y"}))
+	urlPage.GET("/display", handler.HandleHomePage)
+	urlPage.POST("/add", handler.HandleAddLink)
+	urlPage.GET("/:shortcode", handler.HandleGetLink)
+	urlPage.GET("/:shortcode/stats", handler.HandleLinkStats)
+	urlPage.PUT("/:shortcode", handler.HandleUpdateLink)
+	urlPage.DELETE("/:shortcode", handler.HandleDeleteLink)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	backends := map[string]backend{
+		"redis": &serviceBackend{service: service},
+		"api":   &apiBackend{baseURL: server.URL + "/api/v1", apiKey: "k3y", client: server.Client()},
+	}
+
+	for name, b := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			code := "ctl-" + name
+
+			created, err := b.Create(ctx, domain.AddURLRequest{OriginalURL: "https://example.com", CustomShortCode: code, Expiry: time.Now().Add(time.Hour), Tags: []string{"Launch", name}})
+			assert.NoError(t, err)
+			assert.Equal(t, code, created.ShortCode)
+			assert.Equal(t, []string{"launch", name}, created.Tags)
+
+			got, err := b.Get(ctx, code)
+			assert.NoError(t, err)
+			assert.Equal(t, "https://example.com", got.OriginalURL)
+			assert.Equal(t, []string{"launch", name}, got.Tags)
+
+			tagged, err := b.List(ctx, domain.URLFilter{Tag: name})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{code}, shortCodes(tagged))
+
+			expiring, err := b.List(ctx, domain.URLFilter{ExpiringBefore: time.Now().Add(2 * time.Hour)})
+			assert.NoError(t, err)
+			assert.Contains(t, shortCodes(expiring), code)
+
+			renewed, err := b.Renew(ctx, code, time.Now().Add(72*time.Hour))
+			assert.NoError(t, err)
+			assert.WithinDuration(t, time.Now().Add(72*time.Hour), renewed.Expiry, time.Minute)
+
+			expiring, err = b.List(ctx, domain.URLFilter{ExpiringBefore: time.Now().Add(2 * time.Hour)})
+			assert.NoError(t, err)
+			assert.NotContains(t, shortCodes(expiring), code, "A renewed link should no longer be expiring")
+
+			stats, err := b.Stats(ctx, code)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(0), stats.Clicks)
+
+			// Both backends validate links the same way, since the service does it
+			_, err = b.Create(ctx, domain.AddURLRequest{OriginalURL: "javascript:alert(1)", CustomShortCode: code + "-js"})
+			assert.ErrorContains(t, err, "http or https")
+			_, err = b.Create(ctx, domain.AddURLRequest{OriginalURL: "https://example.com", CustomShortCode: code + "-title", Title: strings.Repeat("a", 201)})
+			assert.ErrorContains(t, err, "title is longer than 200 characters")
+			listed, err := b.List(ctx, domain.URLFilter{})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{code}, shortCodes(listed), "Invalid links should not be stored")
+
+			assert.NoError(t, b.Delete(ctx, code))
+			_, err = b.Get(ctx, code)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("api without key", func(t *testing.T) {
+		b := &apiBackend{baseURL: server.URL + "/api/v1", client: server.Client()}
+		_, err := b.List(context.Background(), domain.URLFilter{})
+		assert.ErrorContains(t, err, "401")
+	})
+}
+
+// shortCodes returns the short codes of the links.
+func shortCodes(urls []domain.URL) []string {
+	codes := make([]string, len(urls))
+	for i, url := range urls {
+		codes[i] = url.ShortCode
+	}
+	return codes
+}
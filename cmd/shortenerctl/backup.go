@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/terenzio/URL-Shortening-Service/infrastructure/backup"
+)
+
+// runBackup implements "shortenerctl backup".
+func runBackup(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	output := flags.String("o", "-", "file to write the backup to, or - for standard output")
+	workspace := addWorkspaceFlag(flags)
+	flags.Parse(args)
+
+	repo, groups, err := newRepository(ctx, *workspace)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if *output != "-" {
+		file, err = os.Create(*output)
+		if err != nil {
+			return err
+		}
+		w = file
+	}
+
+	report, err := backup.Write(ctx, repo, groups, w)
+	if file != nil {
+		// The backup is only complete once it is on disk, so failing to sync or close the file fails the backup
+		if err == nil {
+			err = file.Sync()
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Backed up %d links and %d groups\n", report.Links, report.Groups)
+	return nil
+}
+
+// runRestore implements "shortenerctl restore".
+func runRestore(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	input := flags.String("i", "-", "backup file to read, or - for standard input")
+	onConflict := flags.String("on-conflict", string(backup.ConflictSkip), "what to do with links that already exist: skip, overwrite or fail")
+	workspace := addWorkspaceFlag(flags)
+	flags.Parse(args)
+
+	policy := backup.ConflictPolicy(*onConflict)
+	if policy != backup.ConflictSkip && policy != backup.ConflictOverwrite && policy != backup.ConflictFail {
+		return fmt.Errorf("invalid -on-conflict %q: must be skip, overwrite or fail", *onConflict)
+	}
+
+	repo, groups, err := newRepository(ctx, *workspace)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	report, err := backup.Restore(ctx, repo, groups, r, policy)
+	fmt.Fprintf(os.Stderr, "Restored %d groups, and restored %d, overwritten %d, skipped %d, invalid %d links\n",
+		report.Groups, report.Restored, report.Overwritten, report.Skipped, report.Invalid)
+	return err
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/application"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// runCreate implements "shortenerctl create [flags] <original url>".
+func runCreate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	common := addBackendFlags(flags)
+	code := flags.String("code", "", "custom short code; generated when empty")
+	expiry := flags.String("expiry", "", "expiry as an RFC 3339 time or a duration from now, e.g. 72h; defaults to 30 days")
+	forceNew := flags.Bool("force-new", false, "create a new short code even if a live link to the same URL exists")
+	title := flags.String("title", "", "title of the link")
+	description := flags.String("description", "", "description of the link")
+	tags := flags.String("tags", "", "comma-separated tags of the link")
+	notes := flags.String("notes", "", "free-form notes about the link")
+	createdBy := flags.String("created-by", "", "who created the link, shown on its preview page")
+	password := flags.String("password", "", "password that visitors must enter before being redirected")
+	maxClicks := flags.Int64("max-clicks", 0, "number of times the link can be followed before it stops working; 0 is unlimited")
+	activatesAt := flags.String("activates-at", "", "activation as an RFC 3339 time or a duration from now; the link does not redirect before it")
+	forwardQuery := flags.String("forward-query", "", "forward the query parameters of redirects: preserve, override or append the values set on the destination")
+	forwardPath := flags.Bool("forward-path", false, "append the path following the short code in redirects to the destination")
+	redirectType := flags.String("redirect-type", "", "redirect status: 301, 302, 307 or 308, or interstitial for an HTML page that redirects; defaults to the server's")
+	var utm domain.UTM
+	flags.StringVar(&utm.Source, "utm-source", "", "utm_source added to the original URL, e.g. newsletter")
+	flags.StringVar(&utm.Medium, "utm-medium", "", "utm_medium added to the original URL, e.g. email")
+	flags.StringVar(&utm.Campaign, "utm-campaign", "", "utm_campaign added to the original URL, e.g. spring_sale")
+	flags.StringVar(&utm.Term, "utm-term", "", "utm_term added to the original URL")
+	flags.StringVar(&utm.Content, "utm-content", "", "utm_content added to the original URL")
+	utmPreset := flags.String("utm-preset", "", "name of a UTM preset of the server whose parameters are added to the original URL; the -utm flags take precedence")
+	group := flags.String("group", "", "ID of a group of the server to add the link to")
+	var rules []domain.RoutingRule
+	flags.Func("route", "routing rule as criteria and a destination, e.g. 'os=ios,device=tablet https://...'; repeat it to add rules in order", func(value string) error {
+		rule, err := parseRoute(value)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+		return nil
+	})
+	var variants []domain.Variant
+	flags.Func("variant", "A/B variant as a weight and a destination, e.g. '70 https://...'; repeat it for each variant", func(value string) error {
+		variant, err := parseVariant(value)
+		if err != nil {
+			return err
+		}
+		variants = append(variants, variant)
+		return nil
+	})
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: shortenerctl create [flags] <original url>")
+	}
+	expiresAt, err := parseTime(*expiry)
+	if err != nil {
+		return err
+	}
+	activation, err := parseTime(*activatesAt)
+	if err != nil {
+		return err
+	}
+
+	req := domain.AddURLRequest{
+		OriginalURL:     flags.Arg(0),
+		CustomShortCode: *code,
+		Expiry:          expiresAt,
+		Title:           *title,
+		Description:     *description,
+		Tags:            splitList(*tags),
+		Notes:           *notes,
+		CreatedBy:       *createdBy,
+		Password:        *password,
+		MaxClicks:       *maxClicks,
+		ActivatesAt:     activation,
+		Rules:           rules,
+		Variants:        variants,
+		ForwardQuery:    *forwardQuery,
+		ForwardPath:     *forwardPath,
+		RedirectType:    *redirectType,
+		UTMPreset:       *utmPreset,
+		Group:           *group,
+		ForceNew:        *forceNew,
+	}
+	if !utm.IsZero() {
+		req.UTM = &utm
+	}
+
+	b, err := common.newBackend(ctx)
+	if err != nil {
+		return err
+	}
+	url, err := b.Create(ctx, req)
+	if err != nil {
+		return err
+	}
+	return printURLs(*common.format, []domain.URL{*url})
+}
+
+// runGet implements "shortenerctl get [flags] <short code>".
+func runGet(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("get", flag.ExitOnError)
+	common := addBackendFlags(flags)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: shortenerctl get [flags] <short code>")
+	}
+
+	b, err := common.newBackend(ctx)
+	if err != nil {
+		return err
+	}
+	url, err := b.Get(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return printURLs(*common.format, []domain.URL{*url})
+}
+
+// runList implements "shortenerctl list [flags]".
+func runList(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	common := addBackendFlags(flags)
+	expiringBefore := flags.String("expiring-before", "", "only list links expiring before an RFC 3339 time or a duration from now, e.g. 24h")
+	tag := flags.String("tag", "", "only list links with this tag")
+	pending := flags.String("pending", "", "only list links that are (true) or are not (false) waiting for their activation time")
+	flags.Parse(args)
+
+	before, err := parseTime(*expiringBefore)
+	if err != nil {
+		return err
+	}
+	filter := domain.URLFilter{ExpiringBefore: before, Tag: *tag}
+	if *pending != "" {
+		isPending, err := strconv.ParseBool(*pending)
+		if err != nil {
+			return fmt.Errorf("invalid -pending %q: expected true or false", *pending)
+		}
+		filter.Pending = &isPending
+	}
+
+	b, err := common.newBackend(ctx)
+	if err != nil {
+		return err
+	}
+	urls, err := b.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return printURLs(*common.format, urls)
+}
+
+// runDelete implements "shortenerctl delete [flags] <short code>".
+func runDelete(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("delete", flag.ExitOnError)
+	common := addBackendFlags(flags)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: shortenerctl delete [flags] <short code>")
+	}
+
+	b, err := common.newBackend(ctx)
+	if err != nil {
+		return err
+	}
+	if err := b.Delete(ctx, flags.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Deleted %s\n", flags.Arg(0))
+	return nil
+}
+
+// runRenew implements "shortenerctl renew [flags] <short code>".
+func runRenew(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("renew", flag.ExitOnError)
+	common := addBackendFlags(flags)
+	expiry := flags.String("expiry", application.DefaultExpiry.String(), "new expiry as an RFC 3339 time or a duration from now")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: shortenerctl renew [flags] <short code>")
+	}
+	expiresAt, err := parseTime(*expiry)
+	if err != nil {
+		return err
+	}
+
+	b, err := common.newBackend(ctx)
+	if err != nil {
+		return err
+	}
+	url, err := b.Renew(ctx, flags.Arg(0), expiresAt)
+	if err != nil {
+		return err
+	}
+	return printURLs(*common.format, []domain.URL{*url})
+}
+
+// runStats implements "shortenerctl stats [flags] <short code>".
+func runStats(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	common := addBackendFlags(flags)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: shortenerctl stats [flags] <short code>")
+	}
+
+	b, err := common.newBackend(ctx)
+	if err != nil {
+		return err
+	}
+	stats, err := b.Stats(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *common.format == "json" {
+		return printJSON(stats)
+	}
+	// Limited links show their clicks out of the maximum
+	clicks := strconv.FormatInt(stats.Clicks, 10)
+	if stats.MaxClicks > 0 {
+		clicks += "/" + strconv.FormatInt(stats.MaxClicks, 10)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SHORT CODE\tCLICKS\tEXPIRY\tORIGINAL URL")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", stats.ShortCode, clicks, formatExpiry(stats.Expiry), stats.OriginalURL)
+	if len(stats.Countries) > 0 {
+		// The clicks per country follow, most clicked first
+		countries := make([]string, 0, len(stats.Countries))
+		for country := range stats.Countries {
+			countries = append(countries, country)
+		}
+		sort.Slice(countries, func(i, j int) bool {
+			ci, cj := stats.Countries[countries[i]], stats.Countries[countries[j]]
+			return ci > cj || (ci == cj && countries[i] < countries[j])
+		})
+		fmt.Fprintln(w, "\nCOUNTRY\tCLICKS")
+		for _, country := range countries {
+			fmt.Fprintf(w, "%s\t%d\n", country, stats.Countries[country])
+		}
+	}
+	if len(stats.Variants) > 0 {
+		fmt.Fprintln(w, "\nVARIANT\tWEIGHT\tCLICKS")
+		for _, variant := range stats.Variants {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", variant.URL, variant.Weight, variant.Clicks)
+		}
+	}
+	return w.Flush()
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseRoute parses a routing rule given as comma-separated criteria, a space and the destination,
+// e.g. "os=android,device=mobile https://play.google.com/store/apps/details?id=app".
+// The criteria are os, device, bot and countries, whose codes are separated by "+", e.g. "countries=DE+AT".
+func parseRoute(value string) (domain.RoutingRule, error) {
+	var rule domain.RoutingRule
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return rule, fmt.Errorf("invalid route %q: expected criteria and a destination separated by a space", value)
+	}
+	rule.URL = fields[1]
+	for _, criterion := range strings.Split(fields[0], ",") {
+		key, val, _ := strings.Cut(criterion, "=")
+		switch key {
+		case "os":
+			rule.OS = val
+		case "device":
+			rule.Device = val
+		case "bot":
+			bot, err := strconv.ParseBool(val)
+			if err != nil {
+				return rule, fmt.Errorf("invalid route %q: bot must be true or false", value)
+			}
+			rule.Bot = &bot
+		case "countries":
+			rule.Countries = strings.Split(val, "+")
+		default:
+			return rule, fmt.Errorf("invalid route %q: unknown criterion %q - use os, device, bot or countries", value, key)
+		}
+	}
+	return rule, nil
+}
+
+// parseVariant parses an A/B variant given as its weight, a space and the destination, e.g. "70 https://example.com/a".
+func parseVariant(value string) (domain.Variant, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return domain.Variant{}, fmt.Errorf("invalid variant %q: expected a weight and a destination separated by a space", value)
+	}
+	weight, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return domain.Variant{}, fmt.Errorf("invalid variant %q: the weight must be a number", value)
+	}
+	return domain.Variant{URL: fields[1], Weight: weight}, nil
+}
+
+// parseTime parses an RFC 3339 time, or a duration that is added to the current time.
+// An empty value returns the zero time.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(duration), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected an RFC 3339 time like 2024-04-02T00:00:00Z or a duration like 72h", value)
+	}
+	return t, nil
+}
+
+// printURLs writes the links to standard output as a table or as JSON.
+func printURLs(format string, urls []domain.URL) error {
+	if format == "json" {
+		mappings := make([]domain.URLMapping, len(urls))
+		for i, url := range urls {
+			mappings[i] = domain.NewURLMapping(url)
+		}
+		return printJSON(mappings)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SHORT CODE\tEXPIRY\tACTIVATES\tTAGS\tORIGINAL URL")
+	now := time.Now()
+	for _, url := range urls {
+		activates := "-"
+		if url.Pending(now) {
+			activates = url.ActivatesAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", url.ShortCode, formatExpiry(url.Expiry), activates, strings.Join(url.Tags, ","), url.OriginalURL)
+	}
+	return w.Flush()
+}
+
+// printJSON writes the value to standard output as indented JSON.
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// formatExpiry formats an expiry for the table output.
+func formatExpiry(expiry time.Time) string {
+	if expiry.IsZero() {
+		return "never"
+	}
+	return expiry.Local().Format("2006-01-02 15:04:05")
+}
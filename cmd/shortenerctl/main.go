@@ -0,0 +1,138 @@
+// Command shortenerctl is the operations tool of the URL Shortening Service.
+//
+// Usage:
+//
+//	shortenerctl <command> [flags]
+//
+// Run "shortenerctl help" for the list of commands.
+// The Redis connection is configured with the same SHORTENER_REDIS_* environment variables as the server.
+// The link commands can also go through the HTTP API instead, with the -api and -api-key flags
+// or the SHORTENER_API_URL and SHORTENER_API_KEY environment variables.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+	"github.com/terenzio/URL-Shortening-Service/infrastructure/bloom"
+	"github.com/terenzio/URL-Shortening-Service/infrastructure/cache"
+	"github.com/terenzio/URL-Shortening-Service/infrastructure/config"
+	redisRepo "github.com/terenzio/URL-Shortening-Service/infrastructure/redis"
+)
+
+// command is a subcommand of shortenerctl.
+type command struct {
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+// commands lists every subcommand by name.
+var commands = map[string]command{
+	"backup":  {summary: "write all links with their remaining TTL to a gzip-compressed NDJSON file", run: runBackup},
+	"restore": {summary: "restore the links of a backup file", run: runRestore},
+	"create":  {summary: "shorten a URL", run: runCreate},
+	"get":     {summary: "show a link", run: runGet},
+	"list":    {summary: "list links, optionally only those expiring before a time", run: runList},
+	"delete":  {summary: "delete a link", run: runDelete},
+	"renew":   {summary: "move the expiry of a link", run: runRenew},
+	"stats":   {summary: "show the clicks on a link", run: runStats},
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		usage()
+		return
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "shortenerctl: unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(context.Background(), os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "shortenerctl %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the list of subcommands.
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: shortenerctl <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun \"shortenerctl <command> -h\" for the flags of a command.")
+}
+
+// newRedisClient connects to the Redis server configured in the environment.
+func newRedisClient(ctx context.Context) (*redis.Client, error) {
+	cfg := config.Load()
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis at %s: %w", cfg.RedisAddr, err)
+	}
+	return rdb, nil
+}
+
+// addWorkspaceFlag registers the flag of the workspace whose links a command works on.
+func addWorkspaceFlag(flags *flag.FlagSet) *string {
+	return flags.String("workspace", os.Getenv("SHORTENER_WORKSPACE"), "workspace of the links when Redis is used directly; the default workspace when empty")
+}
+
+// findWorkspace returns the workspace with the given ID from the workspaces file configured in the environment,
+// or the default workspace for an empty ID.
+func findWorkspace(id string) (domain.Workspace, error) {
+	if id == "" {
+		return domain.Workspace{}, nil
+	}
+	workspaces, err := config.LoadWorkspaces(config.Load().WorkspacesFile)
+	if err != nil {
+		return domain.Workspace{}, err
+	}
+	for _, workspace := range workspaces {
+		if workspace.ID == id {
+			return workspace, nil
+		}
+	}
+	return domain.Workspace{}, fmt.Errorf("unknown workspace %q", id)
+}
+
+// newRepository connects to the Redis server configured in the environment and returns the URL and group repositories of a workspace.
+func newRepository(ctx context.Context, workspaceID string) (domain.URLRepository, domain.GroupRepository, error) {
+	workspace, err := findWorkspace(workspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	rdb, err := newRedisClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyspace := redisRepo.InWorkspace(workspace.ID)
+	return wrapRepository(redisRepo.NewURLRepository(rdb, keyspace), rdb, keyspace), redisRepo.NewGroupRepository(rdb, keyspace), nil
+}
+
+// wrapRepository wraps a URL repository of the Redis server so that the running servers notice its writes:
+// the new short codes are journaled for their Bloom filters, and the changed ones are dropped from their caches.
+// Neither wrapper keeps anything in memory here.
+func wrapRepository(links *redisRepo.URLRepository, rdb *redis.Client, opts ...redisRepo.Option) domain.URLRepository {
+	var repo domain.URLRepository = links
+	repo = bloom.NewURLRepository(repo, bloom.Options{Store: redisRepo.NewBloomStore(rdb, opts...)})
+	return cache.NewURLRepository(repo, cache.Options{Invalidator: redisRepo.NewInvalidator(rdb, opts...)})
+}
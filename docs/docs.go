@@ -24,50 +24,283 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/redirect/{shortcode}": {
+        "/domains": {
             "get": {
-                "description": "NOTE: Copy the full url including the short code to the browser to be redirected. Do not use the Swagger UI here as it does not support redirection.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "REDIRECT"
+                    "DOMAIN"
                 ],
-                "summary": "Redirects the user to the original URL based on the input short code.",
+                "summary": "Lists the custom domains of the workspace, verified or claimed, sorted by name.",
+                "responses": {
+                    "200": {
+                        "description": "Custom domains",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.CustomDomain"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE 1: Create a TXT record at \"verification_host\" with the \"verification_value\" of the response, then call /domains/{domain}/verify before \"expires_at\". Other workspaces can claim the domain too; the one that verifies it owns it.\nNOTE 2: Once verified, redirects sent to the domain go to the workspace, and its shortened URLs use the domain. Point the domain at the service, e.g. with a CNAME record.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DOMAIN"
+                ],
+                "summary": "Claims a custom domain, to be verified with a DNS TXT record.",
+                "parameters": [
+                    {
+                        "description": "Domain, e.g. go.team.example",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.AddDomainRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Claimed domain",
+                        "schema": {
+                            "$ref": "#/definitions/domain.CustomDomain"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or reserved domain, or the workspace already has it",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/domains/{domain}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DOMAIN"
+                ],
+                "summary": "Displays a custom domain, with its verification record.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Short Code",
-                        "name": "shortcode",
+                        "description": "Domain",
+                        "name": "domain",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "307": {
-                        "description": "Redirected to original url - example: http://localhost:9000/api/v1/redirect/2v5ompxD",
+                    "200": {
+                        "description": "Custom domain",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/domain.CustomDomain"
                         }
                     },
-                    "400": {
-                        "description": "Parameter missing - enter the short code in the URL path",
+                    "404": {
+                        "description": "The workspace has no such domain",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE: Redirects sent to the domain stop working at once, including those of the shortened URLs already handed out.",
+                "tags": [
+                    "DOMAIN"
+                ],
+                "summary": "Deletes a custom domain.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Domain",
+                        "name": "domain",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Deleted"
                     },
                     "404": {
-                        "description": "No original URL exists for the given short code",
+                        "description": "The workspace has no such domain",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/url/add": {
+        "/domains/{domain}/verify": {
             "post": {
-                "description": "NOTE 1: In the JSON body, the \"original_url\" should contain proper formatting with either http or https. Example: https://www.google.com.\nNOTE 2: In the JSON body, the \"expiry\" date is optional, with the default expiration set to 30 days from now. The expiry time can be customized like this example: 2024-04-02T00:00:00Z.\nNOTE 3: In the JSON body, the \"custom_short_code\" is also optional. A unique custom short code can be set for the shortened URL.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE: DNS changes can take a while to be visible; the verification can be retried until it succeeds.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DOMAIN"
+                ],
+                "summary": "Verifies a custom domain with its DNS TXT record.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Domain",
+                        "name": "domain",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Verified domain",
+                        "schema": {
+                            "$ref": "#/definitions/domain.CustomDomain"
+                        }
+                    },
+                    "400": {
+                        "description": "The verification record was not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "The workspace has no such domain",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/groups": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GROUP"
+                ],
+                "summary": "Lists the link groups, sorted by ID.",
+                "responses": {
+                    "200": {
+                        "description": "Groups",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Group"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GROUP"
+                ],
+                "summary": "Displays a link group.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Group",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Group"
+                        }
+                    },
+                    "404": {
+                        "description": "No group exists for the given ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE 1: The ID is lowercased and may use up to 64 letters, digits, \"_\", \"-\" and \".\". Links are added to the group by setting \"group\" to its ID, when they are added or updated.\nNOTE 2: The name is optional and defaults to the ID.",
                 "consumes": [
                     "application/json"
                 ],
@@ -75,98 +308,2026 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "URL"
+                    "GROUP"
                 ],
-                "summary": "Creates a shortened link for the given original URL.",
+                "summary": "Creates or updates a link group.",
                 "parameters": [
                     {
-                        "description": "Original URL, Expiry Time (optional), Custom Short Code (optional)",
-                        "name": "original_url",
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Name and Description (both optional)",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/domain.AddURLRequest"
+                            "$ref": "#/definitions/domain.SaveGroupRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Shortened URL",
+                        "description": "Saved group",
                         "schema": {
-                            "$ref": "#/definitions/domain.AddSuccessResponse"
+                            "$ref": "#/definitions/domain.Group"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE: The links of the group are kept, without a group.",
+                "tags": [
+                    "GROUP"
+                ],
+                "summary": "Deletes a link group.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Deleted"
+                    },
+                    "404": {
+                        "description": "No group exists for the given ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/url/display": {
-            "get": {
-                "description": "Displays the list of all shortened URLs mapped to their original ones in JSON format.",
+        "/groups/{id}/expire": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE: The links stop working right away, and are reported by \"link.expired\" webhook events like links that reached their expiry. The group itself is kept.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "URL"
+                    "GROUP"
+                ],
+                "summary": "Expires every link of a group at once.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Displays the list of all shortened URLs mapped to their original ones in JSON format.",
                 "responses": {
                     "200": {
-                        "description": "URL Mappings",
+                        "description": "Number of links expired",
                         "schema": {
-                            "$ref": "#/definitions/domain.URLMapping"
+                            "$ref": "#/definitions/domain.GroupOperationResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No group exists for the given ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "domain.AddSuccessResponse": {
-            "type": "object",
-            "properties": {
-                "expiry": {
-                    "type": "string"
-                },
-                "original_url": {
-                    "type": "string"
-                },
-                "shortened_url": {
-                    "type": "string"
+        },
+        "/groups/{id}/links": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GROUP"
+                ],
+                "summary": "Lists the live links of a group, soonest to expire first.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL Mappings",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.URLMapping"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No group exists for the given ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
                 }
             }
         },
-        "domain.AddURLRequest": {
+        "/groups/{id}/renew": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GROUP"
+                ],
+                "summary": "Renews every link of a group until the same time.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New expiry time, e.g. 2024-06-01T00:00:00Z",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.RenewGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Number of links renewed",
+                        "schema": {
+                            "$ref": "#/definitions/domain.GroupOperationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No group exists for the given ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/{id}/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GROUP"
+                ],
+                "summary": "Displays the clicks on the live links of a group, in total, per country and per link.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Group statistics",
+                        "schema": {
+                            "$ref": "#/definitions/domain.GroupStats"
+                        }
+                    },
+                    "404": {
+                        "description": "No group exists for the given ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/qr/{shortcode}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE 1: The code encodes the public shortened URL, so scanning it counts a click like following the link does.\nNOTE 2: /qr/{shortcode} is public like the redirects, and resolves the short code in the workspace of the domain it is sent to. It is the \"qr_code_url\" returned by /url/add.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Renders a QR code for a shortened URL.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short Code",
+                        "name": "shortcode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Image format: png or svg (default png)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Width and height in pixels, between 64 and 2048 (default 256)",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Quiet zone around the code in modules, between 0 and 16 (default 4)",
+                        "name": "margin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Error-correction level: L, M, Q or H (default M)",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Color of the dark modules as hex, e.g. 000000 or #1f6feb80 (default black)",
+                        "name": "fg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Color of the light modules as hex (default white)",
+                        "name": "bg",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "QR code",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid rendering options",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No original URL exists for the given short code",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/redirect/{shortcode}": {
+            "get": {
+                "description": "NOTE 1: Copy the full url including the short code to the browser to be redirected. Do not use the Swagger UI here as it does not support redirection.\nNOTE 2: Append \"+\" to the short code, or set \"preview\" to true, to get a page showing where the link leads instead. Previews are not counted as clicks.\nNOTE 3: Protected links respond with a password prompt, and scheduled links with a 404 status until their activation time. Rules, variants and forwarding pick the destination as set on the link.\nNOTE 4: The status is the \"redirect_type\" of the link, or the default of the server (307 unless configured otherwise).",
+                "produces": [
+                    "text/plain",
+                    "text/html"
+                ],
+                "tags": [
+                    "REDIRECT"
+                ],
+                "summary": "Redirects the user to the original URL based on the input short code.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short Code, optionally followed by + for a preview",
+                        "name": "shortcode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Show the preview page instead of redirecting",
+                        "name": "preview",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Preview page, or redirect page of an interstitial link",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "301": {
+                        "description": "Permanently redirected to original url",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "302": {
+                        "description": "Redirected to original url",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "307": {
+                        "description": "Redirected to original url - example: http://localhost:9000/api/v1/redirect/2v5ompxD",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "308": {
+                        "description": "Permanently redirected to original url",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Parameter missing - enter the short code in the URL path, or invalid path after it",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Password prompt of a protected link",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "No original URL exists for the given short code, or the link is not active yet",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "The link has reached its maximum number of clicks",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "NOTE 1: Following a password-protected link shows a prompt, which posts the password here. When it matches, a signed cookie unlocks the link for a while (1 hour by default) and the client is sent back to the link, or to its preview.\nNOTE 2: Wrong passwords are counted per link and client. After too many, the password cannot be entered from that client until the attempts expire.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "REDIRECT"
+                ],
+                "summary": "Checks the password entered for a protected link.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short Code, optionally followed by + for a preview",
+                        "name": "shortcode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password of the link",
+                        "name": "password",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "303": {
+                        "description": "Unlocked - redirected back to the link",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Parameter missing - enter the short code in the URL path",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Wrong password - the prompt is shown again",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "No original URL exists for the given short code",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many wrong passwords",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/add": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE 1: In the JSON body, the \"original_url\" should contain proper formatting with either http or https. Example: https://www.google.com.\nNOTE 2: In the JSON body, the \"expiry\" date is optional, with the default expiration set to 30 days from now. The expiry time can be customized like this example: 2024-04-02T00:00:00Z.\nNOTE 3: In the JSON body, the \"custom_short_code\" is also optional. A unique custom short code can be set for the shortened URL.\nNOTE 4: The other fields are optional, and described in the request model.\nNOTE 5: With deduplication, an original URL shortened again returns the live link of the same API key. Set \"force_new\" to true to always get a new short code.\nNOTE 6: The \"shortened_url\" and \"qr_code_url\" use the domain of the workspace, or its custom domain verified first, when it has one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Creates a shortened link for the given original URL.",
+                "parameters": [
+                    {
+                        "description": "Original URL, Expiry Time (optional), Custom Short Code (optional)",
+                        "name": "original_url",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.AddURLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Shortened URL",
+                        "schema": {
+                            "$ref": "#/definitions/domain.AddSuccessResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "The workspace holds its maximum number of links",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/url/bulk": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE 1: The JSON body is an array of the same objects accepted by /url/add. The number of items is limited (500 by default). Bulk creation is never deduplicated.\nNOTE 2: Every item succeeds or fails on its own. The results are returned in the order of the request, with an \"error\" for each failed item.\nNOTE 3: The status is 200 when every item succeeded, 207 when only some did, and 400 when none did.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Creates shortened links for a list of original URLs.",
+                "parameters": [
+                    {
+                        "description": "List of Original URL, Expiry Time (optional), Custom Short Code (optional)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.AddURLRequest"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Every item was shortened",
+                        "schema": {
+                            "$ref": "#/definitions/domain.BulkAddURLResponse"
+                        }
+                    },
+                    "207": {
+                        "description": "Some items were shortened",
+                        "schema": {
+                            "$ref": "#/definitions/domain.BulkAddURLResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "No item was shortened, or the request is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/domain.BulkAddURLResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Too many items",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/url/display": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Displays the list of all shortened URLs mapped to their original ones in JSON format.\nNOTE: Filter with \"expiring_before\" (soonest first), \"tag\" and \"pending\". The filters can be combined.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Displays the list of all shortened URLs mapped to their original ones in JSON format.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only list URLs expiring before this time",
+                        "name": "expiring_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only list URLs with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only list URLs that are (true) or are not (false) waiting for their activation time",
+                        "name": "pending",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL Mappings",
+                        "schema": {
+                            "$ref": "#/definitions/domain.URLMapping"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid expiring_before time or pending flag",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/url/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE: The mappings are streamed as they are read from the data store, so the export works for any number of links. The CSV layout is the same as the one accepted by /url/import.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Exports every URL mapping as CSV or NDJSON.",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "csv (default) or ndjson",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL mappings",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/url/import": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE 1: The CSV columns are short_code, original_url and expiry. A header row with these names is optional.\nNOTE 2: An empty short_code gets a generated one. An empty expiry defaults to 30 days from now; otherwise it must be an RFC 3339 time in the future, e.g. 2024-04-02T00:00:00Z.\nNOTE 3: Send the CSV as the raw request body, or as the \"file\" field of a multipart form. Set dry_run=true to validate the file without storing anything.\nNOTE 4: Existing short codes are never overwritten; such rows are reported as errors.",
+                "consumes": [
+                    "text/csv",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Imports URL mappings from a CSV file.",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Validate only, without storing",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Validation report",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "The file could not be read",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{shortcode}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Displays a single shortened URL mapped to its original one.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short Code",
+                        "name": "shortcode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL Mapping",
+                        "schema": {
+                            "$ref": "#/definitions/domain.URLMapping"
+                        }
+                    },
+                    "404": {
+                        "description": "No original URL exists for the given short code",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE: Every field in the JSON body is optional, and fields that are left out keep their current value. The request model describes how each field is cleared.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Updates the original URL, the expiry and/or the metadata of an existing short code.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short Code",
+                        "name": "shortcode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Original URL, Expiry Time, Title, Description, Tags and Notes (all optional)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.UpdateURLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated URL Mapping",
+                        "schema": {
+                            "$ref": "#/definitions/domain.URLMapping"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No original URL exists for the given short code",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Deletes a short code.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short Code",
+                        "name": "shortcode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Deleted"
+                    },
+                    "404": {
+                        "description": "No original URL exists for the given short code",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{shortcode}/qr": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE 1: The code encodes the public shortened URL, so scanning it counts a click like following the link does.\nNOTE 2: /qr/{shortcode} is public like the redirects, and resolves the short code in the workspace of the domain it is sent to. It is the \"qr_code_url\" returned by /url/add.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Renders a QR code for a shortened URL.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short Code",
+                        "name": "shortcode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Image format: png or svg (default png)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Width and height in pixels, between 64 and 2048 (default 256)",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Quiet zone around the code in modules, between 0 and 16 (default 4)",
+                        "name": "margin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Error-correction level: L, M, Q or H (default M)",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Color of the dark modules as hex, e.g. 000000 or #1f6feb80 (default black)",
+                        "name": "fg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Color of the light modules as hex (default white)",
+                        "name": "bg",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "QR code",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid rendering options",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No original URL exists for the given short code",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/url/{shortcode}/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "URL"
+                ],
+                "summary": "Displays the number of clicks on a shortened URL.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short Code",
+                        "name": "shortcode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL Statistics",
+                        "schema": {
+                            "$ref": "#/definitions/domain.URLStats"
+                        }
+                    },
+                    "404": {
+                        "description": "No original URL exists for the given short code",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/utm-presets": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "UTM"
+                ],
+                "summary": "Lists the UTM presets, sorted by name.",
+                "responses": {
+                    "200": {
+                        "description": "Presets",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.UTMPreset"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/utm-presets/{name}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "UTM"
+                ],
+                "summary": "Displays a UTM preset.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Preset name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Preset",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UTMPreset"
+                        }
+                    },
+                    "404": {
+                        "description": "No preset exists for the given name",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE 1: The name is lowercased and may use up to 64 letters, digits, \"_\", \"-\" and \".\". Links are tagged with the preset by setting \"utm_preset\" to its name when they are added.\nNOTE 2: Every parameter is optional, so that a preset can leave some to each link, e.g. the content. Source, medium and campaign are lowercased.\nNOTE 3: Links already tagged with a preset keep their parameters when it is changed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "UTM"
+                ],
+                "summary": "Creates or replaces a UTM preset.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Preset name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Source, Medium, Campaign, Term and Content",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.UTM"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Saved preset",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UTMPreset"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE: Links already tagged with the preset keep their parameters.",
+                "tags": [
+                    "UTM"
+                ],
+                "summary": "Deletes a UTM preset.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Preset name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Deleted"
+                    },
+                    "404": {
+                        "description": "No preset exists for the given name",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "WEBHOOK"
+                ],
+                "summary": "Lists all registered webhook subscribers.",
+                "responses": {
+                    "200": {
+                        "description": "Subscribers",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.WebhookSubscriber"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE 1: The \"events\" list is optional. Leave it empty to receive every event: link.created, link.updated, link.deleted, link.expired and link.first_clicked.\nNOTE 2: The \"secret\" is optional. When it is left out a secret is generated. The secret is only returned in this response.\nNOTE 3: Each delivery is signed in the X-Webhook-Signature header as \"sha256=\" + hex(HMAC-SHA256(secret, X-Webhook-Timestamp + \".\" + body)).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "WEBHOOK"
+                ],
+                "summary": "Registers a webhook subscriber for link lifecycle events.",
+                "parameters": [
+                    {
+                        "description": "Subscriber URL, Secret (optional), Events (optional)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.AddWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Registered subscriber",
+                        "schema": {
+                            "$ref": "#/definitions/domain.WebhookSubscriber"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "WEBHOOK"
+                ],
+                "summary": "Deletes a webhook subscriber.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscriber ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Deleted"
+                    },
+                    "404": {
+                        "description": "No subscriber exists for the given ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/workspace": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "NOTE: Requests with the keys of SHORTENER_API_KEYS, or without keys when none are configured, belong to the default workspace, which has an empty ID.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "WORKSPACE"
+                ],
+                "summary": "Displays the workspace of the API key, with the number of links it holds and its quota.",
+                "responses": {
+                    "200": {
+                        "description": "Workspace",
+                        "schema": {
+                            "$ref": "#/definitions/domain.WorkspaceUsage"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "domain.AddDomainRequest": {
+            "type": "object",
+            "properties": {
+                "domain": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.AddSuccessResponse": {
+            "type": "object",
+            "properties": {
+                "expiry": {
+                    "type": "string"
+                },
+                "input_url": {
+                    "type": "string"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "qr_code_url": {
+                    "type": "string"
+                },
+                "shortened_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.AddURLRequest": {
+            "type": "object",
+            "properties": {
+                "activates_at": {
+                    "description": "ActivatesAt keeps the link from resolving before that time, which must be before the expiry.",
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "custom_short_code": {
+                    "description": "CustomShortCode uses letters, digits, \"_\" and \"-\", up to 64 characters; generated when empty.",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expiry": {
+                    "description": "Expiry defaults to 30 days from now.",
+                    "type": "string"
+                },
+                "force_new": {
+                    "description": "ForceNew creates a new short code even when deduplication would return an existing link.",
+                    "type": "boolean"
+                },
+                "forward_path": {
+                    "description": "ForwardPath appends the path following the short code in redirects to the destination.",
+                    "type": "boolean"
+                },
+                "forward_query": {
+                    "description": "ForwardQuery forwards the query of redirects, keeping (\"preserve\"), replacing (\"override\") or adding to (\"append\")\nthe parameters of the destination.",
+                    "type": "string"
+                },
+                "group": {
+                    "description": "Group adds the link to an existing group.",
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks makes the link respond with a 410 status once followed that many times; 0 leaves it unlimited.",
+                    "type": "integer"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Password must be entered by visitors before being redirected; at most 72 bytes, and only its hash is stored.",
+                    "type": "string"
+                },
+                "redirect_type": {
+                    "description": "RedirectType is \"301\", \"302\", \"307\", \"308\", or \"interstitial\" for an HTML page that redirects.",
+                    "type": "string"
+                },
+                "rules": {
+                    "description": "Rules send the visitors they match, by operating system, device class, bot or country, to other destinations.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.RoutingRule"
+                    }
+                },
+                "tags": {
+                    "description": "Tags are lowercased, and use letters, digits, \"_\", \"-\" and \".\".",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "utm": {
+                    "description": "UTM parameters are added to the canonical original URL, on top of those of UTMPreset; source, medium and campaign are required.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.UTM"
+                        }
+                    ]
+                },
+                "utm_preset": {
+                    "type": "string"
+                },
+                "variants": {
+                    "description": "Variants split the other visitors between weighted destinations, each visitor always getting the same one.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Variant"
+                    }
+                }
+            }
+        },
+        "domain.AddWebhookRequest": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.EventType"
+                    }
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.BulkAddURLResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.BulkAddURLResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.BulkAddURLResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "expiry": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "shortened_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.CustomDomain": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "domain": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "verification_host": {
+                    "type": "string"
+                },
+                "verification_value": {
+                    "type": "string"
+                },
+                "verified": {
+                    "type": "boolean"
+                },
+                "verified_at": {
+                    "type": "string"
+                },
+                "workspace": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.EventType": {
+            "type": "string",
+            "enum": [
+                "link.created",
+                "link.updated",
+                "link.deleted",
+                "link.expired",
+                "link.first_clicked"
+            ],
+            "x-enum-varnames": [
+                "EventLinkCreated",
+                "EventLinkUpdated",
+                "EventLinkDeleted",
+                "EventLinkExpired",
+                "EventLinkFirstClicked"
+            ]
+        },
+        "domain.Group": {
             "type": "object",
             "properties": {
-                "custom_short_code": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
                     "type": "string"
                 },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.GroupLinkStats": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
                 "expiry": {
                     "type": "string"
                 },
                 "original_url": {
                     "type": "string"
+                },
+                "short_code": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.GroupOperationResponse": {
+            "type": "object",
+            "properties": {
+                "affected": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.GroupStats": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "countries": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "group": {
+                    "$ref": "#/definitions/domain.Group"
+                },
+                "links": {
+                    "type": "integer"
+                },
+                "per_link": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.GroupLinkStats"
+                    }
+                }
+            }
+        },
+        "domain.ImportReport": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ImportRowError"
+                    }
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "truncated": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "domain.ImportRowError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "short_code": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.PageMetadata": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "fetched_at": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "site_name": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.RenewGroupRequest": {
+            "type": "object",
+            "properties": {
+                "expiry": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.RoutingRule": {
+            "type": "object",
+            "properties": {
+                "bot": {
+                    "type": "boolean"
+                },
+                "countries": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "DE",
+                        "AT",
+                        "CH"
+                    ]
+                },
+                "device": {
+                    "type": "string",
+                    "example": "mobile"
+                },
+                "os": {
+                    "type": "string",
+                    "example": "ios"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://apps.apple.com/app/id284882215"
+                }
+            }
+        },
+        "domain.SaveGroupRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
                 }
             }
         },
         "domain.URLMapping": {
             "type": "object",
             "properties": {
+                "activates_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expiry": {
+                    "type": "string"
+                },
+                "forward_path": {
+                    "type": "boolean"
+                },
+                "forward_query": {
+                    "type": "string"
+                },
+                "group": {
+                    "type": "string"
+                },
+                "input_url": {
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "type": "integer"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "page": {
+                    "$ref": "#/definitions/domain.PageMetadata"
+                },
+                "pending": {
+                    "type": "boolean"
+                },
+                "protected": {
+                    "type": "boolean"
+                },
+                "redirect_type": {
+                    "type": "string"
+                },
+                "remaining_clicks": {
+                    "type": "integer"
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.RoutingRule"
+                    }
+                },
+                "short_code": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Variant"
+                    }
+                }
+            }
+        },
+        "domain.URLStats": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "countries": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
                 "expiry": {
                     "type": "string"
                 },
+                "max_clicks": {
+                    "type": "integer"
+                },
                 "original_url": {
                     "type": "string"
                 },
+                "remaining_clicks": {
+                    "type": "integer"
+                },
                 "short_code": {
                     "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.VariantStats"
+                    }
+                }
+            }
+        },
+        "domain.UTM": {
+            "type": "object",
+            "properties": {
+                "campaign": {
+                    "type": "string"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "medium": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "term": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.UTMPreset": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "utm": {
+                    "$ref": "#/definitions/domain.UTM"
+                }
+            }
+        },
+        "domain.UpdateURLRequest": {
+            "type": "object",
+            "properties": {
+                "activates_at": {
+                    "description": "ActivatesAt that is not in the future activates the link at once.",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "expiry": {
+                    "type": "string"
+                },
+                "forward_path": {
+                    "type": "boolean"
+                },
+                "forward_query": {
+                    "description": "ForwardQuery stops forwarding when empty.",
+                    "type": "string"
+                },
+                "group": {
+                    "description": "Group moves the link to another existing group; an empty one removes it from its group.",
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks counts the clicks already made; 0 removes the limit.",
+                    "type": "integer"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "original_url": {
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Password replaces the password of the link; an empty one makes it public.",
+                    "type": "string"
+                },
+                "redirect_type": {
+                    "description": "RedirectType goes back to the server default when empty.",
+                    "type": "string"
+                },
+                "rules": {
+                    "description": "Rules and Variants are removed with an empty list.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.RoutingRule"
+                    }
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "description": "Title, Description and Notes are cleared with an empty string, and Tags with an empty list.",
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Variant"
+                    }
+                }
+            }
+        },
+        "domain.Variant": {
+            "type": "object",
+            "properties": {
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/landing-b"
+                },
+                "weight": {
+                    "type": "integer",
+                    "example": 50
+                }
+            }
+        },
+        "domain.VariantStats": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "weight": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.WebhookSubscriber": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.EventType"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.WorkspaceUsage": {
+            "type": "object",
+            "properties": {
+                "domain": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "links": {
+                    "type": "integer"
+                },
+                "max_links": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
                 }
             }
         }
     },
-    "externalDocs": {
-        "description": "OpenAPI",
-        "url": "https://swagger.io/resources/open-api/"
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "Required on the management endpoints when SHORTENER_API_KEYS is set.",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        }
     }
 }`
 
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrDomainNotFound is returned when a custom domain is not registered, or belongs to another workspace.
+var ErrDomainNotFound = errors.New("domain not found")
+
+// ErrInvalidDomain is returned when a custom domain is not a valid host name, or is reserved for the service.
+var ErrInvalidDomain = errors.New("invalid domain")
+
+// ErrDomainTaken is returned when a workspace registers a custom domain it has already registered.
+var ErrDomainTaken = errors.New("domain already registered")
+
+// ErrDomainNotVerified is returned when the DNS of a custom domain does not hold its verification record.
+var ErrDomainNotVerified = errors.New("domain not verified")
+
+// DomainVerificationPrefix is the label before a custom domain of the host holding its TXT verification record.
+const DomainVerificationPrefix = "_shortener-challenge."
+
+// DomainVerificationValue is the prefix of the value of the TXT verification record, before the token.
+const DomainVerificationValue = "shortener-verification="
+
+// DomainClaimExpiry is how long a custom domain can stay unverified; the claim is then dropped.
+const DomainClaimExpiry = 7 * 24 * time.Hour
+
+// CustomDomain is a domain registered by a workspace for its short links, e.g. go.team.example.
+// Redirects sent to the domain go to the workspace once the domain is verified, and its shortened URLs use the domain.
+// The domain is verified by a TXT record holding the token at VerificationHost, which proves the workspace controls its DNS.
+// Until then it is a claim of the workspace, which expires at ExpiresAt; several workspaces can claim the same domain,
+// and the one that verifies it owns it.
+type CustomDomain struct {
+	Domain            string     `json:"domain"`
+	Workspace         string     `json:"workspace"`
+	Token             string     `json:"token"`
+	VerificationHost  string     `json:"verification_host"`
+	VerificationValue string     `json:"verification_value"`
+	Verified          bool       `json:"verified"`
+	CreatedAt         time.Time  `json:"created_at"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+}
+
+// AddDomainRequest represents the request body for registering a custom domain.
+type AddDomainRequest struct {
+	Domain string `json:"domain"`
+}
+
+// DomainRepository is an interface that abstracts the registry of custom domains.
+// Verified domains are shared by every workspace, so that a domain belongs to a single one;
+// unverified claims are kept per workspace, so that claiming a domain never blocks the workspace that controls it.
+type DomainRepository interface {
+	// SaveDomain stores a custom domain. A verified domain replaces the domain of the same name of any workspace,
+	// and the claim of its own; an unverified one is stored as a claim of its workspace.
+	SaveDomain(ctx context.Context, domain CustomDomain) error
+	// FindDomain retrieves the verified custom domain with the given name, whatever its workspace.
+	FindDomain(ctx context.Context, name string) (*CustomDomain, error)
+	// ListDomains retrieves the verified custom domains of every workspace.
+	ListDomains(ctx context.Context) ([]CustomDomain, error)
+	DeleteDomain(ctx context.Context, name string) error
+	// FindClaim retrieves an unverified custom domain of the workspace, expired or not.
+	FindClaim(ctx context.Context, workspace, name string) (*CustomDomain, error)
+	ListClaims(ctx context.Context, workspace string) ([]CustomDomain, error)
+	DeleteClaim(ctx context.Context, workspace, name string) error
+}
+
+// TXTResolver looks up the TXT records of a host. *net.Resolver implements it.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, host string) ([]string, error)
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// EventType identifies the kind of link lifecycle event.
+type EventType string
+
+const (
+	// EventLinkCreated is emitted when a new short link is stored.
+	EventLinkCreated EventType = "link.created"
+	// EventLinkUpdated is emitted when an existing short link is changed.
+	EventLinkUpdated EventType = "link.updated"
+	// EventLinkDeleted is emitted when a short link is removed on request.
+	EventLinkDeleted EventType = "link.deleted"
+	// EventLinkExpired is emitted when a short link reaches its expiry time.
+	EventLinkExpired EventType = "link.expired"
+	// EventLinkFirstClicked is emitted the first time a short link is followed.
+	EventLinkFirstClicked EventType = "link.first_clicked"
+)
+
+// EventTypes lists every event type that can be emitted by the service.
+var EventTypes = []EventType{
+	EventLinkCreated,
+	EventLinkUpdated,
+	EventLinkDeleted,
+	EventLinkExpired,
+	EventLinkFirstClicked,
+}
+
+// IsValid reports whether the event type is one of the known EventTypes.
+func (t EventType) IsValid() bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event represents a link lifecycle event in the domain layer.
+// The OriginalURL and Expiry fields are empty for expired links, since the link is already gone.
+type Event struct {
+	ID          string    `json:"id"`
+	Type        EventType `json:"type"`
+	ShortCode   string    `json:"short_code"`
+	OriginalURL string    `json:"original_url,omitempty"`
+	Expiry      time.Time `json:"expiry"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+// EventPublisher is an interface that abstracts where link lifecycle events are sent to.
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrGroupNotFound is returned when a link group does not exist.
+var ErrGroupNotFound = errors.New("group not found")
+
+// ErrInvalidGroup is returned when a link group has an invalid ID or fields.
+var ErrInvalidGroup = errors.New("invalid group")
+
+// Group is a set of links managed together, such as the links of a campaign or a product launch.
+// The ID is chosen when the group is created and is how links refer to it.
+type Group struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SaveGroupRequest represents the request body for creating or updating a group.
+type SaveGroupRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// RenewGroupRequest represents the request body for renewing every link of a group.
+type RenewGroupRequest struct {
+	Expiry time.Time `json:"expiry"`
+}
+
+// GroupLinkStats represents the clicks on a link of a group.
+type GroupLinkStats struct {
+	ShortCode   string    `json:"short_code"`
+	OriginalURL string    `json:"original_url"`
+	Expiry      time.Time `json:"expiry"`
+	Clicks      int64     `json:"clicks"`
+}
+
+// GroupStats represents the usage statistics of the links of a group, added up.
+// Countries counts the clicks per country over every link, like URLStats.
+type GroupStats struct {
+	Group     Group            `json:"group"`
+	Links     int              `json:"links"`
+	Clicks    int64            `json:"clicks"`
+	Countries map[string]int64 `json:"countries,omitempty"`
+	PerLink   []GroupLinkStats `json:"per_link"`
+}
+
+// GroupOperationResponse represents the outcome of an operation on every link of a group.
+type GroupOperationResponse struct {
+	Affected int `json:"affected"`
+}
+
+// GroupRepository is an interface that abstracts the persistence of link groups.
+// The links of a group are indexed by the URLRepository, which stores the group of each link.
+type GroupRepository interface {
+	SaveGroup(ctx context.Context, group Group) error
+	FindGroup(ctx context.Context, id string) (*Group, error)
+	ListGroups(ctx context.Context) ([]Group, error)
+	DeleteGroup(ctx context.Context, id string) error
+}
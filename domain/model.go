@@ -4,29 +4,320 @@ import "time"
 
 // URL represents the URL entity in the domain layer
 type URL struct {
-	OriginalURL string    `json:"original_url"`
-	Expiry      time.Time `json:"expiry"`
-	ShortCode   string    `json:"short_code"`
+	// OriginalURL is the canonical form of the URL, which is hashed and redirected to.
+	OriginalURL string `json:"original_url"`
+	// InputURL is the URL as submitted, kept for display; empty when it was already canonical.
+	InputURL  string    `json:"input_url,omitempty"`
+	Expiry    time.Time `json:"expiry"`
+	ShortCode string    `json:"short_code"`
+	// Title, Description, Tags and Notes help people find and recognize their links.
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Notes       string   `json:"notes,omitempty"`
+	// CreatedBy names who created the link, for display.
+	CreatedBy string `json:"created_by,omitempty"`
+	// CreatedAt and UpdatedAt are zero for links stored before they were recorded.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Page is the metadata of the target page, nil until it is fetched.
+	Page *PageMetadata `json:"page,omitempty"`
+	// PasswordHash is the bcrypt hash of the password of a protected link, empty for public links.
+	PasswordHash string `json:"-"`
+	// MaxClicks is the number of clicks after which the link stops working, 0 when unlimited.
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+	// ActivatesAt is the time before which the link does not resolve, zero when active from its creation.
+	ActivatesAt time.Time `json:"activates_at"`
+	// Rules send the visitors they match to other destinations, in order.
+	Rules []RoutingRule `json:"rules,omitempty"`
+	// Variants split the visitors that match no rule between several destinations.
+	Variants []Variant `json:"variants,omitempty"`
+	// ForwardQuery is the policy for forwarding the query of redirects, empty when it is dropped.
+	ForwardQuery string `json:"forward_query,omitempty"`
+	// ForwardPath appends the path following the short code to the destination.
+	ForwardPath bool `json:"forward_path,omitempty"`
+	// RedirectType is how the link redirects, e.g. "301" or "interstitial", empty for the server default.
+	RedirectType string `json:"redirect_type,omitempty"`
+	// Group is the ID of the group of the link, if any.
+	Group string `json:"group,omitempty"`
+}
+
+// Protected reports whether a password must be entered to follow the URL.
+func (u URL) Protected() bool {
+	return u.PasswordHash != ""
+}
+
+// Pending reports whether the URL is not active yet at the given time.
+func (u URL) Pending(now time.Time) bool {
+	return !u.ActivatesAt.IsZero() && now.Before(u.ActivatesAt)
+}
+
+// HasTag reports whether the URL is tagged with the given tag.
+func (u URL) HasTag(tag string) bool {
+	for _, t := range u.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// URLFilter selects URLs from a listing. Zero fields do not filter.
+type URLFilter struct {
+	// ExpiringBefore keeps the URLs expiring before this time.
+	ExpiringBefore time.Time
+	// Tag keeps the URLs tagged with this tag.
+	Tag string
+	// Pending keeps the URLs that are not active yet when true, and the active ones when false.
+	Pending *bool
+}
+
+// Matches reports whether the URL passes the filter.
+func (f URLFilter) Matches(url URL) bool {
+	if !f.ExpiringBefore.IsZero() && !url.Expiry.Before(f.ExpiringBefore) {
+		return false
+	}
+	if f.Tag != "" && !url.HasTag(f.Tag) {
+		return false
+	}
+	if f.Pending != nil && url.Pending(time.Now()) != *f.Pending {
+		return false
+	}
+	return true
 }
 
 // AddURLRequest represents the request body for adding a new URL.
 type AddURLRequest struct {
-	OriginalURL     string    `json:"original_url"`
-	Expiry          time.Time `json:"expiry"`
-	CustomShortCode string    `json:"custom_short_code"`
+	OriginalURL string `json:"original_url"`
+	// Expiry defaults to 30 days from now.
+	Expiry time.Time `json:"expiry"`
+	// CustomShortCode uses letters, digits, "_" and "-", up to 64 characters; generated when empty.
+	CustomShortCode string `json:"custom_short_code"`
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	// Tags are lowercased, and use letters, digits, "_", "-" and ".".
+	Tags      []string `json:"tags"`
+	Notes     string   `json:"notes"`
+	CreatedBy string   `json:"created_by"`
+	// Password must be entered by visitors before being redirected; at most 72 bytes, and only its hash is stored.
+	Password string `json:"password"`
+	// MaxClicks makes the link respond with a 410 status once followed that many times; 0 leaves it unlimited.
+	MaxClicks int64 `json:"max_clicks"`
+	// ActivatesAt keeps the link from resolving before that time, which must be before the expiry.
+	ActivatesAt time.Time `json:"activates_at"`
+	// Rules send the visitors they match, by operating system, device class, bot or country, to other destinations.
+	Rules []RoutingRule `json:"rules"`
+	// Variants split the other visitors between weighted destinations, each visitor always getting the same one.
+	Variants []Variant `json:"variants"`
+	// ForwardQuery forwards the query of redirects, keeping ("preserve"), replacing ("override") or adding to ("append")
+	// the parameters of the destination.
+	ForwardQuery string `json:"forward_query"`
+	// ForwardPath appends the path following the short code in redirects to the destination.
+	ForwardPath bool `json:"forward_path"`
+	// RedirectType is "301", "302", "307", "308", or "interstitial" for an HTML page that redirects.
+	RedirectType string `json:"redirect_type"`
+	// UTM parameters are added to the canonical original URL, on top of those of UTMPreset; source, medium and campaign are required.
+	UTM       *UTM   `json:"utm"`
+	UTMPreset string `json:"utm_preset"`
+	// Group adds the link to an existing group.
+	Group string `json:"group"`
+	// ForceNew creates a new short code even when deduplication would return an existing link.
+	ForceNew bool `json:"force_new"`
+	// Owner scopes the deduplication; it is set by the server from the API key, never from the body.
+	Owner string `json:"-" swaggerignore:"true"`
 }
 
 // AddSuccessResponse represents the response body for a successful URL addition.
 type AddSuccessResponse struct {
 	OriginalURL  string    `json:"original_url"`
+	InputURL     string    `json:"input_url,omitempty"`
 	Expiry       time.Time `json:"expiry"`
 	ShortenedURL string    `json:"shortened_url"`
+	QRCodeURL    string    `json:"qr_code_url"`
 }
 
 // URLMapping represents the URL mapping entity in the domain layer.
 // This is used to display the list of all shortened URLs.
+// Protected is set for password-protected links; the password itself is never displayed.
+// RemainingClicks is only set for links with a maximum number of clicks, when a single link is displayed.
+// Pending is set for links whose activation time has not come yet.
 type URLMapping struct {
-	ShortCode   string    `json:"short_code"`
+	ShortCode       string        `json:"short_code"`
+	OriginalURL     string        `json:"original_url"`
+	InputURL        string        `json:"input_url,omitempty"`
+	Expiry          time.Time     `json:"expiry"`
+	Title           string        `json:"title,omitempty"`
+	Description     string        `json:"description,omitempty"`
+	Tags            []string      `json:"tags,omitempty"`
+	Notes           string        `json:"notes,omitempty"`
+	CreatedBy       string        `json:"created_by,omitempty"`
+	CreatedAt       *time.Time    `json:"created_at,omitempty"`
+	UpdatedAt       *time.Time    `json:"updated_at,omitempty"`
+	Page            *PageMetadata `json:"page,omitempty"`
+	Protected       bool          `json:"protected,omitempty"`
+	MaxClicks       int64         `json:"max_clicks,omitempty"`
+	RemainingClicks *int64        `json:"remaining_clicks,omitempty"`
+	ActivatesAt     *time.Time    `json:"activates_at,omitempty"`
+	Pending         bool          `json:"pending,omitempty"`
+	Rules           []RoutingRule `json:"rules,omitempty"`
+	Variants        []Variant     `json:"variants,omitempty"`
+	ForwardQuery    string        `json:"forward_query,omitempty"`
+	ForwardPath     bool          `json:"forward_path,omitempty"`
+	RedirectType    string        `json:"redirect_type,omitempty"`
+	Group           string        `json:"group,omitempty"`
+}
+
+// NewURLMapping returns the mapping displayed for a URL.
+func NewURLMapping(url URL) URLMapping {
+	mapping := URLMapping{
+		ShortCode:    url.ShortCode,
+		OriginalURL:  url.OriginalURL,
+		InputURL:     url.InputURL,
+		Expiry:       url.Expiry,
+		Title:        url.Title,
+		Description:  url.Description,
+		Tags:         url.Tags,
+		Notes:        url.Notes,
+		CreatedBy:    url.CreatedBy,
+		Page:         url.Page,
+		Protected:    url.Protected(),
+		MaxClicks:    url.MaxClicks,
+		Pending:      url.Pending(time.Now()),
+		Rules:        url.Rules,
+		Variants:     url.Variants,
+		ForwardQuery: url.ForwardQuery,
+		ForwardPath:  url.ForwardPath,
+		RedirectType: url.RedirectType,
+		Group:        url.Group,
+	}
+	if !url.CreatedAt.IsZero() {
+		mapping.CreatedAt = &url.CreatedAt
+	}
+	if !url.ActivatesAt.IsZero() {
+		mapping.ActivatesAt = &url.ActivatesAt
+	}
+	if !url.UpdatedAt.IsZero() {
+		mapping.UpdatedAt = &url.UpdatedAt
+	}
+	return mapping
+}
+
+// URL returns the URL entity of a displayed mapping.
+func (m URLMapping) URL() URL {
+	url := URL{
+		ShortCode:    m.ShortCode,
+		OriginalURL:  m.OriginalURL,
+		InputURL:     m.InputURL,
+		Expiry:       m.Expiry,
+		Title:        m.Title,
+		Description:  m.Description,
+		Tags:         m.Tags,
+		Notes:        m.Notes,
+		CreatedBy:    m.CreatedBy,
+		Page:         m.Page,
+		MaxClicks:    m.MaxClicks,
+		Rules:        m.Rules,
+		Variants:     m.Variants,
+		ForwardQuery: m.ForwardQuery,
+		ForwardPath:  m.ForwardPath,
+		RedirectType: m.RedirectType,
+		Group:        m.Group,
+	}
+	if m.CreatedAt != nil {
+		url.CreatedAt = *m.CreatedAt
+	}
+	if m.UpdatedAt != nil {
+		url.UpdatedAt = *m.UpdatedAt
+	}
+	if m.ActivatesAt != nil {
+		url.ActivatesAt = *m.ActivatesAt
+	}
+	return url
+}
+
+// UpdateURLRequest represents the request body for updating an existing URL.
+// Fields left out keep their current value.
+type UpdateURLRequest struct {
 	OriginalURL string    `json:"original_url"`
 	Expiry      time.Time `json:"expiry"`
+	// Title, Description and Notes are cleared with an empty string, and Tags with an empty list.
+	Title       *string  `json:"title"`
+	Description *string  `json:"description"`
+	Tags        []string `json:"tags"`
+	Notes       *string  `json:"notes"`
+	// Password replaces the password of the link; an empty one makes it public.
+	Password *string `json:"password"`
+	// MaxClicks counts the clicks already made; 0 removes the limit.
+	MaxClicks *int64 `json:"max_clicks"`
+	// ActivatesAt that is not in the future activates the link at once.
+	ActivatesAt *time.Time `json:"activates_at"`
+	// Rules and Variants are removed with an empty list.
+	Rules    []RoutingRule `json:"rules"`
+	Variants []Variant     `json:"variants"`
+	// ForwardQuery stops forwarding when empty.
+	ForwardQuery *string `json:"forward_query"`
+	ForwardPath  *bool   `json:"forward_path"`
+	// RedirectType goes back to the server default when empty.
+	RedirectType *string `json:"redirect_type"`
+	// Group moves the link to another existing group; an empty one removes it from its group.
+	Group *string `json:"group"`
+}
+
+// BulkAddURLResult represents the outcome of a single item of a bulk URL addition.
+// Either ShortenedURL and Expiry, or Error is set.
+type BulkAddURLResult struct {
+	Index        int        `json:"index"`
+	OriginalURL  string     `json:"original_url"`
+	ShortenedURL string     `json:"shortened_url,omitempty"`
+	Expiry       *time.Time `json:"expiry,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// BulkAddURLResponse represents the response body of a bulk URL addition.
+// The results are in the same order as the items of the request.
+type BulkAddURLResponse struct {
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Results   []BulkAddURLResult `json:"results"`
+}
+
+// ImportRowError describes why a row of an import was rejected.
+type ImportRowError struct {
+	Line      int    `json:"line"`
+	ShortCode string `json:"short_code,omitempty"`
+	Error     string `json:"error"`
+}
+
+// ImportReport represents the response body of a URL import.
+// In a dry run nothing is stored, and Accepted counts the rows that would have been imported.
+// At most a limited number of row errors are listed; Truncated is set when more rows failed.
+type ImportReport struct {
+	DryRun    bool             `json:"dry_run"`
+	Rows      int              `json:"rows"`
+	Accepted  int              `json:"accepted"`
+	Failed    int              `json:"failed"`
+	Errors    []ImportRowError `json:"errors"`
+	Truncated bool             `json:"truncated"`
+}
+
+// URLStats represents the usage statistics of a short code.
+// MaxClicks and RemainingClicks are only set for links with a maximum number of clicks.
+// Variants lists the clicks per variant for links with variants.
+// Countries counts the clicks per country of the visitors, when a GeoIP database is configured; clicks from unknown countries are left out.
+type URLStats struct {
+	ShortCode       string           `json:"short_code"`
+	OriginalURL     string           `json:"original_url"`
+	Expiry          time.Time        `json:"expiry"`
+	Clicks          int64            `json:"clicks"`
+	MaxClicks       int64            `json:"max_clicks,omitempty"`
+	RemainingClicks *int64           `json:"remaining_clicks,omitempty"`
+	Countries       map[string]int64 `json:"countries,omitempty"`
+	Variants        []VariantStats   `json:"variants,omitempty"`
+}
+
+// VariantStats represents the clicks sent to a variant of a link, since it was added.
+type VariantStats struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	Clicks int64  `json:"clicks"`
 }
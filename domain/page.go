@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// PageMetadata represents what the target page of a link says about itself:
+// its <title>, its description and its Open Graph tags.
+type PageMetadata struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	SiteName    string    `json:"site_name,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// PageFetcher is an interface that abstracts how the metadata of a target page is fetched.
+type PageFetcher interface {
+	Fetch(ctx context.Context, url string) (*PageMetadata, error)
+}
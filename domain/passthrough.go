@@ -0,0 +1,22 @@
+package domain
+
+import "errors"
+
+// ErrInvalidPassthrough is returned when a link is given an unknown query forwarding policy.
+var ErrInvalidPassthrough = errors.New("invalid passthrough option")
+
+// Policies for forwarding the query parameters of a redirect to its destination,
+// which differ in what happens when a parameter is set on both. An empty policy does not forward them.
+const (
+	// ForwardQueryPreserve keeps the values of the destination, and only adds the other parameters.
+	ForwardQueryPreserve = "preserve"
+	// ForwardQueryOverride replaces the values of the destination by the incoming ones.
+	ForwardQueryOverride = "override"
+	// ForwardQueryAppend keeps both, the values of the destination first.
+	ForwardQueryAppend = "append"
+)
+
+// Forwards reports whether redirects to the URL carry over the query parameters or the trailing path they were requested with.
+func (u URL) Forwards() bool {
+	return u.ForwardQuery != "" || u.ForwardPath
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPasswordRequired is returned when a password-protected link is followed without its password.
+var ErrPasswordRequired = errors.New("link is password protected")
+
+// ErrWrongPassword is returned when the password entered for a link does not match.
+var ErrWrongPassword = errors.New("wrong password")
+
+// ErrTooManyAttempts is returned when a client entered too many wrong passwords for a link, until the attempts expire.
+var ErrTooManyAttempts = errors.New("too many wrong passwords, try again later")
+
+// AttemptCounter counts failed attempts, such as wrong passwords, within a time window.
+// Keys identify what is attempted and by whom, e.g. a short code and a client address.
+type AttemptCounter interface {
+	// Failures returns the number of failures counted for the key in its current window.
+	Failures(ctx context.Context, key string) (int64, error)
+	// AddFailure counts a failure for the key and returns the new count. The first failure starts a window that lasts the given time.
+	AddFailure(ctx context.Context, key string, window time.Duration) (int64, error)
+	// Reset forgets the failures counted for the key.
+	Reset(ctx context.Context, key string) error
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "errors"
+
+// ErrInvalidRedirectType is returned when a link is given a redirect type that is not supported.
+var ErrInvalidRedirectType = errors.New("invalid redirect type")
+
+// Redirect types of a link: the HTTP status of its redirects, or an HTML page that redirects,
+// for clients that do not follow the Location header.
+const (
+	RedirectMovedPermanently = "301"
+	RedirectFound            = "302"
+	RedirectTemporary        = "307"
+	RedirectPermanent        = "308"
+	RedirectInterstitial     = "interstitial"
+	DefaultRedirectType      = RedirectTemporary
+)
+
+// PermanentRedirect reports whether the redirect type tells clients that the link will always lead to the same place,
+// so that they may cache it.
+func PermanentRedirect(redirectType string) bool {
+	return redirectType == RedirectMovedPermanently || redirectType == RedirectPermanent
+}
@@ -1,11 +1,83 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
-// URLRepository is an interface that abstracts the methods for URL persistence
+// ErrURLNotFound is returned when no URL exists for a given short code.
+var ErrURLNotFound = errors.New("short code not found")
+
+// ErrShortCodeTaken is returned when a short code is already in use.
+var ErrShortCodeTaken = errors.New("short code already exists")
+
+// ErrInvalidShortCode is returned when a custom short code uses characters that cannot be part of a redirect path.
+var ErrInvalidShortCode = errors.New("invalid short code")
+
+// ErrClickLimitReached is returned when a link with a maximum number of clicks has been followed that many times.
+var ErrClickLimitReached = errors.New("click limit reached")
+
+// ErrLinkPending is returned when a link is followed before its activation time.
+var ErrLinkPending = errors.New("link is not active yet")
+
+// ErrInvalidSchedule is returned when a link would activate at or after its expiry.
+var ErrInvalidSchedule = errors.New("activation time must be before the expiry")
+
+// ErrInvalidURL is returned when an original URL cannot be canonicalized, or does not use http or https.
+var ErrInvalidURL = errors.New("invalid original URL")
+
+// ErrInvalidLink is returned when the metadata, password, click limit or expiry of a link are out of bounds.
+var ErrInvalidLink = errors.New("invalid link")
+
+// URLRepository is an interface that abstracts the methods for URL persistence.
+// Clicks and the lookups by group or destination have interfaces of their own, ClickCounter, GroupIndex and DestinationIndex,
+// so that decorators such as caches only implement the storage of the URLs.
 type URLRepository interface {
 	Store(ctx context.Context, url URL) error
+	// StoreNew saves a new URL like Store, but never overwrites an existing short code:
+	// it fails with ErrShortCodeTaken if the short code is already in use.
+	StoreNew(ctx context.Context, url URL) error
+	StoreBatch(ctx context.Context, urls []URL) ([]error, error)
 	FindByShortCode(ctx context.Context, shortCode string) (*URL, error)
 	IsUnique(ctx context.Context, shortCode string) bool
 	FetchAll(ctx context.Context) ([]URL, error)
+	Iterate(ctx context.Context, fn func(URL) error) error
+	Delete(ctx context.Context, shortCode string) error
+	PopExpired(ctx context.Context, before time.Time) ([]string, error)
+	// Expire makes a URL expire at once: it no longer resolves, and PopExpired returns it like a URL that reached its expiry.
+	// It fails with ErrURLNotFound if the short code does not exist.
+	Expire(ctx context.Context, shortCode string) error
+	// Count returns the number of live URLs.
+	Count(ctx context.Context) (int64, error)
+	SetPageMetadata(ctx context.Context, shortCode, originalURL string, page PageMetadata) error
+}
+
+// ClickCounter counts the clicks on short codes, in total, per country and per variant.
+type ClickCounter interface {
+	IncrementClicks(ctx context.Context, shortCode string) (int64, error)
+	// IncrementClicksUpTo atomically increments the click counter unless it already reached maxClicks,
+	// and returns the new count. It fails with ErrClickLimitReached once the limit is reached.
+	IncrementClicksUpTo(ctx context.Context, shortCode string, maxClicks int64) (int64, error)
+	GetClicks(ctx context.Context, shortCode string) (int64, error)
+	// CountClickCountry counts a click on a short code from a visitor of the given country.
+	CountClickCountry(ctx context.Context, shortCode, country string) error
+	// GetClickCountries returns the number of clicks counted per country for a short code.
+	GetClickCountries(ctx context.Context, shortCode string) (map[string]int64, error)
+	// CountClickVariant counts a click on a short code that was sent to the variant with the given URL.
+	CountClickVariant(ctx context.Context, shortCode, variantURL string) error
+	// GetClickVariants returns the number of clicks counted per variant URL for a short code.
+	GetClickVariants(ctx context.Context, shortCode string) (map[string]int64, error)
+}
+
+// GroupIndex finds the URLs of a group.
+type GroupIndex interface {
+	// FindByGroup returns the live URLs of a group, in no particular order.
+	FindByGroup(ctx context.Context, group string) ([]URL, error)
+}
+
+// DestinationIndex finds the URL an owner created for an original URL, for deduplication.
+type DestinationIndex interface {
+	IndexDestination(ctx context.Context, owner string, url URL) error
+	FindByDestination(ctx context.Context, owner, originalURL string) (*URL, error)
 }
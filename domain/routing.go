@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"errors"
+	"hash/fnv"
+)
+
+// ErrInvalidRule is returned when a routing rule matches on an unknown value or has no valid destination.
+var ErrInvalidRule = errors.New("invalid routing rule")
+
+// ErrInvalidVariants is returned when the variants of a link have invalid weights or destinations.
+var ErrInvalidVariants = errors.New("invalid variants")
+
+// Operating systems that routing rules match on.
+const (
+	OSIOS      = "ios"
+	OSAndroid  = "android"
+	OSWindows  = "windows"
+	OSMacOS    = "macos"
+	OSLinux    = "linux"
+	OSChromeOS = "chromeos"
+	OSOther    = "other"
+)
+
+// Device classes that routing rules match on.
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+)
+
+// Visitor describes who follows a link, as far as routing rules are concerned.
+// Country is the ISO 3166-1 alpha-2 code of the country of the visitor, e.g. "DE", and is empty when it is not known.
+// ID identifies the visitor across visits, so that it keeps being sent to the same variant of a link.
+type Visitor struct {
+	OS      string
+	Device  string
+	Bot     bool
+	Country string
+	ID      string
+}
+
+// Variant is one of the destinations a link splits its traffic between, for A/B testing.
+// Each visitor is assigned a variant with a probability proportional to its weight; a weight of 0 pauses the variant.
+type Variant struct {
+	URL    string `json:"url" example:"https://example.com/landing-b"`
+	Weight int    `json:"weight" example:"50"`
+}
+
+// RoutingRule sends the visitors it matches to its URL instead of the original URL of the link.
+// A visitor matches when every criterion that is set matches: the OS, the device class, whether it is a bot,
+// and the country, which must be one of Countries. Visitors of unknown countries never match Countries.
+type RoutingRule struct {
+	OS        string   `json:"os,omitempty" example:"ios"`
+	Device    string   `json:"device,omitempty" example:"mobile"`
+	Bot       *bool    `json:"bot,omitempty"`
+	Countries []string `json:"countries,omitempty" example:"DE,AT,CH"`
+	URL       string   `json:"url" example:"https://apps.apple.com/app/id284882215"`
+}
+
+// Matches reports whether the visitor matches every criterion of the rule.
+func (r RoutingRule) Matches(v Visitor) bool {
+	if r.OS != "" && r.OS != v.OS {
+		return false
+	}
+	if r.Device != "" && r.Device != v.Device {
+		return false
+	}
+	if r.Bot != nil && *r.Bot != v.Bot {
+		return false
+	}
+	if len(r.Countries) > 0 && !r.matchesCountry(v.Country) {
+		return false
+	}
+	return true
+}
+
+// matchesCountry reports whether the country is one of the countries of the rule.
+func (r RoutingRule) matchesCountry(country string) bool {
+	for _, c := range r.Countries {
+		if c == country {
+			return true
+		}
+	}
+	return false
+}
+
+// Destination returns the URL of the first routing rule the visitor matches.
+// Otherwise it returns the variant assigned to the visitor, for links with variants, or the original URL.
+// The index of the variant is returned too, and is -1 when the destination is not a variant.
+func (u URL) Destination(v Visitor) (string, int) {
+	for _, rule := range u.Rules {
+		if rule.Matches(v) {
+			return rule.URL, -1
+		}
+	}
+	if variant := u.variantOf(v.ID); variant >= 0 {
+		return u.Variants[variant].URL, variant
+	}
+	return u.OriginalURL, -1
+}
+
+// variantOf returns the index of the variant assigned to a visitor, or -1 if the link has no variant with a weight.
+// The assignment hashes the short code with the visitor ID, so a visitor keeps the same variant as long as the variants do not change,
+// and visitors are spread over the variants in proportion to their weights.
+func (u URL) variantOf(visitorID string) int {
+	total := 0
+	for _, variant := range u.Variants {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return -1
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(u.ShortCode))
+	hash.Write([]byte{0})
+	hash.Write([]byte(visitorID))
+	point := int(hash.Sum64() % uint64(total))
+	for i, variant := range u.Variants {
+		if point < variant.Weight {
+			return i
+		}
+		point -= variant.Weight
+	}
+	return -1
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInvalidUTM is returned when UTM parameters or the name of a UTM preset are invalid.
+var ErrInvalidUTM = errors.New("invalid UTM parameters")
+
+// ErrUTMPresetNotFound is returned when a UTM preset does not exist.
+var ErrUTMPresetNotFound = errors.New("UTM preset not found")
+
+// UTM holds the campaign parameters that are added to the query of a destination as utm_source, utm_medium,
+// utm_campaign, utm_term and utm_content. Empty fields are not added.
+type UTM struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// IsZero reports whether no UTM parameter is set.
+func (u UTM) IsZero() bool {
+	return u == UTM{}
+}
+
+// Merge returns the parameters with the empty ones taken from the other parameters.
+func (u UTM) Merge(other UTM) UTM {
+	pick := func(value, fallback string) string {
+		if value != "" {
+			return value
+		}
+		return fallback
+	}
+	return UTM{
+		Source:   pick(u.Source, other.Source),
+		Medium:   pick(u.Medium, other.Medium),
+		Campaign: pick(u.Campaign, other.Campaign),
+		Term:     pick(u.Term, other.Term),
+		Content:  pick(u.Content, other.Content),
+	}
+}
+
+// UTMPreset is a named set of UTM parameters stored on the server, so that a campaign is tagged the same way on every link.
+type UTMPreset struct {
+	Name      string    `json:"name"`
+	UTM       UTM       `json:"utm"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UTMPresetRepository is an interface that abstracts the persistence of UTM presets.
+type UTMPresetRepository interface {
+	SavePreset(ctx context.Context, preset UTMPreset) error
+	FindPreset(ctx context.Context, name string) (*UTMPreset, error)
+	ListPresets(ctx context.Context) ([]UTMPreset, error)
+	DeletePreset(ctx context.Context, name string) error
+}
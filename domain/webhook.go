@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrWebhookNotFound is returned when a webhook subscriber does not exist.
+var ErrWebhookNotFound = errors.New("webhook subscriber not found")
+
+// WebhookSubscriber represents a registered receiver of link lifecycle events.
+// An empty Events slice means the subscriber receives every event type.
+type WebhookSubscriber struct {
+	ID        string      `json:"id"`
+	URL       string      `json:"url"`
+	Secret    string      `json:"secret,omitempty"`
+	Events    []EventType `json:"events"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Accepts reports whether the subscriber has asked to receive the given event type.
+func (s WebhookSubscriber) Accepts(eventType EventType) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, t := range s.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery represents a single queued attempt to send an event to a subscriber.
+type WebhookDelivery struct {
+	ID           string `json:"id"`
+	SubscriberID string `json:"subscriber_id"`
+	Event        Event  `json:"event"`
+	Attempts     int    `json:"attempts"`
+}
+
+// AddWebhookRequest represents the request body for registering a new webhook subscriber.
+type AddWebhookRequest struct {
+	URL    string      `json:"url"`
+	Secret string      `json:"secret"`
+	Events []EventType `json:"events"`
+}
+
+// WebhookRepository is an interface that abstracts the persistence of webhook subscribers
+// and the queue of pending deliveries.
+// Reserve hands out a delivery without removing it from the queue, so that a crashed worker
+// does not lose it; the delivery is only gone once it is acknowledged or rescheduled.
+type WebhookRepository interface {
+	SaveSubscriber(ctx context.Context, subscriber WebhookSubscriber) error
+	FindSubscriber(ctx context.Context, id string) (*WebhookSubscriber, error)
+	ListSubscribers(ctx context.Context) ([]WebhookSubscriber, error)
+	DeleteSubscriber(ctx context.Context, id string) error
+	Enqueue(ctx context.Context, delivery WebhookDelivery) error
+	Reserve(ctx context.Context, timeout time.Duration) (*WebhookDelivery, error)
+	Ack(ctx context.Context, delivery WebhookDelivery) error
+	Retry(ctx context.Context, delivery WebhookDelivery, at time.Time) error
+	Bury(ctx context.Context, delivery WebhookDelivery) error
+	PromoteDue(ctx context.Context, now time.Time) (int, error)
+	// RecoverInFlight queues again the deliveries reserved before the given time that were never finished.
+	RecoverInFlight(ctx context.Context, reservedBefore time.Time) (int, error)
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "errors"
+
+// ErrQuotaExceeded is returned when a workspace has used up its quota of links.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Workspace is a tenant of the service, such as a department. Each workspace has its own links, groups,
+// UTM presets and webhooks, so that workspaces can use the same custom short codes without colliding.
+// Requests are assigned to a workspace by their API key, and redirects by the domain they were sent to.
+// The default workspace has an empty ID and no domain: it holds the data of deployments without workspaces,
+// and receives the redirects sent to the shared host.
+type Workspace struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name,omitempty"`
+	APIKeys []string `json:"api_keys"`
+	Domain  string   `json:"domain,omitempty"`
+	Quota   Quota    `json:"quota"`
+}
+
+// Quota limits what a workspace can store. Zero means unlimited.
+// MaxLinks is the number of live links, scheduled ones included.
+type Quota struct {
+	MaxLinks int64 `json:"max_links,omitempty"`
+}
+
+// WorkspaceUsage represents the quota of a workspace and how much of it is used.
+type WorkspaceUsage struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Links    int64  `json:"links"`
+	MaxLinks int64  `json:"max_links,omitempty"`
+}
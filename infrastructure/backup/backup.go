@@ -0,0 +1,314 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// Format and Version identify a backup file. They are written in the header line of every backup,
+// and Restore refuses files with another format or a newer version.
+// Version 2 added the groups to the header, which older versions would have dropped.
+const (
+	Format  = "url-shortener-backup"
+	Version = 2
+)
+
+// restoreBatchSize is the number of records stored per repository call when conflicts are skipped.
+const restoreBatchSize = 100
+
+// ConflictPolicy decides what Restore does with a record whose short code already exists.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the existing link and ignores the record.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing link with the record.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictFail stops the restore at the first record that already exists.
+	ConflictFail ConflictPolicy = "fail"
+)
+
+// ErrConflict is returned by Restore with ConflictFail when a short code already exists.
+var ErrConflict = errors.New("short code already exists")
+
+// ErrGroupConflict is returned by Restore with ConflictFail when a group already exists.
+var ErrGroupConflict = errors.New("group already exists")
+
+// Header is the first line of a backup file.
+// Groups holds every group, so that the links restored after it find their group; backups of version 1 have none.
+type Header struct {
+	Format    string         `json:"format"`
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Groups    []domain.Group `json:"groups,omitempty"`
+}
+
+// Record is a single link in a backup file.
+// TTLSeconds is the time the link had left when the backup was taken; the restored link gets the same time to live.
+// Expiry is the absolute expiry at backup time and is only kept for reference.
+// InputURL is the URL as it was submitted, when it differs from the canonical OriginalURL.
+// The metadata fields are omitted when empty, so backups of links without metadata read as before.
+// PasswordHash is the hash of the password of a protected link, so that it stays protected once restored.
+// Clicks are not backed up, so a restored link with MaxClicks can be followed that many times again.
+type Record struct {
+	ShortCode    string               `json:"short_code"`
+	OriginalURL  string               `json:"original_url"`
+	InputURL     string               `json:"input_url,omitempty"`
+	TTLSeconds   int64                `json:"ttl_seconds"`
+	Expiry       time.Time            `json:"expiry"`
+	Title        string               `json:"title,omitempty"`
+	Description  string               `json:"description,omitempty"`
+	Tags         []string             `json:"tags,omitempty"`
+	Notes        string               `json:"notes,omitempty"`
+	CreatedBy    string               `json:"created_by,omitempty"`
+	CreatedAt    *time.Time           `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time           `json:"updated_at,omitempty"`
+	Page         *domain.PageMetadata `json:"page,omitempty"`
+	PasswordHash string               `json:"password_hash,omitempty"`
+	MaxClicks    int64                `json:"max_clicks,omitempty"`
+	ActivatesAt  *time.Time           `json:"activates_at,omitempty"`
+	Rules        []domain.RoutingRule `json:"rules,omitempty"`
+	Variants     []domain.Variant     `json:"variants,omitempty"`
+	ForwardQuery string               `json:"forward_query,omitempty"`
+	ForwardPath  bool                 `json:"forward_path,omitempty"`
+	RedirectType string               `json:"redirect_type,omitempty"`
+	Group        string               `json:"group,omitempty"`
+}
+
+// WriteReport counts what a backup holds.
+type WriteReport struct {
+	Links  int
+	Groups int
+}
+
+// RestoreReport counts what happened to the records of a restored backup.
+// Invalid counts records that cannot be stored, such as links without an expiry.
+// Groups counts the groups restored or overwritten; existing groups that are kept are not counted.
+type RestoreReport struct {
+	Restored    int
+	Skipped     int
+	Overwritten int
+	Invalid     int
+	Groups      int
+}
+
+// Write streams every group and link of the repositories to w as gzip-compressed NDJSON:
+// a Header line with the groups followed by one Record line per link.
+func Write(ctx context.Context, repo domain.URLRepository, groups domain.GroupRepository, w io.Writer) (WriteReport, error) {
+	var report WriteReport
+	gz := gzip.NewWriter(w)
+	encoder := json.NewEncoder(gz)
+
+	header := Header{Format: Format, Version: Version, CreatedAt: time.Now().UTC()}
+	var err error
+	if header.Groups, err = groups.ListGroups(ctx); err != nil {
+		return report, fmt.Errorf("failed to back up groups: %w", err)
+	}
+	if err := encoder.Encode(header); err != nil {
+		return report, err
+	}
+	report.Groups = len(header.Groups)
+
+	err = repo.Iterate(ctx, func(url domain.URL) error {
+		record := Record{
+			ShortCode:    url.ShortCode,
+			OriginalURL:  url.OriginalURL,
+			InputURL:     url.InputURL,
+			Expiry:       url.Expiry,
+			Title:        url.Title,
+			Description:  url.Description,
+			Tags:         url.Tags,
+			Notes:        url.Notes,
+			CreatedBy:    url.CreatedBy,
+			Page:         url.Page,
+			PasswordHash: url.PasswordHash,
+			MaxClicks:    url.MaxClicks,
+			Rules:        url.Rules,
+			Variants:     url.Variants,
+			ForwardQuery: url.ForwardQuery,
+			ForwardPath:  url.ForwardPath,
+			RedirectType: url.RedirectType,
+			Group:        url.Group,
+		}
+		if !url.CreatedAt.IsZero() {
+			record.CreatedAt = &url.CreatedAt
+		}
+		if !url.UpdatedAt.IsZero() {
+			record.UpdatedAt = &url.UpdatedAt
+		}
+		if !url.ActivatesAt.IsZero() {
+			record.ActivatesAt = &url.ActivatesAt
+		}
+		if !url.Expiry.IsZero() {
+			record.TTLSeconds = int64(time.Until(url.Expiry).Round(time.Second) / time.Second)
+		}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+		report.Links++
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to back up links: %w", err)
+	}
+
+	return report, gz.Close()
+}
+
+// Restore reads a backup written by Write and stores its groups, then its links, in the repositories,
+// resolving existing groups and short codes with the given policy.
+// With ConflictFail the records before the conflicting one are already restored when ErrGroupConflict or ErrConflict is returned.
+func Restore(ctx context.Context, repo domain.URLRepository, groups domain.GroupRepository, r io.Reader, policy ConflictPolicy) (RestoreReport, error) {
+	var report RestoreReport
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return report, fmt.Errorf("not a gzip-compressed backup: %w", err)
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	// Check the header before touching the repository
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return report, err
+		}
+		return report, errors.New("empty backup file")
+	}
+	var header Header
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Format != Format {
+		return report, errors.New("missing backup header - not a backup file")
+	}
+	if header.Version > Version {
+		return report, fmt.Errorf("backup version %d is newer than the supported version %d", header.Version, Version)
+	}
+	if policy != ConflictSkip && policy != ConflictOverwrite && policy != ConflictFail {
+		return report, fmt.Errorf("unknown conflict policy %q", policy)
+	}
+
+	// Restore the groups first, so that no restored link refers to a missing group
+	for _, group := range header.Groups {
+		_, err := groups.FindGroup(ctx, group.ID)
+		if err != nil && !errors.Is(err, domain.ErrGroupNotFound) {
+			return report, fmt.Errorf("group %s: %w", group.ID, err)
+		}
+		if err == nil {
+			if policy == ConflictFail {
+				return report, fmt.Errorf("%w: %s", ErrGroupConflict, group.ID)
+			} else if policy == ConflictSkip {
+				continue
+			}
+		}
+		if err := groups.SaveGroup(ctx, group); err != nil {
+			return report, fmt.Errorf("group %s: %w", group.ID, err)
+		}
+		report.Groups++
+	}
+
+	var batch []domain.URL
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		errs, err := repo.StoreBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				report.Restored++
+			case errors.Is(err, domain.ErrShortCodeTaken):
+				report.Skipped++
+			default:
+				report.Invalid++
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for line := 2; scanner.Scan(); line++ {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return report, fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.ShortCode == "" || record.TTLSeconds <= 0 {
+			report.Invalid++
+			continue
+		}
+		url := domain.URL{
+			ShortCode:    record.ShortCode,
+			OriginalURL:  record.OriginalURL,
+			InputURL:     record.InputURL,
+			Expiry:       time.Now().Add(time.Duration(record.TTLSeconds) * time.Second),
+			Title:        record.Title,
+			Description:  record.Description,
+			Tags:         record.Tags,
+			Notes:        record.Notes,
+			CreatedBy:    record.CreatedBy,
+			Page:         record.Page,
+			PasswordHash: record.PasswordHash,
+			MaxClicks:    record.MaxClicks,
+			Rules:        record.Rules,
+			Variants:     record.Variants,
+			ForwardQuery: record.ForwardQuery,
+			ForwardPath:  record.ForwardPath,
+			RedirectType: record.RedirectType,
+			Group:        record.Group,
+		}
+		if record.CreatedAt != nil {
+			url.CreatedAt = *record.CreatedAt
+		}
+		if record.UpdatedAt != nil {
+			url.UpdatedAt = *record.UpdatedAt
+		}
+		if record.ActivatesAt != nil {
+			url.ActivatesAt = *record.ActivatesAt
+		}
+
+		switch policy {
+		case ConflictSkip:
+			// StoreBatch never overwrites, so existing short codes come back as skipped
+			batch = append(batch, url)
+			if len(batch) == restoreBatchSize {
+				if err := flush(); err != nil {
+					return report, err
+				}
+			}
+		case ConflictOverwrite:
+			exists := !repo.IsUnique(ctx, url.ShortCode)
+			if err := repo.Store(ctx, url); err != nil {
+				return report, fmt.Errorf("line %d: %w", line, err)
+			}
+			if exists {
+				report.Overwritten++
+			} else {
+				report.Restored++
+			}
+		case ConflictFail:
+			if !repo.IsUnique(ctx, url.ShortCode) {
+				return report, fmt.Errorf("line %d: %w: %s", line, ErrConflict, url.ShortCode)
+			}
+			if err := repo.Store(ctx, url); err != nil {
+				return report, fmt.Errorf("line %d: %w", line, err)
+			}
+			report.Restored++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+
+	return report, flush()
+}
@@ -0,0 +1,172 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+	redisRepo "github.com/terenzio/URL-Shortening-Service/infrastructure/redis"
+)
+
+// newTestRepository starts a mini Redis server and returns the link and group repositories connected to it.
+func newTestRepository(t *testing.T) (*miniredis.Miniredis, *redisRepo.URLRepository, *redisRepo.GroupRepository) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	t.Cleanup(mr.Close)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return mr, redisRepo.NewURLRepository(rdb), redisRepo.NewGroupRepository(rdb)
+}
+
+// TestWriteAndRestore tests that a backup restores its links under every conflict policy.
+func TestWriteAndRestore(t *testing.T) {
+	ctx := context.Background()
+
+	// Take a backup of two links, one of them in a group
+	_, source, sourceGroups := newTestRepository(t)
+	assert.NoError(t, sourceGroups.SaveGroup(ctx, domain.Group{ID: "spring", Name: "Spring sale"}))
+	assert.NoError(t, source.Store(ctx, domain.URL{ShortCode: "abc", OriginalURL: "https://a.com", Expiry: time.Now().Add(time.Hour)}))
+	assert.NoError(t, source.Store(ctx, domain.URL{ShortCode: "def", OriginalURL: "https://d.com", Expiry: time.Now().Add(2 * time.Hour), Title: "D", Tags: []string{"docs"}, Group: "spring"}))
+	var buf bytes.Buffer
+	written, err := Write(ctx, source, sourceGroups, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, WriteReport{Links: 2, Groups: 1}, written)
+
+	tests := []struct {
+		name       string
+		policy     ConflictPolicy
+		wantErr    error
+		wantReport RestoreReport
+		wantURL    string
+	}{
+		{
+			name:       "Skip",
+			policy:     ConflictSkip,
+			wantReport: RestoreReport{Restored: 1, Skipped: 1, Groups: 1},
+			wantURL:    "https://existing.com",
+		},
+		{
+			name:       "Overwrite",
+			policy:     ConflictOverwrite,
+			wantReport: RestoreReport{Restored: 1, Overwritten: 1, Groups: 1},
+			wantURL:    "https://a.com",
+		},
+		{
+			name:    "Fail",
+			policy:  ConflictFail,
+			wantErr: ErrConflict,
+			wantURL: "https://existing.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Restore into a store where "abc" already exists
+			mr, target, targetGroups := newTestRepository(t)
+			assert.NoError(t, target.Store(ctx, domain.URL{ShortCode: "abc", OriginalURL: "https://existing.com", Expiry: time.Now().Add(time.Hour)}))
+
+			report, err := Restore(ctx, target, targetGroups, bytes.NewReader(buf.Bytes()), tt.policy)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantReport, report)
+				assert.InDelta(t, (2 * time.Hour).Seconds(), mr.TTL("short:def").Seconds(), 5, "The remaining TTL should be kept")
+				restored, err := target.FindByShortCode(ctx, "def")
+				assert.NoError(t, err)
+				assert.Equal(t, "D", restored.Title, "The metadata should be kept")
+				assert.Equal(t, []string{"docs"}, restored.Tags)
+				group, err := targetGroups.FindGroup(ctx, restored.Group)
+				assert.NoError(t, err, "The group of the link should be restored")
+				assert.Equal(t, "Spring sale", group.Name)
+			}
+			assert.Equal(t, tt.wantURL, mr.HGet("short:abc", "url"))
+		})
+	}
+}
+
+// TestRestore_RejectsUnknownFiles tests that files without a valid header are refused.
+func TestRestore_RejectsUnknownFiles(t *testing.T) {
+	ctx := context.Background()
+	_, repo, groups := newTestRepository(t)
+
+	compress := func(s string) *bytes.Reader {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(s))
+		gz.Close()
+		return bytes.NewReader(buf.Bytes())
+	}
+
+	_, err := Restore(ctx, repo, groups, bytes.NewReader([]byte("plain text")), ConflictSkip)
+	assert.Error(t, err, "Uncompressed files should be refused")
+
+	_, err = Restore(ctx, repo, groups, compress(`{"short_code":"abc"}`+"\n"), ConflictSkip)
+	assert.Error(t, err, "Files without a header should be refused")
+
+	_, err = Restore(ctx, repo, groups, compress(`{"format":"url-shortener-backup","version":99}`+"\n"), ConflictSkip)
+	assert.Error(t, err, "Newer versions should be refused")
+}
+
+// TestRestore_Groups tests that existing groups are resolved with the conflict policy,
+// and that backups of version 1, which have no groups, can still be restored.
+func TestRestore_Groups(t *testing.T) {
+	ctx := context.Background()
+
+	_, source, sourceGroups := newTestRepository(t)
+	assert.NoError(t, sourceGroups.SaveGroup(ctx, domain.Group{ID: "spring", Name: "Spring sale"}))
+	assert.NoError(t, source.Store(ctx, domain.URL{ShortCode: "abc", OriginalURL: "https://a.com", Expiry: time.Now().Add(time.Hour), Group: "spring"}))
+	var buf bytes.Buffer
+	_, err := Write(ctx, source, sourceGroups, &buf)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		policy   ConflictPolicy
+		wantErr  error
+		wantName string
+	}{
+		{name: "Skip", policy: ConflictSkip, wantName: "Existing"},
+		{name: "Overwrite", policy: ConflictOverwrite, wantName: "Spring sale"},
+		{name: "Fail", policy: ConflictFail, wantErr: ErrGroupConflict, wantName: "Existing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Restore into a store where the group "spring" already exists
+			_, target, targetGroups := newTestRepository(t)
+			assert.NoError(t, targetGroups.SaveGroup(ctx, domain.Group{ID: "spring", Name: "Existing"}))
+
+			_, err := Restore(ctx, target, targetGroups, bytes.NewReader(buf.Bytes()), tt.policy)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.True(t, target.IsUnique(ctx, "abc"), "No link should be restored after a group conflict")
+			} else {
+				assert.NoError(t, err)
+				assert.False(t, target.IsUnique(ctx, "abc"))
+			}
+			group, err := targetGroups.FindGroup(ctx, "spring")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantName, group.Name)
+		})
+	}
+
+	t.Run("Version 1", func(t *testing.T) {
+		var old bytes.Buffer
+		gz := gzip.NewWriter(&old)
+		gz.Write([]byte(`{"format":"url-shortener-backup","version":1}` + "\n" + `{"short_code":"abc","original_url":"https://a.com","ttl_seconds":3600}` + "\n"))
+		gz.Close()
+
+		_, target, targetGroups := newTestRepository(t)
+		report, err := Restore(ctx, target, targetGroups, &old, ConflictSkip)
+		assert.NoError(t, err)
+		assert.Equal(t, RestoreReport{Restored: 1}, report)
+	})
+}
@@ -0,0 +1,143 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+)
+
+// snapshotMagic starts every serialized Filter, so that foreign or outdated data is never loaded.
+const snapshotMagic = "BLM1"
+
+// Filter is a Bloom filter of strings. It never forgets a string that was added,
+// but may claim to contain a string that was not, with a probability that grows as it fills up.
+// It is not safe for concurrent use.
+type Filter struct {
+	bits  []uint64
+	m     uint64
+	k     uint32
+	count uint64
+}
+
+// NewFilter creates a Filter sized to hold n strings with a false-positive rate of p.
+func NewFilter(n int, p float64) *Filter {
+	if n < 1 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+	// The optimal number of bits and hash functions for n items and a false-positive rate of p
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return newFilter(m, k)
+}
+
+// newFilter creates a Filter of m bits, rounded up to whole words, using k hash functions.
+func newFilter(m uint64, k uint32) *Filter {
+	words := (m + 63) / 64
+	return &Filter{bits: make([]uint64, words), m: words * 64, k: k}
+}
+
+// Add adds a string to the filter.
+func (f *Filter) Add(s string) {
+	h1, h2 := hash(s)
+	added := false
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if f.bits[word]&mask == 0 {
+			f.bits[word] |= mask
+			added = true
+		}
+	}
+	if added {
+		f.count++
+	}
+}
+
+// MayContain reports whether the string may have been added.
+// A false result is certain; a true result is wrong with about the filter's false-positive rate.
+func (f *Filter) MayContain(s string) bool {
+	h1, h2 := hash(s)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the approximate number of strings added. Strings whose bits were all set already are not counted.
+func (f *Filter) Count() uint64 {
+	return f.count
+}
+
+// Bits returns the size of the filter in bits.
+func (f *Filter) Bits() uint64 {
+	return f.m
+}
+
+// EstimatedFalsePositiveRate returns the expected false-positive rate of the filter, given how full it is.
+func (f *Filter) EstimatedFalsePositiveRate() float64 {
+	return math.Pow(1-math.Exp(-float64(f.k)*float64(f.count)/float64(f.m)), float64(f.k))
+}
+
+// MarshalBinary encodes the filter, so that it can be persisted and loaded again by UnmarshalBinary.
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, len(snapshotMagic)+4+8+8+len(f.bits)*8)
+	data = append(data, snapshotMagic...)
+	data = binary.BigEndian.AppendUint32(data, f.k)
+	data = binary.BigEndian.AppendUint64(data, f.m)
+	data = binary.BigEndian.AppendUint64(data, f.count)
+	for _, word := range f.bits {
+		data = binary.BigEndian.AppendUint64(data, word)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a filter encoded by MarshalBinary, replacing the content of f.
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	const headerSize = len(snapshotMagic) + 4 + 8 + 8
+	if len(data) < headerSize || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return errors.New("not a Bloom filter snapshot")
+	}
+	data = data[len(snapshotMagic):]
+	k := binary.BigEndian.Uint32(data)
+	m := binary.BigEndian.Uint64(data[4:])
+	count := binary.BigEndian.Uint64(data[12:])
+	data = data[20:]
+	if k == 0 || m == 0 || m%64 != 0 || uint64(len(data)) != m/8 {
+		return errors.New("corrupted Bloom filter snapshot")
+	}
+
+	*f = *newFilter(m, k)
+	f.count = count
+	for i := range f.bits {
+		f.bits[i] = binary.BigEndian.Uint64(data[i*8:])
+	}
+	return nil
+}
+
+// hash returns the two hashes of s used for double hashing. The second one is odd, so that it never repeats bits.
+// It must not change, since persisted filters depend on it.
+func hash(s string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	h1 := mix(h.Sum64())
+	h2 := mix(h1) | 1
+	return h1, h2
+}
+
+// mix is the finalizer of SplitMix64. It spreads every input bit over the whole output,
+// which FNV alone does poorly for the low bits used on small filters.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,61 @@
+package bloom
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFilter tests that a filter never forgets a string, and stays close to its false-positive rate when full
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		items int
+		rate  float64
+	}{
+		{name: "one percent", items: 10000, rate: 0.01},
+		{name: "one per mille", items: 10000, rate: 0.001},
+		{name: "tiny", items: 10, rate: 0.05},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewFilter(tt.items, tt.rate)
+			for i := 0; i < tt.items; i++ {
+				filter.Add(fmt.Sprintf("code-%d", i))
+			}
+			for i := 0; i < tt.items; i++ {
+				assert.True(t, filter.MayContain(fmt.Sprintf("code-%d", i)))
+			}
+
+			falsePositives := 0
+			const probes = 100000
+			for i := 0; i < probes; i++ {
+				if filter.MayContain(fmt.Sprintf("other-%d", i)) {
+					falsePositives++
+				}
+			}
+			assert.Less(t, float64(falsePositives)/probes, tt.rate*2)
+			assert.InDelta(t, tt.rate, filter.EstimatedFalsePositiveRate(), tt.rate)
+		})
+	}
+}
+
+// TestFilter_MarshalBinary tests that a filter is the same after being saved and loaded
+func TestFilter_MarshalBinary(t *testing.T) {
+	filter := NewFilter(1000, 0.01)
+	filter.Add("abc")
+	filter.Add("def")
+
+	data, err := filter.MarshalBinary()
+	assert.NoError(t, err)
+
+	loaded := &Filter{}
+	assert.NoError(t, loaded.UnmarshalBinary(data))
+	assert.Equal(t, filter, loaded)
+	assert.True(t, loaded.MayContain("abc"))
+
+	assert.Error(t, loaded.UnmarshalBinary([]byte("garbage")))
+	assert.Error(t, loaded.UnmarshalBinary(data[:len(data)-1]))
+}
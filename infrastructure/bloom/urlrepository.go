@@ -0,0 +1,383 @@
+package bloom
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// journalSkew is subtracted from every time used to read the journal, so that clock differences between
+// the instances writing it cannot hide a short code.
+const journalSkew = 5 * time.Second
+
+// Store persists the filter and a journal of the short codes written since it was saved.
+type Store interface {
+	SaveSnapshot(ctx context.Context, data []byte, coveredUntil time.Time) error
+	LoadSnapshot(ctx context.Context) ([]byte, time.Time, error)
+	Record(ctx context.Context, shortCodes ...string) error
+	RecordedSince(ctx context.Context, since time.Time) ([]string, error)
+}
+
+// Subscriber announces the short codes written by the other instances of the service as they happen.
+// It calls subscribed every time the subscription is (re)established, and returns when it fails.
+type Subscriber interface {
+	Subscribe(ctx context.Context, subscribed func(), fn func(shortCode string)) error
+}
+
+// Options configures a URLRepository.
+type Options struct {
+	// ExpectedItems is the number of short codes the filter is sized for at least. Once it is fuller than its
+	// FalsePositiveRate allows, it is rebuilt for twice the number of existing short codes.
+	ExpectedItems int
+	// FalsePositiveRate is the share of unused short codes that still have to be checked in storage when the filter is full.
+	FalsePositiveRate float64
+	// Store persists the filter and journals the written short codes. It is required for the filter to be used.
+	Store Store
+	// Subscriber, if set, adds the short codes written by other instances without waiting for the next journal sync.
+	Subscriber Subscriber
+}
+
+// Stats reports how the filter is doing.
+type Stats struct {
+	// Ready is false until the filter holds every existing short code; until then all checks go to storage.
+	Ready bool `json:"ready"`
+	// Items and Bits describe the filter, and EstimatedFalsePositiveRate is the rate expected from how full it is.
+	Items                      uint64  `json:"items"`
+	Bits                       uint64  `json:"bits"`
+	EstimatedFalsePositiveRate float64 `json:"estimated_false_positive_rate"`
+	// Checks counts the uniqueness checks, and LocalAnswers those answered by the filter alone.
+	Checks       uint64 `json:"checks"`
+	LocalAnswers uint64 `json:"local_answers"`
+	// FalsePositives counts the short codes the filter thought might exist but storage found unused,
+	// and ObservedFalsePositiveRate is their share of all the unused short codes that were checked.
+	FalsePositives            uint64  `json:"false_positives"`
+	ObservedFalsePositiveRate float64 `json:"observed_false_positive_rate"`
+}
+
+// URLRepository answers uniqueness checks from a Bloom filter of the existing short codes in front of another
+// domain.URLRepository. A short code the filter has never seen is unique without asking storage;
+// any other short code is still checked in storage. Since the filter learns of the short codes written by other instances
+// a moment later, uniqueness checks are only a fast pre-check: new links must still be written with StoreNew.
+// Every short code written through this repository is journaled before it is written,
+// so that every instance adds it to its filter, even after a restart from an older snapshot.
+// Methods that are not overridden go straight to the underlying repository.
+type URLRepository struct {
+	domain.URLRepository
+	opts Options
+
+	mu           sync.RWMutex
+	filter       *Filter
+	building     *Filter // the filter being rebuilt, which also receives every added short code
+	ready        bool
+	syncedUntil  time.Time // every journal entry written before this time is in the filter
+	needsRebuild bool
+
+	checks         atomic.Uint64
+	localAnswers   atomic.Uint64
+	falsePositives atomic.Uint64
+}
+
+// NewURLRepository creates a new instance of URLRepository in front of the given repository.
+// The filter is only used once Run has loaded or built it; until then every check goes to storage.
+func NewURLRepository(repo domain.URLRepository, opts Options) *URLRepository {
+	// The same default as NewFilter, which the rebuilds are compared with
+	if opts.FalsePositiveRate <= 0 || opts.FalsePositiveRate >= 1 {
+		opts.FalsePositiveRate = 0.01
+	}
+	return &URLRepository{
+		URLRepository: repo,
+		opts:          opts,
+		filter:        NewFilter(opts.ExpectedItems, opts.FalsePositiveRate),
+	}
+}
+
+// IsUnique checks if a short code is unique, without asking storage when the filter has never seen it.
+// Another instance may have just written the short code, so the answer is only a hint for StoreNew.
+func (r *URLRepository) IsUnique(ctx context.Context, shortCode string) bool {
+	r.checks.Add(1)
+
+	r.mu.RLock()
+	mayExist := !r.ready || r.filter.MayContain(shortCode)
+	ready := r.ready
+	r.mu.RUnlock()
+	if !mayExist {
+		r.localAnswers.Add(1)
+		return true
+	}
+
+	unique := r.URLRepository.IsUnique(ctx, shortCode)
+	if unique && ready {
+		r.falsePositives.Add(1)
+	}
+	return unique
+}
+
+// Store journals the short code, then saves the URL entity.
+func (r *URLRepository) Store(ctx context.Context, url domain.URL) error {
+	if err := r.record(ctx, url.ShortCode); err != nil {
+		return err
+	}
+	return r.URLRepository.Store(ctx, url)
+}
+
+// StoreNew journals the short code, then saves the new URL entity.
+func (r *URLRepository) StoreNew(ctx context.Context, url domain.URL) error {
+	if err := r.record(ctx, url.ShortCode); err != nil {
+		return err
+	}
+	return r.URLRepository.StoreNew(ctx, url)
+}
+
+// StoreBatch journals the short codes, then saves the URL entities.
+func (r *URLRepository) StoreBatch(ctx context.Context, urls []domain.URL) ([]error, error) {
+	shortCodes := make([]string, len(urls))
+	for i, url := range urls {
+		shortCodes[i] = url.ShortCode
+	}
+	if err := r.record(ctx, shortCodes...); err != nil {
+		return nil, err
+	}
+	return r.URLRepository.StoreBatch(ctx, urls)
+}
+
+// Stats returns the size and the hit rates of the filter.
+func (r *URLRepository) Stats() Stats {
+	r.mu.RLock()
+	stats := Stats{
+		Ready:                      r.ready,
+		Items:                      r.filter.Count(),
+		Bits:                       r.filter.Bits(),
+		EstimatedFalsePositiveRate: r.filter.EstimatedFalsePositiveRate(),
+	}
+	r.mu.RUnlock()
+
+	stats.Checks = r.checks.Load()
+	stats.LocalAnswers = r.localAnswers.Load()
+	stats.FalsePositives = r.falsePositives.Load()
+	if unused := stats.LocalAnswers + stats.FalsePositives; unused > 0 {
+		stats.ObservedFalsePositiveRate = float64(stats.FalsePositives) / float64(unused)
+	}
+	return stats
+}
+
+// Run loads the filter from the last snapshot, or builds it from the keyspace if there is none,
+// then keeps it up to date until the context is cancelled: the journal is read every syncInterval,
+// and the filter is saved every snapshotInterval, after a rebuild if it is full.
+// It does nothing without a Store.
+func (r *URLRepository) Run(ctx context.Context, syncInterval, snapshotInterval time.Duration) {
+	if r.opts.Store == nil {
+		return
+	}
+	if r.opts.Subscriber != nil {
+		go r.subscribe(ctx, syncInterval)
+	}
+
+	if err := r.load(ctx); err != nil {
+		log.Printf("Error loading the Bloom filter snapshot, rebuilding it: %v", err)
+		r.needsRebuild = true
+	}
+	if r.needsRebuild {
+		if err := r.rebuild(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error rebuilding the Bloom filter: %v", err)
+		}
+	}
+
+	syncTicker := time.NewTicker(syncInterval)
+	defer syncTicker.Stop()
+	snapshotTicker := time.NewTicker(snapshotInterval)
+	defer snapshotTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-syncTicker.C:
+			if err := r.sync(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Error reading the Bloom filter journal: %v", err)
+			}
+		case <-snapshotTicker.C:
+			if err := r.snapshot(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Error saving the Bloom filter snapshot: %v", err)
+			}
+		}
+	}
+}
+
+// subscribe adds the short codes announced by the Subscriber until the context is cancelled,
+// subscribing again every retryInterval when the subscription fails.
+// The short codes announced in the meantime are missed, but the journal sync adds them.
+func (r *URLRepository) subscribe(ctx context.Context, retryInterval time.Duration) {
+	for {
+		err := r.opts.Subscriber.Subscribe(ctx, nil, r.add)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Error listening for new short codes, subscribing again in %v: %v", retryInterval, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// load replaces the filter with the last snapshot and the journal written since, and marks it ready.
+// It asks for a rebuild when there is no snapshot, or when the snapshot was made for fewer than ExpectedItems short codes.
+func (r *URLRepository) load(ctx context.Context) error {
+	data, coveredUntil, err := r.opts.Store.LoadSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		r.needsRebuild = true
+		return nil
+	}
+	filter := &Filter{}
+	if err := filter.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	if filter.Bits() < r.filter.Bits() {
+		r.needsRebuild = true
+		return nil
+	}
+
+	r.mu.Lock()
+	r.filter = filter
+	r.syncedUntil = coveredUntil
+	r.mu.Unlock()
+	if err := r.sync(ctx); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.ready = true
+	r.mu.Unlock()
+	return nil
+}
+
+// rebuild builds a new filter from every short code in the keyspace and swaps it in.
+// The new filter is sized for ExpectedItems short codes, or for twice the number of existing ones if that is more,
+// so that it is not full again soon. The current filter keeps answering meanwhile,
+// and short codes written during the rebuild are added to both.
+func (r *URLRepository) rebuild(ctx context.Context) error {
+	count, err := r.URLRepository.Count(ctx)
+	if err != nil {
+		return err
+	}
+	items := r.opts.ExpectedItems
+	if 2*count > int64(items) {
+		items = int(2 * count)
+	}
+
+	r.mu.Lock()
+	r.building = NewFilter(items, r.opts.FalsePositiveRate)
+	start := time.Now()
+	r.mu.Unlock()
+
+	err = r.URLRepository.Iterate(ctx, func(url domain.URL) error {
+		r.mu.Lock()
+		r.building.Add(url.ShortCode)
+		r.mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		r.mu.Lock()
+		r.building = nil
+		r.mu.Unlock()
+		return err
+	}
+
+	// Short codes written while the keyspace was scanned may have been missed by the scan, so add them from the journal
+	shortCodes, err := r.opts.Store.RecordedSince(ctx, start.Add(-journalSkew))
+	if err != nil {
+		r.mu.Lock()
+		r.building = nil
+		r.mu.Unlock()
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, shortCode := range shortCodes {
+		r.building.Add(shortCode)
+	}
+	r.filter, r.building = r.building, nil
+	r.syncedUntil = start
+	r.ready = true
+	r.needsRebuild = false
+	return nil
+}
+
+// sync adds the short codes journaled since the last sync to the filter.
+func (r *URLRepository) sync(ctx context.Context) error {
+	r.mu.RLock()
+	since := r.syncedUntil.Add(-journalSkew)
+	r.mu.RUnlock()
+
+	now := time.Now()
+	shortCodes, err := r.opts.Store.RecordedSince(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, shortCode := range shortCodes {
+		r.addLocked(shortCode)
+	}
+	r.syncedUntil = now
+	return nil
+}
+
+// snapshot saves the filter, after rebuilding it if it is full: its estimated false-positive rate exceeds FalsePositiveRate.
+func (r *URLRepository) snapshot(ctx context.Context) error {
+	r.mu.RLock()
+	full := r.filter.EstimatedFalsePositiveRate() > r.opts.FalsePositiveRate
+	ready := r.ready
+	r.mu.RUnlock()
+	if !ready || full {
+		// Deleted and expired short codes are only dropped from the filter by a rebuild
+		if err := r.rebuild(ctx); err != nil {
+			return err
+		}
+	}
+
+	r.mu.RLock()
+	data, err := r.filter.MarshalBinary()
+	coveredUntil := r.syncedUntil.Add(-journalSkew)
+	r.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return r.opts.Store.SaveSnapshot(ctx, data, coveredUntil)
+}
+
+// record journals short codes and adds them to the local filter before they are written,
+// so that no instance can consider them unique once they exist.
+func (r *URLRepository) record(ctx context.Context, shortCodes ...string) error {
+	for _, shortCode := range shortCodes {
+		r.add(shortCode)
+	}
+	if r.opts.Store == nil {
+		return nil
+	}
+	return r.opts.Store.Record(ctx, shortCodes...)
+}
+
+// add adds a short code to the filter, and to the one being rebuilt.
+func (r *URLRepository) add(shortCode string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addLocked(shortCode)
+}
+
+// addLocked is add for callers that already hold the lock.
+func (r *URLRepository) addLocked(shortCode string) {
+	r.filter.Add(shortCode)
+	if r.building != nil {
+		r.building.Add(shortCode)
+	}
+}
@@ -0,0 +1,155 @@
+package bloom
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+	redisRepo "github.com/terenzio/URL-Shortening-Service/infrastructure/redis"
+)
+
+// countingRepository wraps a repository and counts the uniqueness checks that reach it.
+type countingRepository struct {
+	domain.URLRepository
+	checks atomic.Int64
+}
+
+// IsUnique counts the check and forwards it to the wrapped repository.
+func (r *countingRepository) IsUnique(ctx context.Context, shortCode string) bool {
+	r.checks.Add(1)
+	return r.URLRepository.IsUnique(ctx, shortCode)
+}
+
+// newTestRepository starts a mini Redis server and returns a counting repository connected to it.
+func newTestRepository(t *testing.T) (*redis.Client, *countingRepository) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	t.Cleanup(mr.Close)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return rdb, &countingRepository{URLRepository: redisRepo.NewURLRepository(rdb)}
+}
+
+// startRepository creates a filtered repository, runs it until the test ends, and waits until the filter is ready.
+func startRepository(t *testing.T, rdb *redis.Client, backing domain.URLRepository) *URLRepository {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	repo := NewURLRepository(backing, Options{ExpectedItems: 1000, FalsePositiveRate: 0.01, Store: redisRepo.NewBloomStore(rdb)})
+	go repo.Run(ctx, 10*time.Millisecond, time.Hour)
+	assert.Eventually(t, func() bool { return repo.Stats().Ready }, time.Second, 10*time.Millisecond)
+	return repo
+}
+
+// TestURLRepository_IsUnique tests that only short codes the filter may contain are checked in storage.
+func TestURLRepository_IsUnique(t *testing.T) {
+	ctx := context.Background()
+	rdb, backing := newTestRepository(t)
+	assert.NoError(t, backing.Store(ctx, domain.URL{ShortCode: "existing", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}))
+	repo := startRepository(t, rdb, backing)
+
+	// A short code stored while the filter is running must never be unique
+	assert.NoError(t, repo.Store(ctx, domain.URL{ShortCode: "stored", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}))
+	// A short code in the filter that is not in storage is a false positive
+	repo.add("ghost")
+
+	tests := []struct {
+		shortCode     string
+		unique        bool
+		storageChecks int64
+	}{
+		{shortCode: "existing", unique: false, storageChecks: 1},
+		{shortCode: "stored", unique: false, storageChecks: 1},
+		{shortCode: "ghost", unique: true, storageChecks: 1},
+		{shortCode: "unused", unique: true, storageChecks: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shortCode, func(t *testing.T) {
+			before := backing.checks.Load()
+			assert.Equal(t, tt.unique, repo.IsUnique(ctx, tt.shortCode))
+			assert.Equal(t, tt.storageChecks, backing.checks.Load()-before)
+		})
+	}
+
+	stats := repo.Stats()
+	assert.Equal(t, uint64(4), stats.Checks)
+	assert.Equal(t, uint64(1), stats.LocalAnswers)
+	assert.Equal(t, uint64(1), stats.FalsePositives)
+	assert.Equal(t, 0.5, stats.ObservedFalsePositiveRate)
+}
+
+// TestURLRepository_Snapshot tests that an instance started from a snapshot also knows the short codes written since.
+func TestURLRepository_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	rdb, backing := newTestRepository(t)
+
+	first := startRepository(t, rdb, backing)
+	assert.NoError(t, first.Store(ctx, domain.URL{ShortCode: "before", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}))
+	assert.NoError(t, first.sync(ctx))
+	assert.NoError(t, first.snapshot(ctx))
+
+	// Written after the snapshot by another instance
+	writer := NewURLRepository(backing, Options{Store: redisRepo.NewBloomStore(rdb)})
+	assert.NoError(t, writer.Store(ctx, domain.URL{ShortCode: "after", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}))
+
+	// The second instance must load the snapshot instead of scanning the keyspace
+	assert.NoError(t, rdb.Del(ctx, "short:before").Err())
+	second := startRepository(t, rdb, backing)
+	second.mu.RLock()
+	assert.True(t, second.filter.MayContain("before"), "The short code should come from the snapshot")
+	assert.True(t, second.filter.MayContain("after"), "The short code should come from the journal")
+	second.mu.RUnlock()
+
+	// The first instance picks up the other instance's short code from the journal
+	assert.Eventually(t, func() bool {
+		first.mu.RLock()
+		defer first.mu.RUnlock()
+		return first.filter.MayContain("after")
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestURLRepository_NotReady tests that every check goes to storage until the filter is loaded.
+func TestURLRepository_NotReady(t *testing.T) {
+	ctx := context.Background()
+	_, backing := newTestRepository(t)
+	repo := NewURLRepository(backing, Options{ExpectedItems: 1000, FalsePositiveRate: 0.01})
+
+	assert.True(t, repo.IsUnique(ctx, "unused"))
+	assert.Equal(t, int64(1), backing.checks.Load())
+	assert.False(t, repo.Stats().Ready)
+}
+
+// TestURLRepository_Resize tests that a full filter is rebuilt for twice the existing short codes, and only once.
+func TestURLRepository_Resize(t *testing.T) {
+	ctx := context.Background()
+	rdb, backing := newTestRepository(t)
+	for _, shortCode := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		assert.NoError(t, backing.Store(ctx, domain.URL{ShortCode: shortCode, OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}))
+	}
+
+	// Sized for 2 short codes, the filter is full as soon as it is built
+	repo := NewURLRepository(backing, Options{ExpectedItems: 2, FalsePositiveRate: 0.01, Store: redisRepo.NewBloomStore(rdb)})
+	for _, shortCode := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		repo.add(shortCode)
+	}
+	repo.ready = true
+	assert.NoError(t, repo.snapshot(ctx))
+	assert.Equal(t, NewFilter(20, 0.01).Bits(), repo.Stats().Bits)
+
+	// The rebuilt filter is not full, so the next snapshot keeps it
+	filter := repo.filter
+	assert.NoError(t, repo.snapshot(ctx))
+	assert.Same(t, filter, repo.filter)
+
+	// Another instance loads the larger snapshot instead of rebuilding
+	other := NewURLRepository(backing, Options{ExpectedItems: 2, FalsePositiveRate: 0.01, Store: redisRepo.NewBloomStore(rdb)})
+	assert.NoError(t, other.load(ctx))
+	assert.False(t, other.needsRebuild)
+	assert.Equal(t, repo.Stats().Bits, other.Stats().Bits)
+}
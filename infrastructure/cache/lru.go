@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// entry is a cached lookup result. A nil url records that the short code does not exist.
+type entry struct {
+	shortCode string
+	url       *domain.URL
+	expiresAt time.Time
+}
+
+// lru is a fixed-size, least recently used cache of lookup results with a per-entry expiry.
+// It is safe for concurrent use.
+type lru struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+// newLRU creates an lru that holds at most capacity entries.
+func newLRU(capacity int) *lru {
+	return &lru{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element, capacity),
+	}
+}
+
+// get returns the cached entry for the short code, if there is one that has not expired.
+func (c *lru) get(shortCode string, now time.Time) (entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[shortCode]
+	if !ok {
+		return entry{}, false
+	}
+	e := element.Value.(entry)
+	if !now.Before(e.expiresAt) {
+		c.order.Remove(element)
+		delete(c.items, shortCode)
+		return entry{}, false
+	}
+	c.order.MoveToFront(element)
+	return e, true
+}
+
+// set caches an entry, evicting the least recently used one if the cache is full.
+func (c *lru) set(e entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[e.shortCode]; ok {
+		element.Value = e
+		c.order.MoveToFront(element)
+		return
+	}
+	c.items[e.shortCode] = c.order.PushFront(e)
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(entry).shortCode)
+	}
+}
+
+// remove drops the entry of the short code, if there is one.
+func (c *lru) remove(shortCode string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[shortCode]; ok {
+		c.order.Remove(element)
+		delete(c.items, shortCode)
+	}
+}
+
+// clear drops every entry.
+func (c *lru) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.items = make(map[string]*list.Element, c.capacity)
+}
+
+// len returns the number of cached entries, including expired ones that were not looked up yet.
+func (c *lru) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+	"golang.org/x/sync/singleflight"
+)
+
+// Invalidator broadcasts changed short codes to every instance of the service,
+// so that each of them can drop its cached copy.
+type Invalidator interface {
+	Publish(ctx context.Context, shortCodes ...string) error
+	// Subscribe calls subscribed every time the subscription is (re)established, and fn for every short code
+	// announced afterwards, until the context is cancelled or the subscription fails.
+	Subscribe(ctx context.Context, subscribed func(), fn func(shortCode string)) error
+}
+
+// The subscription to the invalidations is retried with an exponential backoff between these delays.
+const (
+	minListenBackoff = time.Second
+	maxListenBackoff = time.Minute
+)
+
+// Options configures a caching URLRepository.
+type Options struct {
+	// Capacity is the maximum number of short codes kept in memory.
+	// With a capacity of 0 nothing is cached, but writes still publish their invalidations.
+	Capacity int
+	// TTL is the longest time a found URL is served from memory. It is shortened to the URL's own expiry.
+	TTL time.Duration
+	// NegativeTTL is how long a short code that was not found is remembered as missing.
+	NegativeTTL time.Duration
+	// Invalidator, if set, shares invalidations with the other instances of the service.
+	Invalidator Invalidator
+}
+
+// URLRepository is a read-through cache in front of another domain.URLRepository.
+// Lookups by short code are answered from memory when possible, and concurrent lookups of the same
+// short code that miss the cache share a single call to the underlying repository.
+// Every write through this repository drops the cached copy locally and, with an Invalidator, on every other instance.
+// Methods that are not overridden go straight to the underlying repository.
+type URLRepository struct {
+	domain.URLRepository
+	entries     *lru
+	lookups     singleflight.Group
+	generation  atomic.Uint64
+	ttl         time.Duration
+	negativeTTL time.Duration
+	invalidator Invalidator
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+}
+
+// NewURLRepository creates a new instance of URLRepository that caches lookups of the given repository.
+func NewURLRepository(repo domain.URLRepository, opts Options) *URLRepository {
+	return &URLRepository{
+		URLRepository: repo,
+		entries:       newLRU(opts.Capacity),
+		ttl:           opts.TTL,
+		negativeTTL:   opts.NegativeTTL,
+		invalidator:   opts.Invalidator,
+		minBackoff:    minListenBackoff,
+		maxBackoff:    maxListenBackoff,
+	}
+}
+
+// FindByShortCode retrieves a URL by its short code, from memory if it was looked up recently.
+// A short code that was recently not found fails with domain.ErrURLNotFound without asking the underlying repository.
+func (r *URLRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	if e, ok := r.entries.get(shortCode, time.Now()); ok {
+		if e.url == nil {
+			return nil, fmt.Errorf("%w: %s", domain.ErrURLNotFound, shortCode)
+		}
+		return copyURL(e.url), nil
+	}
+
+	result, err, _ := r.lookups.Do(shortCode, func() (interface{}, error) {
+		// The lookup is shared by every waiting caller, so it must not fail because the first caller went away.
+		// A result is only cached if nothing was invalidated while it was being looked up, as it may be stale.
+		generation := r.generation.Load()
+		url, err := r.URLRepository.FindByShortCode(context.WithoutCancel(ctx), shortCode)
+		now := time.Now()
+		if errors.Is(err, domain.ErrURLNotFound) {
+			if r.negativeTTL > 0 && r.generation.Load() == generation {
+				r.entries.set(entry{shortCode: shortCode, expiresAt: now.Add(r.negativeTTL)})
+			}
+			return nil, err
+		} else if err != nil {
+			return nil, err
+		}
+		if r.generation.Load() != generation {
+			return url, nil
+		}
+
+		// Never serve a URL from memory after the URL itself has expired
+		expiresAt := now.Add(r.ttl)
+		if !url.Expiry.IsZero() && url.Expiry.Before(expiresAt) {
+			expiresAt = url.Expiry
+		}
+		r.entries.set(entry{shortCode: shortCode, url: copyURL(url), expiresAt: expiresAt})
+		return url, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyURL(result.(*domain.URL)), nil
+}
+
+// Store saves a URL entity and invalidates its cached copy.
+func (r *URLRepository) Store(ctx context.Context, url domain.URL) error {
+	err := r.URLRepository.Store(ctx, url)
+	r.invalidate(ctx, url.ShortCode)
+	return err
+}
+
+// StoreNew saves a new URL entity and invalidates its cached copy, which may remember the short code as missing.
+func (r *URLRepository) StoreNew(ctx context.Context, url domain.URL) error {
+	err := r.URLRepository.StoreNew(ctx, url)
+	r.invalidate(ctx, url.ShortCode)
+	return err
+}
+
+// StoreBatch saves several URL entities and invalidates their cached copies.
+func (r *URLRepository) StoreBatch(ctx context.Context, urls []domain.URL) ([]error, error) {
+	errs, err := r.URLRepository.StoreBatch(ctx, urls)
+	shortCodes := make([]string, len(urls))
+	for i, url := range urls {
+		shortCodes[i] = url.ShortCode
+	}
+	r.invalidate(ctx, shortCodes...)
+	return errs, err
+}
+
+// Delete removes a URL and invalidates its cached copy.
+func (r *URLRepository) Delete(ctx context.Context, shortCode string) error {
+	err := r.URLRepository.Delete(ctx, shortCode)
+	r.invalidate(ctx, shortCode)
+	return err
+}
+
+// Expire makes a URL expire at once and invalidates its cached copy.
+func (r *URLRepository) Expire(ctx context.Context, shortCode string) error {
+	err := r.URLRepository.Expire(ctx, shortCode)
+	r.invalidate(ctx, shortCode)
+	return err
+}
+
+// SetPageMetadata stores the page metadata of a link and invalidates its cached copy.
+func (r *URLRepository) SetPageMetadata(ctx context.Context, shortCode, originalURL string, page domain.PageMetadata) error {
+	err := r.URLRepository.SetPageMetadata(ctx, shortCode, originalURL, page)
+	r.invalidate(ctx, shortCode)
+	return err
+}
+
+// Listen drops the cached copies of the short codes invalidated by other instances, until the context is cancelled.
+// The cache is flushed every time the subscription is confirmed, since the invalidations published before were missed.
+// When the subscription fails, it subscribes again after an exponential backoff. It does nothing without an Invalidator.
+func (r *URLRepository) Listen(ctx context.Context) {
+	if r.invalidator == nil {
+		return
+	}
+	backoff := r.minBackoff
+	for {
+		subscribed := time.Now()
+		err := r.invalidator.Subscribe(ctx, r.flush, r.drop)
+		if ctx.Err() != nil {
+			return
+		}
+		// A subscription that held for a while starts the backoff over
+		if time.Since(subscribed) > r.maxBackoff {
+			backoff = r.minBackoff
+		}
+		log.Printf("Error listening for cache invalidations, subscribing again in %v: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, r.maxBackoff)
+	}
+}
+
+// invalidate drops the cached copies of the short codes on this instance and, with an Invalidator, on all others.
+// The local copies are dropped even if the write failed, since its outcome is unknown.
+func (r *URLRepository) invalidate(ctx context.Context, shortCodes ...string) {
+	for _, shortCode := range shortCodes {
+		r.drop(shortCode)
+	}
+	if r.invalidator == nil || len(shortCodes) == 0 {
+		return
+	}
+	if err := r.invalidator.Publish(ctx, shortCodes...); err != nil {
+		log.Printf("Error publishing cache invalidation: %v", err)
+	}
+}
+
+// drop removes the cached copy of a short code, and keeps lookups that are in flight from caching what they find.
+func (r *URLRepository) drop(shortCode string) {
+	r.generation.Add(1)
+	r.lookups.Forget(shortCode)
+	r.entries.remove(shortCode)
+}
+
+// flush drops every cached copy, and keeps lookups that are in flight from caching what they find.
+func (r *URLRepository) flush() {
+	r.generation.Add(1)
+	r.entries.clear()
+}
+
+// copyURL returns a copy of the URL, so that callers cannot change the cached value.
+func copyURL(url *domain.URL) *domain.URL {
+	c := *url
+	c.Tags = append([]string(nil), url.Tags...)
+	return &c
+}
@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+	redisRepo "github.com/terenzio/URL-Shortening-Service/infrastructure/redis"
+)
+
+// countingRepository wraps a repository and counts the lookups that reach it.
+type countingRepository struct {
+	domain.URLRepository
+	lookups atomic.Int64
+	delay   time.Duration
+}
+
+// FindByShortCode counts the lookup and forwards it to the wrapped repository.
+func (r *countingRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	r.lookups.Add(1)
+	time.Sleep(r.delay)
+	return r.URLRepository.FindByShortCode(ctx, shortCode)
+}
+
+// newTestRepository starts a mini Redis server and returns a counting repository connected to it.
+func newTestRepository(t *testing.T) (*redis.Client, *countingRepository) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	t.Cleanup(mr.Close)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	return rdb, &countingRepository{URLRepository: redisRepo.NewURLRepository(rdb)}
+}
+
+// TestURLRepository_FindByShortCode tests that lookups, including misses, are served from memory.
+func TestURLRepository_FindByShortCode(t *testing.T) {
+	ctx := context.Background()
+	_, backing := newTestRepository(t)
+	repo := NewURLRepository(backing, Options{Capacity: 10, TTL: time.Minute, NegativeTTL: time.Minute})
+	assert.NoError(t, backing.Store(ctx, domain.URL{ShortCode: "abc", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}))
+
+	for i := 0; i < 3; i++ {
+		url, err := repo.FindByShortCode(ctx, "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", url.OriginalURL)
+		url.OriginalURL = "https://changed-by-caller.com"
+
+		_, err = repo.FindByShortCode(ctx, "missing")
+		assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	}
+	assert.Equal(t, int64(2), backing.lookups.Load(), "Only the first lookup of each short code should reach Redis")
+
+	url, _ := repo.FindByShortCode(ctx, "abc")
+	assert.Equal(t, "https://example.com", url.OriginalURL, "Callers must not be able to change the cached URL")
+}
+
+// TestURLRepository_ExpiryCap tests that a URL is never served from memory after it expired.
+func TestURLRepository_ExpiryCap(t *testing.T) {
+	ctx := context.Background()
+	_, backing := newTestRepository(t)
+	repo := NewURLRepository(backing, Options{Capacity: 10, TTL: time.Hour})
+	assert.NoError(t, backing.Store(ctx, domain.URL{ShortCode: "abc", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}))
+
+	_, err := repo.FindByShortCode(ctx, "abc")
+	assert.NoError(t, err)
+	e, ok := repo.entries.get("abc", time.Now())
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), e.expiresAt, time.Second)
+	_, ok = repo.entries.get("abc", time.Now().Add(61*time.Minute))
+	assert.False(t, ok, "The entry should expire with the URL")
+}
+
+// TestURLRepository_Singleflight tests that concurrent misses of the same short code share one lookup.
+func TestURLRepository_Singleflight(t *testing.T) {
+	ctx := context.Background()
+	_, backing := newTestRepository(t)
+	backing.delay = 50 * time.Millisecond
+	repo := NewURLRepository(backing, Options{Capacity: 10, TTL: time.Minute})
+	assert.NoError(t, backing.Store(ctx, domain.URL{ShortCode: "abc", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.FindByShortCode(ctx, "abc")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1), backing.lookups.Load())
+}
+
+// TestURLRepository_Invalidation tests that writes on one instance drop the cached copies on every instance.
+func TestURLRepository_Invalidation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rdb, backing := newTestRepository(t)
+
+	// Two instances sharing the same Redis
+	first := NewURLRepository(backing, Options{Capacity: 10, TTL: time.Hour, NegativeTTL: time.Hour, Invalidator: redisRepo.NewInvalidator(rdb)})
+	second := NewURLRepository(backing, Options{Capacity: 10, TTL: time.Hour, NegativeTTL: time.Hour, Invalidator: redisRepo.NewInvalidator(rdb)})
+	go second.Listen(ctx)
+
+	// Wait until the second instance is subscribed
+	assert.Eventually(t, func() bool {
+		count, _ := rdb.PubSubNumSub(ctx, "shortener:invalidate").Result()
+		return count["shortener:invalidate"] == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// The second instance remembers that the short code does not exist
+	_, err := second.FindByShortCode(ctx, "abc")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	// Creating it on the first instance must make it visible on the second
+	assert.NoError(t, first.Store(ctx, domain.URL{ShortCode: "abc", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}))
+	assert.Eventually(t, func() bool {
+		url, err := second.FindByShortCode(ctx, "abc")
+		return err == nil && url.OriginalURL == "https://example.com"
+	}, time.Second, 10*time.Millisecond)
+
+	// Deleting it on the first instance must make it disappear on the second
+	assert.NoError(t, first.Delete(ctx, "abc"))
+	assert.Eventually(t, func() bool {
+		_, err := second.FindByShortCode(ctx, "abc")
+		return errors.Is(err, domain.ErrURLNotFound)
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestURLRepository_Reconnect tests that an instance whose connection to Redis drops flushes its cache once it is subscribed again,
+// since the invalidations published meanwhile were missed.
+func TestURLRepository_Reconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	t.Cleanup(mr.Close)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	backing := redisRepo.NewURLRepository(rdb)
+	repo := NewURLRepository(backing, Options{Capacity: 10, TTL: time.Hour, NegativeTTL: time.Hour, Invalidator: redisRepo.NewInvalidator(rdb)})
+	repo.minBackoff, repo.maxBackoff = time.Millisecond, 4*time.Millisecond
+	go repo.Listen(ctx)
+	subscribed := func() bool {
+		count, _ := rdb.PubSubNumSub(ctx, "shortener:invalidate").Result()
+		return count["shortener:invalidate"] == 1
+	}
+	assert.Eventually(t, subscribed, time.Second, 10*time.Millisecond)
+
+	// The instance remembers that the short code does not exist
+	_, err = repo.FindByShortCode(ctx, "abc")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	// The connection drops, and the short code is created without the instance hearing of it
+	mr.Close()
+	assert.NoError(t, mr.Restart())
+	assert.NoError(t, backing.Store(ctx, domain.URL{ShortCode: "abc", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}))
+
+	assert.Eventually(t, subscribed, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		url, err := repo.FindByShortCode(ctx, "abc")
+		return err == nil && url.OriginalURL == "https://example.com"
+	}, time.Second, 10*time.Millisecond)
+}
+
+// flakyInvalidator fails its first subscriptions, then confirms the next one and holds it until the context is cancelled.
+type flakyInvalidator struct {
+	failures      int
+	subscriptions atomic.Int64
+}
+
+func (i *flakyInvalidator) Publish(ctx context.Context, shortCodes ...string) error {
+	return nil
+}
+
+func (i *flakyInvalidator) Subscribe(ctx context.Context, subscribed func(), fn func(shortCode string)) error {
+	if int(i.subscriptions.Add(1)) <= i.failures {
+		return errors.New("connection refused")
+	}
+	subscribed()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// TestURLRepository_ListenRetry tests that a failed subscription is retried, and that the cache is flushed once it succeeds.
+func TestURLRepository_ListenRetry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, backing := newTestRepository(t)
+	invalidator := &flakyInvalidator{failures: 2}
+	repo := NewURLRepository(backing, Options{Capacity: 10, TTL: time.Hour, NegativeTTL: time.Hour, Invalidator: invalidator})
+	repo.minBackoff, repo.maxBackoff = time.Millisecond, 4*time.Millisecond
+
+	// A miss is remembered before the subscription fails
+	_, err := repo.FindByShortCode(ctx, "abc")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	assert.Equal(t, 1, repo.entries.len())
+
+	done := make(chan struct{})
+	go func() {
+		repo.Listen(ctx)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return invalidator.subscriptions.Load() == 3 }, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return repo.entries.len() == 0 }, time.Second, time.Millisecond)
+
+	// Listen only returns once the context is cancelled
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Listen did not return after the context was cancelled")
+	}
+}
+
+// TestLRU_Eviction tests that the least recently used entry is evicted first.
+func TestLRU_Eviction(t *testing.T) {
+	now := time.Now()
+	cache := newLRU(2)
+	cache.set(entry{shortCode: "a", expiresAt: now.Add(time.Hour)})
+	cache.set(entry{shortCode: "b", expiresAt: now.Add(time.Hour)})
+	cache.get("a", now)
+	cache.set(entry{shortCode: "c", expiresAt: now.Add(time.Hour)})
+
+	_, okA := cache.get("a", now)
+	_, okB := cache.get("b", now)
+	_, okC := cache.get("c", now)
+	assert.True(t, okA)
+	assert.False(t, okB, "The least recently used entry should be evicted")
+	assert.True(t, okC)
+	assert.Equal(t, 2, cache.len())
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// Config holds the settings of the service.
+// Every setting can be overridden with an environment variable, and falls back to a default suited for local development.
+type Config struct {
+	// ServerAddr is the address the HTTP server listens on.
+	ServerAddr string
+	// PublicBaseURL is the scheme and host used to build the shortened URLs returned to clients.
+	PublicBaseURL string
+	// RedisAddr, RedisPassword and RedisDB select the Redis server that stores the links.
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+	// BulkLimit is the maximum number of links accepted by a single bulk creation request.
+	BulkLimit int
+	// CacheSize is the number of short codes each instance keeps in memory for redirects; 0 disables the cache.
+	// CacheTTL is the longest time a link is served from memory, and CacheNegativeTTL how long unknown short codes are remembered.
+	CacheSize        int
+	CacheTTL         time.Duration
+	CacheNegativeTTL time.Duration
+	// BloomCapacity is the number of short codes the Bloom filter of uniqueness checks is sized for at least; 0 disables the filter.
+	// BloomFalsePositiveRate is the share of unused short codes still checked in Redis once the filter is full.
+	BloomCapacity          int
+	BloomFalsePositiveRate float64
+	// Deduplicate makes shortening the same original URL again return the existing live link of the same API key.
+	Deduplicate bool
+	// CanonicalSortQuery, CanonicalStripParams and CanonicalStripFragment enable the optional URL canonicalization steps:
+	// sorting the query, removing the listed query parameters (e.g. "utm_*") and removing the fragment.
+	CanonicalSortQuery     bool
+	CanonicalStripParams   []string
+	CanonicalStripFragment bool
+	// MetadataWorkers is the number of concurrent fetches of the target pages of new links; 0 disables fetching.
+	// MetadataQueueSize is the number of links waiting to be fetched, beyond which new links are not fetched.
+	// MetadataTimeout limits each fetch, and MetadataMaxBytes is the part of a page that is read.
+	MetadataWorkers   int
+	MetadataQueueSize int
+	MetadataTimeout   time.Duration
+	MetadataMaxBytes  int
+	// LinkCookieSecret signs the cookies that let visitors follow a password-protected link again without its password,
+	// for LinkUnlockTTL. Every instance must share it; each instance uses a random secret when it is empty.
+	// PasswordMaxAttempts is the number of wrong passwords a client can enter for a link within PasswordAttemptWindow.
+	LinkCookieSecret      string
+	LinkUnlockTTL         time.Duration
+	PasswordMaxAttempts   int
+	PasswordAttemptWindow time.Duration
+	// PendingLinkPage shows a "not yet available" page for scheduled links before their activation time, instead of a plain 404.
+	PendingLinkPage bool
+	// RedirectType is how links without a redirect type of their own redirect: "301", "302", "307", "308" or "interstitial".
+	RedirectType string
+	// GeoIPDatabase is the path of a MaxMind-format (.mmdb) country database, such as GeoLite2-Country,
+	// used to route links by country and count clicks per country; an empty path disables both.
+	// The file is checked for changes every GeoIPReloadInterval and reloaded when it was replaced.
+	GeoIPDatabase       string
+	GeoIPReloadInterval time.Duration
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies whose X-Forwarded-For header is trusted
+	// to give the client IP address. With none, the address of the connection is used.
+	TrustedProxies []string
+	// APIKeys are the keys accepted by the management API. The API is open when no key is configured.
+	// They belong to the default workspace.
+	APIKeys []string
+	// WorkspacesFile is the path of a JSON file listing the workspaces besides the default one, with their
+	// API keys, domain and quota; see LoadWorkspaces. An empty path leaves only the default workspace.
+	WorkspacesFile string
+}
+
+// Load reads the configuration from the environment.
+func Load() Config {
+	return Config{
+		ServerAddr:             getString("SHORTENER_ADDR", ":9000"),
+		PublicBaseURL:          getString("SHORTENER_PUBLIC_URL", "http://localhost:9000"),
+		RedisAddr:              getString("SHORTENER_REDIS_ADDR", "localhost:6379"),
+		RedisPassword:          getString("SHORTENER_REDIS_PASSWORD", ""),
+		RedisDB:                getInt("SHORTENER_REDIS_DB", 0),
+		BulkLimit:              getInt("SHORTENER_BULK_LIMIT", 500),
+		CacheSize:              getInt("SHORTENER_CACHE_SIZE", 100000),
+		CacheTTL:               getDuration("SHORTENER_CACHE_TTL", time.Minute),
+		CacheNegativeTTL:       getDuration("SHORTENER_CACHE_NEGATIVE_TTL", 5*time.Second),
+		BloomCapacity:          getInt("SHORTENER_BLOOM_CAPACITY", 1000000),
+		BloomFalsePositiveRate: getFloat("SHORTENER_BLOOM_FALSE_POSITIVE_RATE", 0.01),
+		Deduplicate:            getBool("SHORTENER_DEDUPLICATE", false),
+		CanonicalSortQuery:     getBool("SHORTENER_CANONICAL_SORT_QUERY", false),
+		CanonicalStripParams:   getList("SHORTENER_CANONICAL_STRIP_PARAMS"),
+		CanonicalStripFragment: getBool("SHORTENER_CANONICAL_STRIP_FRAGMENT", false),
+		MetadataWorkers:        getInt("SHORTENER_METADATA_WORKERS", 4),
+		MetadataQueueSize:      getInt("SHORTENER_METADATA_QUEUE_SIZE", 1000),
+		MetadataTimeout:        getDuration("SHORTENER_METADATA_TIMEOUT", 5*time.Second),
+		MetadataMaxBytes:       getInt("SHORTENER_METADATA_MAX_BYTES", 1<<20),
+		LinkCookieSecret:       getString("SHORTENER_LINK_COOKIE_SECRET", ""),
+		LinkUnlockTTL:          getDuration("SHORTENER_LINK_UNLOCK_TTL", time.Hour),
+		PasswordMaxAttempts:    getInt("SHORTENER_PASSWORD_MAX_ATTEMPTS", 5),
+		PasswordAttemptWindow:  getDuration("SHORTENER_PASSWORD_ATTEMPT_WINDOW", 15*time.Minute),
+		PendingLinkPage:        getBool("SHORTENER_PENDING_LINK_PAGE", true),
+		RedirectType:           getString("SHORTENER_REDIRECT_TYPE", "307"),
+		GeoIPDatabase:          getString("SHORTENER_GEOIP_DATABASE", ""),
+		GeoIPReloadInterval:    getDuration("SHORTENER_GEOIP_RELOAD_INTERVAL", time.Minute),
+		TrustedProxies:         getListOr("SHORTENER_TRUSTED_PROXIES", []string{"127.0.0.1"}),
+		APIKeys:                getList("SHORTENER_API_KEYS"),
+		WorkspacesFile:         getString("SHORTENER_WORKSPACES_FILE", ""),
+	}
+}
+
+// LoadWorkspaces reads the workspaces of a JSON file: an array of objects with an "id", a "name",
+// the "api_keys" of the workspace, the "domain" its redirects are served on, and a "quota", e.g.
+// [{"id": "marketing", "api_keys": ["..."], "domain": "go.marketing.example.com", "quota": {"max_links": 10000}}].
+// An empty path gives no workspaces.
+func LoadWorkspaces(path string) ([]domain.Workspace, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var workspaces []domain.Workspace
+	if err := json.Unmarshal(data, &workspaces); err != nil {
+		return nil, fmt.Errorf("invalid workspaces file %s: %w", path, err)
+	}
+	return workspaces, nil
+}
+
+// getString returns the value of the environment variable, or the fallback if it is not set.
+func getString(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// getInt returns the integer value of the environment variable, or the fallback if it is not set or not a number.
+func getInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Ignoring invalid value %q for %s, using %d", value, key, fallback)
+		return fallback
+	}
+	return number
+}
+
+// getBool returns the boolean value of the environment variable, e.g. "true" or "1", or the fallback if it is not set or invalid.
+func getBool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Ignoring invalid value %q for %s, using %t", value, key, fallback)
+		return fallback
+	}
+	return b
+}
+
+// getFloat returns the decimal value of the environment variable, or the fallback if it is not set or not a number.
+func getFloat(key string, fallback float64) float64 {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Ignoring invalid value %q for %s, using %g", value, key, fallback)
+		return fallback
+	}
+	return number
+}
+
+// getDuration returns the duration value of the environment variable, e.g. "90s", or the fallback if it is not set or invalid.
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Ignoring invalid value %q for %s, using %s", value, key, fallback)
+		return fallback
+	}
+	return duration
+}
+
+// getList returns the comma-separated values of the environment variable, without empty entries.
+func getList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getListOr returns the comma-separated values of the environment variable, or the fallback if it is not set.
+// An empty value gives an empty list.
+func getListOr(key string, fallback []string) []string {
+	if _, ok := os.LookupEnv(key); !ok {
+		return fallback
+	}
+	return getList(key)
+}
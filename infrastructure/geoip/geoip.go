@@ -0,0 +1,127 @@
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// countryRecord is the part of a GeoIP2 or GeoLite2 Country or City record that is read.
+// The registered country is the fallback for addresses whose users are not located, such as those of some mobile networks.
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// Database looks up the country of IP addresses in a MaxMind-format (.mmdb) database file,
+// such as GeoLite2-Country, and reloads it when the file changes.
+// The whole file is read into memory, so it can be replaced while the service runs.
+type Database struct {
+	path string
+
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+	size    int64
+}
+
+// Open loads the database file at path.
+func Open(path string) (*Database, error) {
+	db := &Database{path: path}
+	if err := db.load(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// load reads the database file and replaces the current database with it.
+func (db *Database) load() error {
+	info, err := os.Stat(db.path)
+	if err != nil {
+		return fmt.Errorf("failed to read GeoIP database: %w", err)
+	}
+	data, err := os.ReadFile(db.path)
+	if err != nil {
+		return fmt.Errorf("failed to read GeoIP database: %w", err)
+	}
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return fmt.Errorf("failed to open GeoIP database %s: %w", db.path, err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.reader, db.modTime, db.size = reader, info.ModTime(), info.Size()
+	return nil
+}
+
+// changed reports whether the database file was modified since it was loaded.
+func (db *Database) changed() (bool, error) {
+	info, err := os.Stat(db.path)
+	if err != nil {
+		return false, err
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return !info.ModTime().Equal(db.modTime) || info.Size() != db.size, nil
+}
+
+// Watch checks the database file for changes every interval until the context is canceled,
+// and reloads it when it was modified. A file that cannot be loaded is logged, and the previous database is kept.
+// Replace the file by renaming a complete copy over it, so that it is never read half written.
+func (db *Database) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := db.changed()
+			if err != nil {
+				log.Printf("Error checking the GeoIP database: %v", err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+			if err := db.load(); err != nil {
+				log.Printf("Error reloading the GeoIP database, keeping the previous one: %v", err)
+				continue
+			}
+			log.Printf("Reloaded the GeoIP database %s", db.path)
+		}
+	}
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country of an IP address, e.g. "DE",
+// or an empty string if the address is not in the database.
+func (db *Database) Country(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	db.mu.RLock()
+	reader := db.reader
+	db.mu.RUnlock()
+
+	var record countryRecord
+	if err := reader.Lookup(ip, &record); err != nil {
+		return ""
+	}
+	country := record.Country.ISOCode
+	if country == "" {
+		country = record.RegisteredCountry.ISOCode
+	}
+	return strings.ToUpper(country)
+}
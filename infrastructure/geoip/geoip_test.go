@@ -0,0 +1,213 @@
+package geoip
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testRecord is a network of a test database with the country of its record.
+// Registered sets the registered country instead of the country.
+type testRecord struct {
+	network    string
+	country    string
+	registered bool
+}
+
+// writeTestDatabase writes a minimal IPv4 database in the MaxMind DB format, with 24-bit records.
+// See https://maxmind.github.io/MaxMind-DB/ for the format.
+func writeTestDatabase(t *testing.T, path string, records []testRecord) {
+	t.Helper()
+
+	// The data section holds one record per network
+	var data []byte
+	offsets := make([]int, len(records))
+	for i, record := range records {
+		field := "country"
+		if record.registered {
+			field = "registered_country"
+		}
+		offsets[i] = len(data)
+		data = append(data, mmdbMap(mmdbString(field), mmdbMap(mmdbString("iso_code"), mmdbString(record.country)))...)
+	}
+
+	// The search tree branches on the bits of the address; a record is a node index, empty or a pointer into the data
+	const empty, dataRef = -1, -2
+	type node [2]struct{ kind, value int }
+	nodes := []node{{{empty, 0}, {empty, 0}}}
+	for i, record := range records {
+		_, network, err := net.ParseCIDR(record.network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ones, _ := network.Mask.Size()
+		ip := network.IP.To4()
+		current := 0
+		for bit := 0; bit < ones; bit++ {
+			side := int(ip[bit/8]>>(7-bit%8)) & 1
+			if bit == ones-1 {
+				nodes[current][side].kind, nodes[current][side].value = dataRef, offsets[i]
+				break
+			}
+			if nodes[current][side].kind == empty {
+				nodes = append(nodes, node{{empty, 0}, {empty, 0}})
+				nodes[current][side].kind, nodes[current][side].value = 0, len(nodes)-1
+			}
+			current = nodes[current][side].value
+		}
+	}
+	var tree []byte
+	for _, n := range nodes {
+		for _, record := range n {
+			value := record.value
+			switch record.kind {
+			case empty:
+				value = len(nodes)
+			case dataRef:
+				value = len(nodes) + 16 + record.value
+			}
+			tree = append(tree, byte(value>>16), byte(value>>8), byte(value))
+		}
+	}
+
+	metadata := mmdbMap(
+		mmdbString("binary_format_major_version"), mmdbUint(5, 2),
+		mmdbString("binary_format_minor_version"), mmdbUint(5, 0),
+		mmdbString("build_epoch"), mmdbUint(9, uint64(time.Now().Unix())),
+		mmdbString("database_type"), mmdbString("Test-Country"),
+		mmdbString("description"), mmdbMap(),
+		mmdbString("ip_version"), mmdbUint(5, 4),
+		mmdbString("languages"), mmdbControl(11, 0),
+		mmdbString("node_count"), mmdbUint(6, uint64(len(nodes))),
+		mmdbString("record_size"), mmdbUint(5, 24),
+	)
+
+	var file []byte
+	file = append(file, tree...)
+	file = append(file, make([]byte, 16)...)
+	file = append(file, data...)
+	file = append(file, "\xAB\xCD\xEFMaxMind.com"...)
+	file = append(file, metadata...)
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// mmdbControl encodes the control byte of a value of the given type and size, with the extended type byte when needed.
+func mmdbControl(kind, size int) []byte {
+	if kind > 7 {
+		return []byte{byte(size), byte(kind - 7)}
+	}
+	return []byte{byte(kind<<5 | size)}
+}
+
+func mmdbString(s string) []byte {
+	return append(mmdbControl(2, len(s)), s...)
+}
+
+// mmdbUint encodes an unsigned integer of the given type (5 for uint16, 6 for uint32, 9 for uint64) in as few bytes as possible.
+func mmdbUint(kind int, value uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], value)
+	bytes := buf[:]
+	for len(bytes) > 0 && bytes[0] == 0 {
+		bytes = bytes[1:]
+	}
+	return append(mmdbControl(kind, len(bytes)), bytes...)
+}
+
+// mmdbMap encodes a map from alternating encoded keys and values.
+func mmdbMap(pairs ...[]byte) []byte {
+	encoded := mmdbControl(7, len(pairs)/2)
+	for _, pair := range pairs {
+		encoded = append(encoded, pair...)
+	}
+	return encoded
+}
+
+// TestDatabase_Country tests that addresses are located in their country, or their registered country.
+func TestDatabase_Country(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	writeTestDatabase(t, path, []testRecord{
+		{network: "81.2.69.0/24", country: "gb"},
+		{network: "89.160.20.0/22", country: "SE"},
+		{network: "175.16.199.0/24", country: "CN", registered: true},
+	})
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		ip   net.IP
+		want string
+	}{
+		{name: "first network", ip: net.ParseIP("81.2.69.160"), want: "GB"},
+		{name: "wider network", ip: net.ParseIP("89.160.23.1"), want: "SE"},
+		{name: "registered country", ip: net.ParseIP("175.16.199.10"), want: "CN"},
+		{name: "unknown address", ip: net.ParseIP("192.0.2.1"), want: ""},
+		{name: "IPv6 address", ip: net.ParseIP("2001:db8::1"), want: ""},
+		{name: "no address", ip: nil, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, db.Country(tt.ip))
+		})
+	}
+}
+
+// TestDatabase_Watch tests that a replaced database file is reloaded, and that a broken one is ignored.
+func TestDatabase_Watch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "country.mmdb")
+	writeTestDatabase(t, path, []testRecord{{network: "81.2.69.0/24", country: "GB"}})
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go db.Watch(ctx, 10*time.Millisecond)
+
+	// Replace the file the safe way, with a rename, and a modification time that is certain to differ
+	replace := func(write func(path string)) {
+		next := filepath.Join(dir, "next.mmdb")
+		write(next)
+		later := time.Now().Add(time.Hour)
+		if err := os.Chtimes(next, later, later); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(next, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ip := net.ParseIP("81.2.69.160")
+
+	replace(func(path string) { writeTestDatabase(t, path, []testRecord{{network: "81.2.69.0/24", country: "DE"}}) })
+	assert.Eventually(t, func() bool { return db.Country(ip) == "DE" }, time.Second, 10*time.Millisecond)
+
+	replace(func(path string) { assert.NoError(t, os.WriteFile(path, []byte("not a database"), 0o644)) })
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "DE", db.Country(ip), "A broken file should keep the previous database")
+}
+
+// TestOpen_Invalid tests that a missing or broken database file is refused.
+func TestOpen_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	_, err := Open(filepath.Join(dir, "missing.mmdb"))
+	assert.Error(t, err)
+
+	broken := filepath.Join(dir, "broken.mmdb")
+	assert.NoError(t, os.WriteFile(broken, []byte("not a database"), 0o644))
+	_, err = Open(broken)
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader is the request header that carries the API key.
+// The key may also be sent as a bearer token in the Authorization header.
+const APIKeyHeader = "X-API-Key"
+
+// ownerContextKey is the gin context key holding the owner of an authenticated request.
+const ownerContextKey = "owner"
+
+// workspaceContextKey is the gin context key holding the workspace ID of an authenticated request.
+const workspaceContextKey = "workspace"
+
+// APIKeyAuth returns a middleware that rejects requests without one of the given API keys.
+// Keys are compared in constant time, so response times do not reveal how much of a key was right.
+// The owner of an accepted request is derived from its key, see requestOwner.
+func APIKeyAuth(keys []string) gin.HandlerFunc {
+	workspaces := make(map[string]string, len(keys))
+	for _, key := range keys {
+		workspaces[key] = ""
+	}
+	return WorkspaceAPIKeyAuth(workspaces)
+}
+
+// WorkspaceAPIKeyAuth is like APIKeyAuth with the workspace ID of every key, which is empty for the default workspace.
+// The workspace of an accepted request is that of its key, see requestWorkspace.
+func WorkspaceAPIKeyAuth(keys map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := requestAPIKey(c)
+		for key, workspace := range keys {
+			if provided != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+				c.Set(ownerContextKey, ownerID(key))
+				c.Set(workspaceContextKey, workspace)
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized - a valid API key is required in the " + APIKeyHeader + " header"})
+	}
+}
+
+// requestAPIKey returns the API key sent with the request, or an empty string if there is none.
+func requestAPIKey(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// requestOwner returns the owner of the request: an ID derived from its API key, or an empty string when the API is open.
+func requestOwner(c *gin.Context) string {
+	return c.GetString(ownerContextKey)
+}
+
+// requestWorkspace returns the workspace ID of the request's API key, or an empty string for the default workspace.
+func requestWorkspace(c *gin.Context) string {
+	return c.GetString(workspaceContextKey)
+}
+
+// ownerID derives a stable owner ID from an API key, without storing the key itself.
+func ownerID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
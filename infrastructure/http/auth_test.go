@@ -0,0 +1,60 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAPIKeyAuth tests the middleware that protects the management API.
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		header         string
+		value          string
+		expectedStatus int
+	}{
+		{name: "missing key", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong key", header: APIKeyHeader, value: "wrong", expectedStatus: http.StatusUnauthorized},
+		{name: "valid key", header: APIKeyHeader, value: "key2", expectedStatus: http.StatusOK},
+		{name: "valid bearer token", header: "Authorization", value: "Bearer key1", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newTestContext(http.MethodGet, "/url/display", nil)
+			if tt.header != "" {
+				c.Request.Header.Set(tt.header, tt.value)
+			}
+
+			APIKeyAuth([]string{"key1", "key2"})(c)
+			if !c.IsAborted() {
+				c.Status(http.StatusOK)
+				c.Writer.WriteHeaderNow()
+			}
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+// TestAPIKeyAuth_Owner tests that accepted requests are owned by an ID derived from their key, which never reveals the key.
+func TestAPIKeyAuth_Owner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	owner := func(key string) string {
+		c, _ := newTestContext(http.MethodGet, "/url/display", nil)
+		c.Request.Header.Set(APIKeyHeader, key)
+		APIKeyAuth([]string{"key1", "key2"})(c)
+		return requestOwner(c)
+	}
+
+	assert.Equal(t, owner("key1"), owner("key1"))
+	assert.NotEqual(t, owner("key1"), owner("key2"))
+	assert.NotContains(t, owner("key1"), "key1")
+	assert.Empty(t, owner("wrong"))
+}
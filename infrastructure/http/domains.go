@@ -0,0 +1,129 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// HandleListDomains lists the custom domains of the workspace.
+// @Summary Lists the custom domains of the workspace, verified or claimed, sorted by name.
+// @Tags DOMAIN
+// @Produce json
+// @Success 200 {array} urlModel.CustomDomain "Custom domains"
+// @Security ApiKeyAuth
+// @Router /domains [get]
+func (h *Handler) HandleListDomains(c *gin.Context) {
+	domains, err := h.service.ListDomains(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to fetch domains: %v", err)})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, domains)
+}
+
+// HandleAddDomain claims a custom domain for the workspace.
+// @Summary Claims a custom domain, to be verified with a DNS TXT record.
+// @Description NOTE 1: Create a TXT record at "verification_host" with the "verification_value" of the response, then call /domains/{domain}/verify before "expires_at". Other workspaces can claim the domain too; the one that verifies it owns it.
+// @Description NOTE 2: Once verified, redirects sent to the domain go to the workspace, and its shortened URLs use the domain. Point the domain at the service, e.g. with a CNAME record.
+// @Tags DOMAIN
+// @Accept json
+// @Param request body urlModel.AddDomainRequest true "Domain, e.g. go.team.example"
+// @Produce json
+// @Success 201 {object} urlModel.CustomDomain "Claimed domain"
+// @Failure 400 {object} map[string]string "Invalid or reserved domain, or the workspace already has it"
+// @Security ApiKeyAuth
+// @Router /domains [post]
+func (h *Handler) HandleAddDomain(c *gin.Context) {
+	var req urlModel.AddDomainRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Bad request - invalid JSON body"})
+		return
+	}
+
+	customDomain, err := h.service.AddDomain(c, req.Domain)
+	if errors.Is(err, urlModel.ErrInvalidDomain) || errors.Is(err, urlModel.ErrDomainTaken) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Invalid request - %v", err)})
+		return
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to add domain: %v", err)})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, customDomain)
+}
+
+// HandleGetDomain displays a custom domain of the workspace.
+// @Summary Displays a custom domain, with its verification record.
+// @Tags DOMAIN
+// @Param domain path string true "Domain"
+// @Produce json
+// @Success 200 {object} urlModel.CustomDomain "Custom domain"
+// @Failure 404 {object} map[string]string "The workspace has no such domain"
+// @Security ApiKeyAuth
+// @Router /domains/{domain} [get]
+func (h *Handler) HandleGetDomain(c *gin.Context) {
+	customDomain, err := h.service.GetDomain(c, c.Param("domain"))
+	if domainError(c, err, "Failed to fetch domain") {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, customDomain)
+}
+
+// HandleDeleteDomain removes a custom domain of the workspace.
+// @Summary Deletes a custom domain.
+// @Description NOTE: Redirects sent to the domain stop working at once, including those of the shortened URLs already handed out.
+// @Tags DOMAIN
+// @Param domain path string true "Domain"
+// @Success 204 "Deleted"
+// @Failure 404 {object} map[string]string "The workspace has no such domain"
+// @Security ApiKeyAuth
+// @Router /domains/{domain} [delete]
+func (h *Handler) HandleDeleteDomain(c *gin.Context) {
+	err := h.service.DeleteDomain(c, c.Param("domain"))
+	if domainError(c, err, "Failed to delete domain") {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandleVerifyDomain checks the DNS TXT record of a custom domain of the workspace.
+// @Summary Verifies a custom domain with its DNS TXT record.
+// @Description NOTE: DNS changes can take a while to be visible; the verification can be retried until it succeeds.
+// @Tags DOMAIN
+// @Param domain path string true "Domain"
+// @Produce json
+// @Success 200 {object} urlModel.CustomDomain "Verified domain"
+// @Failure 400 {object} map[string]string "The verification record was not found"
+// @Failure 404 {object} map[string]string "The workspace has no such domain"
+// @Security ApiKeyAuth
+// @Router /domains/{domain}/verify [post]
+func (h *Handler) HandleVerifyDomain(c *gin.Context) {
+	customDomain, err := h.service.VerifyDomain(c, c.Param("domain"))
+	if errors.Is(err, urlModel.ErrDomainNotVerified) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Verification failed - %v", err)})
+		return
+	} else if domainError(c, err, "Failed to verify domain") {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, customDomain)
+}
+
+// domainError responds to the error of an operation on a custom domain, and reports whether there was one.
+func domainError(c *gin.Context, err error, message string) bool {
+	if errors.Is(err, urlModel.ErrDomainNotFound) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "The workspace has no such domain"})
+		return true
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("%s: %v", message, err)})
+		return true
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/application"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// mockDomainRepository keeps custom domains in memory: the verified ones by name, and the claims of each workspace.
+type mockDomainRepository struct {
+	verified map[string]urlModel.CustomDomain
+	claims   map[string]map[string]urlModel.CustomDomain
+}
+
+// newMockDomainRepository creates a mockDomainRepository holding the given domains, verified or claimed.
+func newMockDomainRepository(domains ...urlModel.CustomDomain) *mockDomainRepository {
+	m := &mockDomainRepository{verified: map[string]urlModel.CustomDomain{}, claims: map[string]map[string]urlModel.CustomDomain{}}
+	for _, customDomain := range domains {
+		_ = m.SaveDomain(context.Background(), customDomain)
+	}
+	return m
+}
+
+func (m *mockDomainRepository) SaveDomain(ctx context.Context, customDomain urlModel.CustomDomain) error {
+	if customDomain.Verified {
+		m.verified[customDomain.Domain] = customDomain
+		delete(m.claims[customDomain.Workspace], customDomain.Domain)
+		return nil
+	}
+	if m.claims[customDomain.Workspace] == nil {
+		m.claims[customDomain.Workspace] = map[string]urlModel.CustomDomain{}
+	}
+	m.claims[customDomain.Workspace][customDomain.Domain] = customDomain
+	return nil
+}
+
+func (m *mockDomainRepository) FindDomain(ctx context.Context, name string) (*urlModel.CustomDomain, error) {
+	return findMockDomain(m.verified, name)
+}
+
+func (m *mockDomainRepository) ListDomains(ctx context.Context) ([]urlModel.CustomDomain, error) {
+	return listMockDomains(m.verified), nil
+}
+
+func (m *mockDomainRepository) DeleteDomain(ctx context.Context, name string) error {
+	return deleteMockDomain(m.verified, name)
+}
+
+func (m *mockDomainRepository) FindClaim(ctx context.Context, workspace, name string) (*urlModel.CustomDomain, error) {
+	return findMockDomain(m.claims[workspace], name)
+}
+
+func (m *mockDomainRepository) ListClaims(ctx context.Context, workspace string) ([]urlModel.CustomDomain, error) {
+	return listMockDomains(m.claims[workspace]), nil
+}
+
+func (m *mockDomainRepository) DeleteClaim(ctx context.Context, workspace, name string) error {
+	return deleteMockDomain(m.claims[workspace], name)
+}
+
+func findMockDomain(domains map[string]urlModel.CustomDomain, name string) (*urlModel.CustomDomain, error) {
+	customDomain, ok := domains[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", urlModel.ErrDomainNotFound, name)
+	}
+	return &customDomain, nil
+}
+
+func listMockDomains(domains map[string]urlModel.CustomDomain) []urlModel.CustomDomain {
+	var list []urlModel.CustomDomain
+	for _, customDomain := range domains {
+		list = append(list, customDomain)
+	}
+	return list
+}
+
+func deleteMockDomain(domains map[string]urlModel.CustomDomain, name string) error {
+	if _, ok := domains[name]; !ok {
+		return fmt.Errorf("%w: %s", urlModel.ErrDomainNotFound, name)
+	}
+	delete(domains, name)
+	return nil
+}
+
+// stubResolver answers TXT lookups from memory, and fails for unknown hosts like a DNS server without the record.
+type stubResolver map[string][]string
+
+func (r stubResolver) LookupTXT(ctx context.Context, host string) ([]string, error) {
+	records, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return records, nil
+}
+
+// TestHandleDomains tests claiming and verifying the custom domains of a workspace,
+// and that its shortened URLs use the domain once verified.
+func TestHandleDomains(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	expired := time.Now().Add(-time.Hour)
+	domains := newMockDomainRepository(
+		urlModel.CustomDomain{Domain: "go.other.com", Workspace: "other", Verified: true},
+		urlModel.CustomDomain{Domain: "old.acme.com", Workspace: "acme", ExpiresAt: &expired},
+	)
+	resolver := stubResolver{}
+	repo := &mockURLRepository{IsUniqueFunc: func(ctx context.Context, code string) bool { return true }}
+	service := application.NewURLService(repo,
+		application.WithWorkspace(urlModel.Workspace{ID: "acme"}),
+		application.WithDomains(domains, resolver),
+		application.WithReservedDomains("sho.rt:443", "go.beta.com"),
+	)
+	h := NewHandler(service, WithPublicBaseURL("https://sho.rt"))
+
+	addLink := func() urlModel.AddSuccessResponse {
+		c, w := newTestContext(http.MethodPost, "/url/add", []byte(`{"original_url":"https://example.com"}`))
+		h.HandleAddLink(c)
+		var resp urlModel.AddSuccessResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	get := func(name string) int {
+		c, w := newTestContext(http.MethodGet, "/domains/"+name, nil)
+		c.Params = gin.Params{{Key: "domain", Value: name}}
+		h.HandleGetDomain(c)
+		return w.Code
+	}
+
+	// Registration
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "invalid JSON", body: `{`, expectedStatus: http.StatusBadRequest},
+		{name: "not a host name", body: `{"domain":"localhost:9000"}`, expectedStatus: http.StatusBadRequest},
+		{name: "shared host", body: `{"domain":"SHO.RT"}`, expectedStatus: http.StatusBadRequest},
+		{name: "domain of a configured workspace", body: `{"domain":"go.beta.com"}`, expectedStatus: http.StatusBadRequest},
+		{name: "new domain", body: `{"domain":" Go.Acme.com. "}`, expectedStatus: http.StatusCreated},
+		{name: "same domain again", body: `{"domain":"go.acme.com"}`, expectedStatus: http.StatusBadRequest},
+		{name: "domain verified by another workspace", body: `{"domain":"go.other.com"}`, expectedStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newTestContext(http.MethodPost, "/domains", []byte(tt.body))
+			h.HandleAddDomain(c)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+	registered := domains.claims["acme"]["go.acme.com"]
+	assert.Equal(t, "acme", registered.Workspace)
+	assert.Equal(t, "_shortener-challenge.go.acme.com", registered.VerificationHost)
+	assert.Equal(t, "shortener-verification="+registered.Token, registered.VerificationValue)
+	assert.False(t, registered.Verified)
+	if assert.NotNil(t, registered.ExpiresAt) {
+		assert.WithinDuration(t, time.Now().Add(urlModel.DomainClaimExpiry), *registered.ExpiresAt, time.Minute)
+	}
+	// Claims do not take the domain from its owner
+	assert.Equal(t, "other", domains.verified["go.other.com"].Workspace)
+
+	// Only the domains claimed by the workspace are visible, until their claim expires
+	c, w := newTestContext(http.MethodGet, "/domains", nil)
+	h.HandleListDomains(c)
+	var listed []urlModel.CustomDomain
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	if assert.Len(t, listed, 2) {
+		assert.Equal(t, "go.acme.com", listed[0].Domain)
+		assert.Equal(t, "go.other.com", listed[1].Domain)
+		assert.Equal(t, "acme", listed[1].Workspace)
+	}
+	assert.Equal(t, http.StatusNotFound, get("old.acme.com"))
+
+	// Unverified domains are not used yet
+	assert.Equal(t, "https://sho.rt/api/v1/redirect/", addLink().ShortenedURL[:len("https://sho.rt/api/v1/redirect/")])
+
+	// Verification
+	verify := func(name string) int {
+		c, w := newTestContext(http.MethodPost, "/domains/"+name+"/verify", nil)
+		c.Params = gin.Params{{Key: "domain", Value: name}}
+		h.HandleVerifyDomain(c)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusBadRequest, verify("go.acme.com"))
+	resolver["_shortener-challenge.go.acme.com"] = []string{"v=spf1 -all", "shortener-verification=wrong"}
+	assert.Equal(t, http.StatusBadRequest, verify("go.acme.com"))
+	resolver["_shortener-challenge.go.acme.com"] = append(resolver["_shortener-challenge.go.acme.com"], registered.VerificationValue)
+	assert.Equal(t, http.StatusOK, verify("go.acme.com"))
+	assert.True(t, domains.verified["go.acme.com"].Verified)
+	assert.NotNil(t, domains.verified["go.acme.com"].VerifiedAt)
+	assert.Nil(t, domains.verified["go.acme.com"].ExpiresAt)
+	assert.NotContains(t, domains.claims["acme"], "go.acme.com")
+
+	// Verified domains are used by the shortened URLs and their QR codes, with the scheme of the public base URL
+	added := addLink()
+	assert.Equal(t, "https://go.acme.com/api/v1/redirect/", added.ShortenedURL[:len("https://go.acme.com/api/v1/redirect/")])
+	assert.Equal(t, "https://go.acme.com/api/v1/qr/", added.QRCodeURL[:len("https://go.acme.com/api/v1/qr/")])
+
+	// The workspace that verifies a domain takes it over
+	resolver["_shortener-challenge.go.other.com"] = []string{domains.claims["acme"]["go.other.com"].VerificationValue}
+	assert.Equal(t, http.StatusOK, verify("go.other.com"))
+	assert.Equal(t, "acme", domains.verified["go.other.com"].Workspace)
+
+	// Deletion
+	c, w = newTestContext(http.MethodDelete, "/domains/go.acme.com", nil)
+	c.Params = gin.Params{{Key: "domain", Value: "go.acme.com"}}
+	h.HandleDeleteDomain(c)
+	c.Writer.WriteHeaderNow()
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.NotContains(t, domains.verified, "go.acme.com")
+	assert.Contains(t, domains.verified, "go.other.com")
+	assert.Equal(t, http.StatusNotFound, get("go.acme.com"))
+}
+
+// TestWorkspaces_DomainRegistry tests that redirects sent to a verified custom domain resolve the short code in its workspace.
+func TestWorkspaces_DomainRegistry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newHandler := func(destination string) *Handler {
+		repo := &mockURLRepository{
+			FindByShortCodeFunc: func(ctx context.Context, shortCode string) (*urlModel.URL, error) {
+				return &urlModel.URL{ShortCode: shortCode, OriginalURL: destination}, nil
+			},
+		}
+		return NewHandler(application.NewURLService(repo))
+	}
+	workspaces := NewWorkspaces(newHandler("https://default.example.com"), nil)
+	workspaces.Add("acme", "", newHandler("https://acme.example.com"), nil)
+	workspaces.Add("beta", "go.beta.com", newHandler("https://beta.example.com"), nil)
+	workspaces.UseDomainRegistry(newMockDomainRepository(
+		urlModel.CustomDomain{Domain: "go.acme.com", Workspace: "acme", Verified: true},
+		urlModel.CustomDomain{Domain: "pending.acme.com", Workspace: "acme"},
+		urlModel.CustomDomain{Domain: "sho.rt", Workspace: "acme", Verified: true},
+		urlModel.CustomDomain{Domain: "go.removed.com", Workspace: "removed", Verified: true},
+	), "sho.rt:443")
+
+	router := gin.New()
+	router.GET("/redirect/:shortcode", workspaces.Redirect((*Handler).HandleRedirectToOriginalLink))
+
+	tests := []struct {
+		name           string
+		host           string
+		expectedStatus int
+		expected       string
+	}{
+		{name: "verified custom domain", host: "go.acme.com", expectedStatus: http.StatusTemporaryRedirect, expected: "https://acme.example.com"},
+		{name: "domain of a workspace that is not loaded", host: "go.removed.com", expectedStatus: http.StatusNotFound},
+		{name: "unverified custom domain", host: "pending.acme.com", expectedStatus: http.StatusTemporaryRedirect, expected: "https://default.example.com"},
+		{name: "domain of the configuration", host: "go.beta.com", expectedStatus: http.StatusTemporaryRedirect, expected: "https://beta.example.com"},
+		{name: "shared domain", host: "sho.rt", expectedStatus: http.StatusTemporaryRedirect, expected: "https://default.example.com"},
+		{name: "unknown domain", host: "example.org", expectedStatus: http.StatusTemporaryRedirect, expected: "https://default.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/redirect/abc123", nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expected, w.Header().Get("Location"))
+		})
+	}
+}
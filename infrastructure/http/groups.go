@@ -0,0 +1,199 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// HandleListGroups lists the link groups.
+// @Summary Lists the link groups, sorted by ID.
+// @Tags GROUP
+// @Produce json
+// @Success 200 {array} urlModel.Group "Groups"
+// @Security ApiKeyAuth
+// @Router /groups [get]
+func (h *Handler) HandleListGroups(c *gin.Context) {
+	groups, err := h.service.ListGroups(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to fetch groups: %v", err)})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, groups)
+}
+
+// HandleGetGroup displays a single link group.
+// @Summary Displays a link group.
+// @Tags GROUP
+// @Param id path string true "Group ID"
+// @Produce json
+// @Success 200 {object} urlModel.Group "Group"
+// @Failure 404 {object} map[string]string "No group exists for the given ID"
+// @Security ApiKeyAuth
+// @Router /groups/{id} [get]
+func (h *Handler) HandleGetGroup(c *gin.Context) {
+	group, err := h.service.GetGroup(c, c.Param("id"))
+	if groupError(c, err, "Failed to fetch group") {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, group)
+}
+
+// HandleSaveGroup creates a link group, or updates its name and description.
+// @Summary Creates or updates a link group.
+// @Description NOTE 1: The ID is lowercased and may use up to 64 letters, digits, "_", "-" and ".". Links are added to the group by setting "group" to its ID, when they are added or updated.
+// @Description NOTE 2: The name is optional and defaults to the ID.
+// @Tags GROUP
+// @Accept json
+// @Param id path string true "Group ID"
+// @Param request body urlModel.SaveGroupRequest true "Name and Description (both optional)"
+// @Produce json
+// @Success 200 {object} urlModel.Group "Saved group"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Security ApiKeyAuth
+// @Router /groups/{id} [put]
+func (h *Handler) HandleSaveGroup(c *gin.Context) {
+	var req urlModel.SaveGroupRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Bad request - invalid JSON body"})
+		return
+	}
+
+	group, err := h.service.SaveGroup(c, c.Param("id"), req)
+	if errors.Is(err, urlModel.ErrInvalidGroup) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Invalid request - %v", err)})
+		return
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to save group: %v", err)})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, group)
+}
+
+// HandleDeleteGroup removes a link group.
+// @Summary Deletes a link group.
+// @Description NOTE: The links of the group are kept, without a group.
+// @Tags GROUP
+// @Param id path string true "Group ID"
+// @Success 204 "Deleted"
+// @Failure 404 {object} map[string]string "No group exists for the given ID"
+// @Security ApiKeyAuth
+// @Router /groups/{id} [delete]
+func (h *Handler) HandleDeleteGroup(c *gin.Context) {
+	err := h.service.DeleteGroup(c, c.Param("id"))
+	if groupError(c, err, "Failed to delete group") {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandleGroupLinks lists the links of a group.
+// @Summary Lists the live links of a group, soonest to expire first.
+// @Tags GROUP
+// @Param id path string true "Group ID"
+// @Produce json
+// @Success 200 {array} urlModel.URLMapping "URL Mappings"
+// @Failure 404 {object} map[string]string "No group exists for the given ID"
+// @Security ApiKeyAuth
+// @Router /groups/{id}/links [get]
+func (h *Handler) HandleGroupLinks(c *gin.Context) {
+	links, err := h.service.GroupLinks(c, c.Param("id"))
+	if groupError(c, err, "Failed to fetch the links of the group") {
+		return
+	}
+
+	urlMappings := make([]urlModel.URLMapping, 0, len(links))
+	for _, link := range links {
+		urlMappings = append(urlMappings, urlModel.NewURLMapping(link))
+	}
+	c.IndentedJSON(http.StatusOK, urlMappings)
+}
+
+// HandleGroupStats displays the clicks on the links of a group.
+// @Summary Displays the clicks on the live links of a group, in total, per country and per link.
+// @Tags GROUP
+// @Param id path string true "Group ID"
+// @Produce json
+// @Success 200 {object} urlModel.GroupStats "Group statistics"
+// @Failure 404 {object} map[string]string "No group exists for the given ID"
+// @Security ApiKeyAuth
+// @Router /groups/{id}/stats [get]
+func (h *Handler) HandleGroupStats(c *gin.Context) {
+	stats, err := h.service.GetGroupStats(c, c.Param("id"))
+	if groupError(c, err, "Failed to fetch the statistics of the group") {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, stats)
+}
+
+// HandleExpireGroup makes every link of a group expire.
+// @Summary Expires every link of a group at once.
+// @Description NOTE: The links stop working right away, and are reported by "link.expired" webhook events like links that reached their expiry. The group itself is kept.
+// @Tags GROUP
+// @Param id path string true "Group ID"
+// @Produce json
+// @Success 200 {object} urlModel.GroupOperationResponse "Number of links expired"
+// @Failure 404 {object} map[string]string "No group exists for the given ID"
+// @Security ApiKeyAuth
+// @Router /groups/{id}/expire [post]
+func (h *Handler) HandleExpireGroup(c *gin.Context) {
+	expired, err := h.service.ExpireGroup(c, c.Param("id"))
+	if groupError(c, err, "Failed to expire the links of the group") {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, urlModel.GroupOperationResponse{Affected: expired})
+}
+
+// HandleRenewGroup moves the expiry of every link of a group.
+// @Summary Renews every link of a group until the same time.
+// @Tags GROUP
+// @Accept json
+// @Param id path string true "Group ID"
+// @Param request body urlModel.RenewGroupRequest true "New expiry time, e.g. 2024-06-01T00:00:00Z"
+// @Produce json
+// @Success 200 {object} urlModel.GroupOperationResponse "Number of links renewed"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 404 {object} map[string]string "No group exists for the given ID"
+// @Security ApiKeyAuth
+// @Router /groups/{id}/renew [post]
+func (h *Handler) HandleRenewGroup(c *gin.Context) {
+	var req urlModel.RenewGroupRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Bad request - invalid JSON body"})
+		return
+	}
+	if !req.Expiry.After(time.Now()) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Invalid request - expiry must be in the future"})
+		return
+	}
+
+	renewed, err := h.service.RenewGroup(c, c.Param("id"), req.Expiry)
+	if groupError(c, err, "Failed to renew the links of the group") {
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, urlModel.GroupOperationResponse{Affected: renewed})
+}
+
+// groupError writes the response for an error of a group operation: 404 for an unknown group, and 500 otherwise.
+// It reports whether there was an error.
+func groupError(c *gin.Context, err error, message string) bool {
+	if errors.Is(err, urlModel.ErrGroupNotFound) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "No group exists for the given ID"})
+		return true
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("%s: %v", message, err)})
+		return true
+	}
+	return false
+}
@@ -0,0 +1,253 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/application"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// mockGroupRepository keeps link groups in memory.
+type mockGroupRepository map[string]urlModel.Group
+
+func (m mockGroupRepository) SaveGroup(ctx context.Context, group urlModel.Group) error {
+	m[group.ID] = group
+	return nil
+}
+
+func (m mockGroupRepository) FindGroup(ctx context.Context, id string) (*urlModel.Group, error) {
+	group, ok := m[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", urlModel.ErrGroupNotFound, id)
+	}
+	return &group, nil
+}
+
+func (m mockGroupRepository) ListGroups(ctx context.Context) ([]urlModel.Group, error) {
+	var groups []urlModel.Group
+	for _, group := range m {
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (m mockGroupRepository) DeleteGroup(ctx context.Context, id string) error {
+	if _, ok := m[id]; !ok {
+		return fmt.Errorf("%w: %s", urlModel.ErrGroupNotFound, id)
+	}
+	delete(m, id)
+	return nil
+}
+
+// newGroupLinksRepository returns a mock repository keeping the given links in memory, and indexing them by group.
+func newGroupLinksRepository(links map[string]urlModel.URL) *mockURLRepository {
+	return &mockURLRepository{
+		StoreFunc: func(ctx context.Context, url urlModel.URL) error {
+			links[url.ShortCode] = url
+			return nil
+		},
+		StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+			if _, ok := links[url.ShortCode]; ok {
+				return urlModel.ErrShortCodeTaken
+			}
+			links[url.ShortCode] = url
+			return nil
+		},
+		FindByShortCodeFunc: func(ctx context.Context, shortCode string) (*urlModel.URL, error) {
+			url, ok := links[shortCode]
+			if !ok {
+				return nil, urlModel.ErrURLNotFound
+			}
+			return &url, nil
+		},
+		FindByGroupFunc: func(ctx context.Context, group string) ([]urlModel.URL, error) {
+			var urls []urlModel.URL
+			for _, url := range links {
+				if url.Group == group {
+					urls = append(urls, url)
+				}
+			}
+			return urls, nil
+		},
+		ExpireFunc: func(ctx context.Context, shortCode string) error {
+			delete(links, shortCode)
+			return nil
+		},
+		GetClicksFunc: func(ctx context.Context, shortCode string) (int64, error) {
+			return int64(len(shortCode)), nil
+		},
+		GetClickCountriesFunc: func(ctx context.Context, shortCode string) (map[string]int64, error) {
+			return map[string]int64{"DE": 1, shortCode: 2}, nil
+		},
+	}
+}
+
+// TestHandleGroups tests saving, displaying, listing and deleting link groups.
+func TestHandleGroups(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	links := map[string]urlModel.URL{
+		"abc": {ShortCode: "abc", OriginalURL: "https://example.com/a", Expiry: time.Now().Add(time.Hour), Group: "spring"},
+	}
+	groups := mockGroupRepository{}
+	h := NewHandler(application.NewURLService(newGroupLinksRepository(links), application.WithGroups(groups)))
+
+	save := func(id, body string) (int, urlModel.Group) {
+		c, w := newTestContext(http.MethodPut, "/groups/"+id, []byte(body))
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		h.HandleSaveGroup(c)
+		var group urlModel.Group
+		_ = json.Unmarshal(w.Body.Bytes(), &group)
+		return w.Code, group
+	}
+
+	code, group := save("Spring", `{"name":" Spring sale ","description":"Newsletter links"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, urlModel.Group{ID: "spring", Name: "Spring sale", Description: "Newsletter links", CreatedAt: group.CreatedAt, UpdatedAt: group.UpdatedAt}, group)
+	code, updated := save("spring", `{"name":"Spring"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, group.CreatedAt.Equal(updated.CreatedAt), "Updating a group should keep its creation time")
+	code, group = save("autumn", `{}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "autumn", group.Name, "The name should default to the ID")
+	code, _ = save("spring sale", `{}`)
+	assert.Equal(t, http.StatusBadRequest, code, "An ID with a space should be refused")
+
+	c, w := newTestContext(http.MethodGet, "/groups", nil)
+	h.HandleListGroups(c)
+	var listed []urlModel.Group
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	if assert.Len(t, listed, 2) {
+		assert.Equal(t, "autumn", listed[0].ID)
+		assert.Equal(t, "spring", listed[1].ID)
+	}
+
+	c, w = newTestContext(http.MethodGet, "/groups/winter", nil)
+	c.Params = gin.Params{{Key: "id", Value: "winter"}}
+	h.HandleGetGroup(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Deleting a group keeps its links, out of any group
+	for _, expected := range []int{http.StatusNoContent, http.StatusNotFound} {
+		c, w = newTestContext(http.MethodDelete, "/groups/spring", nil)
+		c.Params = gin.Params{{Key: "id", Value: "spring"}}
+		h.HandleDeleteGroup(c)
+		c.Writer.WriteHeaderNow()
+		assert.Equal(t, expected, w.Code)
+	}
+	if assert.Contains(t, links, "abc") {
+		assert.Empty(t, links["abc"].Group)
+	}
+}
+
+// TestHandleGroupOperations tests listing the links of a group, their statistics, and expiring and renewing them together.
+func TestHandleGroupOperations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	soon, later := time.Now().Add(time.Hour), time.Now().Add(2*time.Hour)
+	links := map[string]urlModel.URL{
+		"abc":   {ShortCode: "abc", OriginalURL: "https://example.com/a", Expiry: later, Group: "spring"},
+		"de":    {ShortCode: "de", OriginalURL: "https://example.com/d", Expiry: soon, Group: "spring"},
+		"other": {ShortCode: "other", OriginalURL: "https://example.com/o", Expiry: soon},
+	}
+	groups := mockGroupRepository{"spring": {ID: "spring", Name: "Spring"}}
+	h := NewHandler(application.NewURLService(newGroupLinksRepository(links), application.WithGroups(groups)))
+	request := func(handler gin.HandlerFunc, method, id, body string) (int, []byte) {
+		c, w := newTestContext(method, "/groups/"+id, []byte(body))
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		handler(c)
+		return w.Code, w.Body.Bytes()
+	}
+
+	code, body := request(h.HandleGroupLinks, http.MethodGet, "spring", "")
+	assert.Equal(t, http.StatusOK, code)
+	var mappings []urlModel.URLMapping
+	assert.NoError(t, json.Unmarshal(body, &mappings))
+	if assert.Len(t, mappings, 2) {
+		assert.Equal(t, "de", mappings[0].ShortCode, "The links should be sorted by expiry")
+		assert.Equal(t, "abc", mappings[1].ShortCode)
+	}
+	code, _ = request(h.HandleGroupLinks, http.MethodGet, "winter", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = request(h.HandleGroupStats, http.MethodGet, "spring", "")
+	assert.Equal(t, http.StatusOK, code)
+	var stats urlModel.GroupStats
+	assert.NoError(t, json.Unmarshal(body, &stats))
+	assert.Equal(t, "spring", stats.Group.ID)
+	assert.Equal(t, 2, stats.Links)
+	assert.Equal(t, int64(5), stats.Clicks)
+	assert.Equal(t, map[string]int64{"DE": 2, "abc": 2, "de": 2}, stats.Countries)
+	assert.Len(t, stats.PerLink, 2)
+
+	renewal := later.Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	code, _ = request(h.HandleRenewGroup, http.MethodPost, "spring", `{"expiry":"2001-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, code, "An expiry in the past should be refused")
+	code, body = request(h.HandleRenewGroup, http.MethodPost, "spring", `{"expiry":"`+renewal+`"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"affected": 2}`, string(body))
+	assert.Equal(t, renewal, links["de"].Expiry.UTC().Format(time.RFC3339))
+	assert.True(t, links["other"].Expiry.Equal(soon), "Links out of the group should not be renewed")
+
+	code, body = request(h.HandleExpireGroup, http.MethodPost, "spring", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"affected": 2}`, string(body))
+	assert.NotContains(t, links, "abc")
+	assert.NotContains(t, links, "de")
+	assert.Contains(t, links, "other")
+}
+
+// TestHandleAddLink_Group tests that links are added to existing groups only.
+func TestHandleAddLink_Group(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	groups := mockGroupRepository{"spring": {ID: "spring", Name: "Spring"}}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedGroup  string
+	}{
+		{
+			name:           "existing group",
+			body:           `{"original_url":"https://example.com","group":" Spring "}`,
+			expectedStatus: http.StatusOK,
+			expectedGroup:  "spring",
+		},
+		{
+			name:           "no group",
+			body:           `{"original_url":"https://example.com"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown group",
+			body:           `{"original_url":"https://example.com","group":"autumn"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored urlModel.URL
+			repo := &mockURLRepository{
+				IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+				StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+					stored = url
+					return nil
+				},
+			}
+			h := NewHandler(application.NewURLService(repo, application.WithGroups(groups)))
+
+			c, w := newTestContext(http.MethodPost, "/url/add", []byte(tt.body))
+			h.HandleAddLink(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedGroup, stored.Group)
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package http
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,24 +14,91 @@ import (
 )
 
 type Handler struct {
-	service *application.URLService
+	service       *application.URLService
+	publicBaseURL string
+	bulkLimit     int
+	unlockSecret  []byte
+	unlockTTL     time.Duration
+	pendingPage   bool
+	countries     CountryLocator
+	redirectType  string
+}
+
+// HandlerOption configures optional behaviour of the Handler.
+type HandlerOption func(*Handler)
+
+// WithPublicBaseURL sets the scheme and host used to build the shortened URLs, e.g. https://sho.rt
+func WithPublicBaseURL(publicBaseURL string) HandlerOption {
+	return func(h *Handler) {
+		h.publicBaseURL = strings.TrimSuffix(publicBaseURL, "/")
+	}
+}
+
+// WithBulkLimit sets the maximum number of links accepted by a single bulk creation request.
+func WithBulkLimit(limit int) HandlerOption {
+	return func(h *Handler) {
+		h.bulkLimit = limit
+	}
 }
 
 // NewHandler creates a new instance of Handler
-func NewHandler(service *application.URLService) *Handler {
-	return &Handler{service: service}
+func NewHandler(service *application.URLService, opts ...HandlerOption) *Handler {
+	h := &Handler{
+		service:       service,
+		publicBaseURL: "http://localhost:9000",
+		bulkLimit:     500,
+		unlockTTL:     defaultUnlockTTL,
+		pendingPage:   true,
+		redirectType:  urlModel.DefaultRedirectType,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.unlockSecret == nil {
+		h.unlockSecret = newUnlockSecret()
+	}
+	return h
 }
 
 // HandleHomePage displays the list of all shortened URLs mapped to their original ones.
 // @Summary Displays the list of all shortened URLs mapped to their original ones in JSON format.
 // @Description Displays the list of all shortened URLs mapped to their original ones in JSON format.
+// @Description NOTE: Filter with "expiring_before" (soonest first), "tag" and "pending". The filters can be combined.
 // @Tags URL
+// @Param expiring_before query string false "Only list URLs expiring before this time"
+// @Param tag query string false "Only list URLs with this tag"
+// @Param pending query bool false "Only list URLs that are (true) or are not (false) waiting for their activation time"
 // @Produce json
 // @Success 200 {object} urlModel.URLMapping "URL Mappings"
+// @Failure 400 {string} string "Invalid expiring_before time or pending flag"
+// @Security ApiKeyAuth
 // @Router /url/display [get]
 func (h *Handler) HandleHomePage(c *gin.Context) {
 
-	urls, err := h.service.FetchAllURLs(c)
+	var urls []urlModel.URL
+	var err error
+	filter := urlModel.URLFilter{Tag: c.Query("tag")}
+	if expiringBefore := c.Query("expiring_before"); expiringBefore != "" {
+		before, parseErr := time.Parse(time.RFC3339, expiringBefore)
+		if parseErr != nil {
+			c.String(http.StatusBadRequest, "Invalid expiring_before time - expected an RFC 3339 time like 2024-04-02T00:00:00Z")
+			return
+		}
+		filter.ExpiringBefore = before
+	}
+	if pending := c.Query("pending"); pending != "" {
+		isPending, parseErr := strconv.ParseBool(pending)
+		if parseErr != nil {
+			c.String(http.StatusBadRequest, "Invalid pending flag - expected true or false")
+			return
+		}
+		filter.Pending = &isPending
+	}
+	if filter != (urlModel.URLFilter{}) {
+		urls, err = h.service.ListURLs(c, filter)
+	} else {
+		urls, err = h.service.FetchAllURLs(c)
+	}
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Failed to fetch URLs: %v", err)
 		return
@@ -38,7 +108,7 @@ func (h *Handler) HandleHomePage(c *gin.Context) {
 	var urlMappings []urlModel.URLMapping
 	for _, url := range urls {
 		// Append the URLMapping to the URLMappings slice
-		urlMappings = append(urlMappings, urlModel.URLMapping{ShortCode: url.ShortCode, OriginalURL: url.OriginalURL, Expiry: url.Expiry})
+		urlMappings = append(urlMappings, urlModel.NewURLMapping(url))
 	}
 
 	c.IndentedJSON(http.StatusOK, urlMappings)
@@ -71,11 +141,16 @@ func (h *Handler) HandleHomePage(c *gin.Context) {
 // @Description NOTE 1: In the JSON body, the "original_url" should contain proper formatting with either http or https. Example: https://www.google.com.
 // @Description NOTE 2: In the JSON body, the "expiry" date is optional, with the default expiration set to 30 days from now. The expiry time can be customized like this example: 2024-04-02T00:00:00Z.
 // @Description NOTE 3: In the JSON body, the "custom_short_code" is also optional. A unique custom short code can be set for the shortened URL.
+// @Description NOTE 4: The other fields are optional, and described in the request model.
+// @Description NOTE 5: With deduplication, an original URL shortened again returns the live link of the same API key. Set "force_new" to true to always get a new short code.
+// @Description NOTE 6: The "shortened_url" and "qr_code_url" use the domain of the workspace, or its custom domain verified first, when it has one.
 // @Tags URL
 // @Accept json
 // @Param original_url body urlModel.AddURLRequest true "Original URL, Expiry Time (optional), Custom Short Code (optional)"
 // @Produce json
 // @Success 200 {object} urlModel.AddSuccessResponse "Shortened URL"
+// @Failure 403 {object} map[string]string "The workspace holds its maximum number of links"
+// @Security ApiKeyAuth
 // @Router /url/add [post]
 func (h *Handler) HandleAddLink(c *gin.Context) {
 
@@ -86,85 +161,130 @@ func (h *Handler) HandleAddLink(c *gin.Context) {
 		return
 	}
 
-	// Check if the original URL is valid
-	originalURL := newUrl.OriginalURL
-	if !isValidUrl(originalURL) {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Invalid request - Missing http or https - example: https://www.google.com"})
+	// Create the shortened URL
+	// The custom short code is used if it is set and unique, otherwise a short code is generated
+	// The expiry time defaults to 30 days from now if it is not set
+	newUrl.Owner = requestOwner(c)
+	created, err := h.service.CreateURL(c, newUrl)
+	if errors.Is(err, urlModel.ErrShortCodeTaken) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Custom short code already exists"})
+		return
+	} else if isInvalidLink(err) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Invalid request - %v", err)})
+		return
+	} else if errors.Is(err, urlModel.ErrQuotaExceeded) {
+		c.IndentedJSON(http.StatusForbidden, gin.H{"message": fmt.Sprintf("Quota exceeded - %v", err)})
+		return
+	} else if err != nil {
+		c.String(http.StatusInternalServerError, "Error shortening URL: %v", err)
 		return
 	}
 
-	// Get the expiry time
-	// Check if the expiry time is not set
-	// If not, set the expiry time to the defaultExpiryDays
-	customExpiryTime := newUrl.Expiry
-	now := time.Now()
-	var defaultExpiryDays time.Duration = 30
-	defaultExpiryTime := now.Add(defaultExpiryDays * 24 * time.Hour)
-	var adjustedExpiryTime time.Time
-	if customExpiryTime == (time.Time{}) {
-		adjustedExpiryTime = defaultExpiryTime
-	} else {
-		// Check if the futureTime is after the current time
-		if customExpiryTime.After(now) {
-			adjustedExpiryTime = customExpiryTime
-		} else {
-			adjustedExpiryTime = defaultExpiryTime
-		}
-	}
-
-	// Get the custom short code
-	// Check if the custom short code is unique
-	// If not, return an error
-	// If it is unique, store the URL with the custom short code
-	// If the custom short code is not set, generate a random short code
-	// Store the URL with the generated short code
 	// Return the shortened URL and the expiry time
-	var updatedModel urlModel.URL
-	customShortCode := newUrl.CustomShortCode
-	if customShortCode != "" {
-		// Check if the custom short code is unique
-		if !h.service.IsUniqueShortCode(c, customShortCode) {
-			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Custom short code already exists"})
-			return
-		} else {
-			fmt.Println("Custom Short Code: ", customShortCode, " is unique")
+	baseURL := h.linkBaseURL(c)
+	shortenedURL := h.shortenedURL(baseURL, created.ShortCode)
+	c.IndentedJSON(http.StatusOK, urlModel.AddSuccessResponse{ShortenedURL: shortenedURL, QRCodeURL: h.qrCodeURL(baseURL, created.ShortCode), Expiry: created.Expiry, OriginalURL: created.OriginalURL, InputURL: created.InputURL})
+}
 
-			// Store the URL with the custom short code
-			updatedModel = urlModel.URL{
-				ShortCode:   customShortCode,
-				OriginalURL: originalURL,
-				Expiry:      adjustedExpiryTime,
-			}
-			h.service.StoreURL(c, updatedModel)
+// HandleBulkAddLinks creates shortened links for a list of original URLs in one request.
+// @Summary Creates shortened links for a list of original URLs.
+// @Description NOTE 1: The JSON body is an array of the same objects accepted by /url/add. The number of items is limited (500 by default). Bulk creation is never deduplicated.
+// @Description NOTE 2: Every item succeeds or fails on its own. The results are returned in the order of the request, with an "error" for each failed item.
+// @Description NOTE 3: The status is 200 when every item succeeded, 207 when only some did, and 400 when none did.
+// @Tags URL
+// @Accept json
+// @Param request body []urlModel.AddURLRequest true "List of Original URL, Expiry Time (optional), Custom Short Code (optional)"
+// @Produce json
+// @Success 200 {object} urlModel.BulkAddURLResponse "Every item was shortened"
+// @Success 207 {object} urlModel.BulkAddURLResponse "Some items were shortened"
+// @Failure 400 {object} urlModel.BulkAddURLResponse "No item was shortened, or the request is invalid"
+// @Failure 413 {object} map[string]string "Too many items"
+// @Security ApiKeyAuth
+// @Router /url/bulk [post]
+func (h *Handler) HandleBulkAddLinks(c *gin.Context) {
+
+	// Validate the input
+	var requests []urlModel.AddURLRequest
+	if err := c.BindJSON(&requests); err != nil || len(requests) == 0 {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Bad request - a non-empty JSON array of URLs is required"})
+		return
+	}
+	if len(requests) > h.bulkLimit {
+		c.IndentedJSON(http.StatusRequestEntityTooLarge, gin.H{"message": fmt.Sprintf("Too many URLs - at most %d are accepted per request", h.bulkLimit)})
+		return
+	}
+
+	// Items without an original URL are rejected at once; the service validates the others on their own
+	response := urlModel.BulkAddURLResponse{Results: make([]urlModel.BulkAddURLResult, len(requests))}
+	var valid []urlModel.AddURLRequest
+	var indexes []int
+	for i, req := range requests {
+		response.Results[i] = urlModel.BulkAddURLResult{Index: i, OriginalURL: req.OriginalURL}
+		if req.OriginalURL == "" {
+			response.Results[i].Error = "original_url is required"
+			continue
 		}
-	} else {
-		generatedShortCode, err := h.service.ShortenURL(c, originalURL)
+		valid = append(valid, req)
+		indexes = append(indexes, i)
+	}
+
+	// Create the valid items in one batch
+	if len(valid) > 0 {
+		urls, errs, err := h.service.CreateURLs(c, valid)
 		if err != nil {
-			c.String(http.StatusInternalServerError, "Error shortening URL: %v", err)
+			c.String(http.StatusInternalServerError, "Error shortening URLs: %v", err)
 			return
 		}
-		// Store the URL with the generated short code
-		updatedModel = urlModel.URL{
-			ShortCode:   generatedShortCode,
-			OriginalURL: originalURL,
-			Expiry:      adjustedExpiryTime,
+		baseURL := h.linkBaseURL(c)
+		for j, i := range indexes {
+			if errs[j] != nil {
+				response.Results[i].Error = errs[j].Error()
+				continue
+			}
+			expiry := urls[j].Expiry
+			response.Results[i].OriginalURL = urls[j].OriginalURL
+			response.Results[i].ShortenedURL = h.shortenedURL(baseURL, urls[j].ShortCode)
+			response.Results[i].Expiry = &expiry
 		}
-		h.service.StoreURL(c, updatedModel)
 	}
 
-	shortenedURL := fmt.Sprintf("http://localhost:9000/api/v1/redirect/%s", updatedModel.ShortCode)
-	c.IndentedJSON(http.StatusOK, gin.H{"shortened_url": shortenedURL, "expiry": adjustedExpiryTime, "original_url": originalURL})
+	// Count the outcomes to pick the status code
+	for _, result := range response.Results {
+		if result.Error == "" {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+	status := http.StatusOK
+	if response.Succeeded == 0 {
+		status = http.StatusBadRequest
+	} else if response.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.IndentedJSON(status, response)
 }
 
 // HandleRedirectToOriginalLink redirects the user to the original URL based on the short code.
 // @Summary Redirects the user to the original URL based on the input short code.
-// @Description NOTE: Copy the full url including the short code to the browser to be redirected. Do not use the Swagger UI here as it does not support redirection.
+// @Description NOTE 1: Copy the full url including the short code to the browser to be redirected. Do not use the Swagger UI here as it does not support redirection.
+// @Description NOTE 2: Append "+" to the short code, or set "preview" to true, to get a page showing where the link leads instead. Previews are not counted as clicks.
+// @Description NOTE 3: Protected links respond with a password prompt, and scheduled links with a 404 status until their activation time. Rules, variants and forwarding pick the destination as set on the link.
+// @Description NOTE 4: The status is the "redirect_type" of the link, or the default of the server (307 unless configured otherwise).
 // @Tags REDIRECT
-// @Param shortcode path string true "Short Code"
+// @Param shortcode path string true "Short Code, optionally followed by + for a preview"
+// @Param preview query bool false "Show the preview page instead of redirecting"
 // @Produce plain
+// @Produce html
+// @Success 200 {string} string "Preview page, or redirect page of an interstitial link"
+// @Success 301 {string} string "Permanently redirected to original url"
+// @Success 302 {string} string "Redirected to original url"
 // @Success 307 {string} string "Redirected to original url - example: http://localhost:9000/api/v1/redirect/2v5ompxD"
-// @Failure 400  {string}  string "Parameter missing - enter the short code in the URL path"
-// @Failure 404  {string}  string "No original URL exists for the given short code"
+// @Success 308 {string} string "Permanently redirected to original url"
+// @Failure 401 {string} string "Password prompt of a protected link"
+// @Failure 400  {string}  string "Parameter missing - enter the short code in the URL path, or invalid path after it"
+// @Failure 404  {string}  string "No original URL exists for the given short code, or the link is not active yet"
+// @Failure 410  {string}  string "The link has reached its maximum number of clicks"
 // @Router /redirect/{shortcode} [get]
 func (h *Handler) HandleRedirectToOriginalLink(c *gin.Context) {
 	shortCode := c.Param("shortcode")
@@ -173,12 +293,220 @@ func (h *Handler) HandleRedirectToOriginalLink(c *gin.Context) {
 		return
 	}
 
+	// A trailing "+" or the preview parameter shows where the link leads instead of following it
+	if code, ok := strings.CutSuffix(shortCode, "+"); ok || isPreviewRequest(c) {
+		if code == "" {
+			c.String(http.StatusBadRequest, "Parameter missing - enter the short code in the URL path")
+			return
+		}
+		h.renderPreview(c, code)
+		return
+	}
+
 	// Get the original URL based on the short code
-	originalURL, err := h.service.GetOriginalURL(c, shortCode)
+	link, err := h.service.ResolveURL(c, shortCode)
 	if err != nil {
 		c.String(http.StatusNotFound, "No original URL exists for the given short code: %v", err)
 		return
 	}
+	// Scheduled links are not available before their activation time, whatever their password
+	if link.Pending(time.Now()) {
+		h.renderPending(c, shortCode)
+		return
+	}
+	// A path after the short code is only followed by links that forward it
+	path, err := trailingPath(c.Param("path"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid path after the short code: %v", err)
+		return
+	} else if path != "" && !link.ForwardPath {
+		c.String(http.StatusNotFound, "No original URL exists for the given short code and path")
+		return
+	}
+	// Protected links ask for their password, unless it was entered recently
+	if link.Protected() && !h.isUnlocked(c, *link) {
+		h.renderPasswordPrompt(c, http.StatusUnauthorized, shortCode, "")
+		return
+	}
+
+	visitor := h.visitor(c)
+	if len(link.Variants) > 0 {
+		visitor.ID = h.visitorID(c)
+	}
+	destination, err := h.service.FollowURL(c, *link, visitor)
+	if errors.Is(err, urlModel.ErrClickLimitReached) {
+		c.String(http.StatusGone, "This link has reached its maximum number of clicks and no longer works")
+		return
+	} else if errors.Is(err, urlModel.ErrLinkPending) {
+		h.renderPending(c, shortCode)
+		return
+	} else if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to follow the link: %v", err)
+		return
+	}
+
+	h.redirect(c, *link, forward(destination, *link, path, c.Request.URL.RawQuery))
+}
+
+// HandleGetLink displays a single shortened URL without following it.
+// @Summary Displays a single shortened URL mapped to its original one.
+// @Tags URL
+// @Param shortcode path string true "Short Code"
+// @Produce json
+// @Success 200 {object} urlModel.URLMapping "URL Mapping"
+// @Failure 404 {object} map[string]string "No original URL exists for the given short code"
+// @Security ApiKeyAuth
+// @Router /url/{shortcode} [get]
+func (h *Handler) HandleGetLink(c *gin.Context) {
+	url, err := h.service.GetURL(c, c.Param("shortcode"))
+	if errors.Is(err, urlModel.ErrURLNotFound) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "No original URL exists for the given short code"})
+		return
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to fetch URL: %v", err)})
+		return
+	}
+
+	mapping := urlModel.NewURLMapping(*url)
+	if url.MaxClicks > 0 {
+		remaining, err := h.service.RemainingClicks(c, *url)
+		if err != nil {
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to fetch URL: %v", err)})
+			return
+		}
+		mapping.RemainingClicks = &remaining
+	}
+	c.IndentedJSON(http.StatusOK, mapping)
+}
+
+// HandleLinkStats displays the usage statistics of a shortened URL.
+// @Summary Displays the number of clicks on a shortened URL.
+// @Tags URL
+// @Param shortcode path string true "Short Code"
+// @Produce json
+// @Success 200 {object} urlModel.URLStats "URL Statistics"
+// @Failure 404 {object} map[string]string "No original URL exists for the given short code"
+// @Security ApiKeyAuth
+// @Router /url/{shortcode}/stats [get]
+func (h *Handler) HandleLinkStats(c *gin.Context) {
+	stats, err := h.service.GetStats(c, c.Param("shortcode"))
+	if errors.Is(err, urlModel.ErrURLNotFound) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "No original URL exists for the given short code"})
+		return
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to fetch statistics: %v", err)})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, stats)
+}
+
+// HandleUpdateLink changes the original URL, the expiry and/or the metadata of an existing short code.
+// @Summary Updates the original URL, the expiry and/or the metadata of an existing short code.
+// @Description NOTE: Every field in the JSON body is optional, and fields that are left out keep their current value. The request model describes how each field is cleared.
+// @Tags URL
+// @Accept json
+// @Param shortcode path string true "Short Code"
+// @Param request body urlModel.UpdateURLRequest true "Original URL, Expiry Time, Title, Description, Tags and Notes (all optional)"
+// @Produce json
+// @Success 200 {object} urlModel.URLMapping "Updated URL Mapping"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 404 {object} map[string]string "No original URL exists for the given short code"
+// @Security ApiKeyAuth
+// @Router /url/{shortcode} [put]
+func (h *Handler) HandleUpdateLink(c *gin.Context) {
+	shortCode := c.Param("shortcode")
+
+	// Validate the input
+	var req urlModel.UpdateURLRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Bad request - invalid JSON body"})
+		return
+	}
+
+	url, err := h.service.UpdateURL(c, shortCode, req)
+	if errors.Is(err, urlModel.ErrURLNotFound) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "No original URL exists for the given short code"})
+		return
+	} else if isInvalidLink(err) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Invalid request - %v", err)})
+		return
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to update URL: %v", err)})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, urlModel.NewURLMapping(*url))
+}
+
+// HandleDeleteLink removes a short code so that it no longer redirects.
+// @Summary Deletes a short code.
+// @Tags URL
+// @Param shortcode path string true "Short Code"
+// @Success 204 "Deleted"
+// @Failure 404 {object} map[string]string "No original URL exists for the given short code"
+// @Security ApiKeyAuth
+// @Router /url/{shortcode} [delete]
+func (h *Handler) HandleDeleteLink(c *gin.Context) {
+	shortCode := c.Param("shortcode")
+
+	err := h.service.DeleteURL(c, shortCode)
+	if errors.Is(err, urlModel.ErrURLNotFound) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "No original URL exists for the given short code"})
+		return
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to delete URL: %v", err)})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// isInvalidLink reports whether the service refused a link because of an invalid field of the request.
+func isInvalidLink(err error) bool {
+	return errors.Is(err, urlModel.ErrInvalidURL) || errors.Is(err, urlModel.ErrInvalidLink) || errors.Is(err, urlModel.ErrInvalidSchedule) ||
+		errors.Is(err, urlModel.ErrInvalidRule) || errors.Is(err, urlModel.ErrInvalidVariants) ||
+		errors.Is(err, urlModel.ErrInvalidPassthrough) || errors.Is(err, urlModel.ErrInvalidRedirectType) ||
+		errors.Is(err, urlModel.ErrInvalidUTM) || errors.Is(err, urlModel.ErrUTMPresetNotFound) ||
+		errors.Is(err, urlModel.ErrGroupNotFound) || errors.Is(err, urlModel.ErrInvalidShortCode)
+}
+
+// HandleWorkspace displays the workspace of the API key and the use of its quota.
+// @Summary Displays the workspace of the API key, with the number of links it holds and its quota.
+// @Description NOTE: Requests with the keys of SHORTENER_API_KEYS, or without keys when none are configured, belong to the default workspace, which has an empty ID.
+// @Tags WORKSPACE
+// @Produce json
+// @Success 200 {object} urlModel.WorkspaceUsage "Workspace"
+// @Security ApiKeyAuth
+// @Router /workspace [get]
+func (h *Handler) HandleWorkspace(c *gin.Context) {
+	usage, err := h.service.WorkspaceUsage(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to fetch the workspace: %v", err)})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, usage)
+}
+
+// linkBaseURL returns the scheme and host of the shortened URLs of the workspace: its public domain,
+// with the scheme of the public base URL, or the public base URL when it has none.
+// The public base URL is also used when the custom domains cannot be read, since a working link beats none.
+func (h *Handler) linkBaseURL(c *gin.Context) string {
+	domain, err := h.service.PublicDomain(c)
+	if err != nil || domain == "" {
+		return h.publicBaseURL
+	}
+	scheme, _, _ := strings.Cut(h.publicBaseURL, "://")
+	return scheme + "://" + domain
+}
+
+// shortenedURL builds the public URL that redirects to the original URL of the short code, see linkBaseURL.
+func (h *Handler) shortenedURL(baseURL, shortCode string) string {
+	return fmt.Sprintf("%s/api/v1/redirect/%s", baseURL, shortCode)
+}
 
-	c.Redirect(http.StatusTemporaryRedirect, originalURL)
+// qrCodeURL builds the public URL of the QR code image of the short code, on the same domain as its shortened URL.
+func (h *Handler) qrCodeURL(baseURL, shortCode string) string {
+	return fmt.Sprintf("%s/api/v1/qr/%s", baseURL, shortCode)
 }
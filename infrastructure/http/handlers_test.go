@@ -5,8 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -19,10 +24,28 @@ import (
 // mockURLRepository is a simple mock for url repository used in tests.
 // It allows us to inject custom behavior for each repository method.
 type mockURLRepository struct {
-	StoreFunc           func(ctx context.Context, url urlModel.URL) error
-	FindByShortCodeFunc func(ctx context.Context, shortCode string) (*urlModel.URL, error)
-	IsUniqueFunc        func(ctx context.Context, shortCode string) bool
-	FetchAllFunc        func(ctx context.Context) ([]urlModel.URL, error)
+	StoreFunc               func(ctx context.Context, url urlModel.URL) error
+	StoreNewFunc            func(ctx context.Context, url urlModel.URL) error
+	StoreBatchFunc          func(ctx context.Context, urls []urlModel.URL) ([]error, error)
+	FindByShortCodeFunc     func(ctx context.Context, shortCode string) (*urlModel.URL, error)
+	IsUniqueFunc            func(ctx context.Context, shortCode string) bool
+	FetchAllFunc            func(ctx context.Context) ([]urlModel.URL, error)
+	IterateFunc             func(ctx context.Context, fn func(urlModel.URL) error) error
+	DeleteFunc              func(ctx context.Context, shortCode string) error
+	IncrementClicksFunc     func(ctx context.Context, shortCode string) (int64, error)
+	IncrementClicksUpToFunc func(ctx context.Context, shortCode string, maxClicks int64) (int64, error)
+	GetClicksFunc           func(ctx context.Context, shortCode string) (int64, error)
+	CountClickCountryFunc   func(ctx context.Context, shortCode, country string) error
+	GetClickCountriesFunc   func(ctx context.Context, shortCode string) (map[string]int64, error)
+	CountClickVariantFunc   func(ctx context.Context, shortCode, variantURL string) error
+	GetClickVariantsFunc    func(ctx context.Context, shortCode string) (map[string]int64, error)
+	PopExpiredFunc          func(ctx context.Context, before time.Time) ([]string, error)
+	ExpireFunc              func(ctx context.Context, shortCode string) error
+	FindByGroupFunc         func(ctx context.Context, group string) ([]urlModel.URL, error)
+	CountFunc               func(ctx context.Context) (int64, error)
+	IndexDestinationFunc    func(ctx context.Context, owner string, url urlModel.URL) error
+	FindByDestinationFunc   func(ctx context.Context, owner, originalURL string) (*urlModel.URL, error)
+	SetPageMetadataFunc     func(ctx context.Context, shortCode, originalURL string, page urlModel.PageMetadata) error
 }
 
 // Store mocks storing a URL in the repository.
@@ -33,6 +56,22 @@ func (m *mockURLRepository) Store(ctx context.Context, url urlModel.URL) error {
 	return nil
 }
 
+// StoreNew mocks storing a new URL in the repository.
+func (m *mockURLRepository) StoreNew(ctx context.Context, url urlModel.URL) error {
+	if m.StoreNewFunc != nil {
+		return m.StoreNewFunc(ctx, url)
+	}
+	return nil
+}
+
+// StoreBatch mocks storing several URLs in the repository.
+func (m *mockURLRepository) StoreBatch(ctx context.Context, urls []urlModel.URL) ([]error, error) {
+	if m.StoreBatchFunc != nil {
+		return m.StoreBatchFunc(ctx, urls)
+	}
+	return make([]error, len(urls)), nil
+}
+
 // FindByShortCode mocks finding a URL by its short code.
 func (m *mockURLRepository) FindByShortCode(ctx context.Context, shortCode string) (*urlModel.URL, error) {
 	if m.FindByShortCodeFunc != nil {
@@ -57,6 +96,134 @@ func (m *mockURLRepository) FetchAll(ctx context.Context) ([]urlModel.URL, error
 	return nil, nil
 }
 
+// Iterate mocks iterating over all URLs in the repository.
+func (m *mockURLRepository) Iterate(ctx context.Context, fn func(urlModel.URL) error) error {
+	if m.IterateFunc != nil {
+		return m.IterateFunc(ctx, fn)
+	}
+	return nil
+}
+
+// Delete mocks deleting a URL from the repository.
+func (m *mockURLRepository) Delete(ctx context.Context, shortCode string) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, shortCode)
+	}
+	return nil
+}
+
+// IncrementClicks mocks counting a click on a short code.
+func (m *mockURLRepository) IncrementClicks(ctx context.Context, shortCode string) (int64, error) {
+	if m.IncrementClicksFunc != nil {
+		return m.IncrementClicksFunc(ctx, shortCode)
+	}
+	return 0, nil
+}
+
+// IncrementClicksUpTo mocks counting a click on a short code with a maximum number of clicks.
+func (m *mockURLRepository) IncrementClicksUpTo(ctx context.Context, shortCode string, maxClicks int64) (int64, error) {
+	if m.IncrementClicksUpToFunc != nil {
+		return m.IncrementClicksUpToFunc(ctx, shortCode, maxClicks)
+	}
+	return 0, nil
+}
+
+// GetClicks mocks reading the click counter of a short code.
+func (m *mockURLRepository) GetClicks(ctx context.Context, shortCode string) (int64, error) {
+	if m.GetClicksFunc != nil {
+		return m.GetClicksFunc(ctx, shortCode)
+	}
+	return 0, nil
+}
+
+// CountClickCountry mocks counting a click from a country.
+func (m *mockURLRepository) CountClickCountry(ctx context.Context, shortCode, country string) error {
+	if m.CountClickCountryFunc != nil {
+		return m.CountClickCountryFunc(ctx, shortCode, country)
+	}
+	return nil
+}
+
+// GetClickCountries mocks reading the clicks per country of a short code.
+func (m *mockURLRepository) GetClickCountries(ctx context.Context, shortCode string) (map[string]int64, error) {
+	if m.GetClickCountriesFunc != nil {
+		return m.GetClickCountriesFunc(ctx, shortCode)
+	}
+	return nil, nil
+}
+
+// CountClickVariant mocks counting a click sent to a variant.
+func (m *mockURLRepository) CountClickVariant(ctx context.Context, shortCode, variantURL string) error {
+	if m.CountClickVariantFunc != nil {
+		return m.CountClickVariantFunc(ctx, shortCode, variantURL)
+	}
+	return nil
+}
+
+// GetClickVariants mocks reading the clicks per variant of a short code.
+func (m *mockURLRepository) GetClickVariants(ctx context.Context, shortCode string) (map[string]int64, error) {
+	if m.GetClickVariantsFunc != nil {
+		return m.GetClickVariantsFunc(ctx, shortCode)
+	}
+	return nil, nil
+}
+
+// PopExpired mocks claiming the expired short codes.
+func (m *mockURLRepository) PopExpired(ctx context.Context, before time.Time) ([]string, error) {
+	if m.PopExpiredFunc != nil {
+		return m.PopExpiredFunc(ctx, before)
+	}
+	return nil, nil
+}
+
+// Expire mocks making a URL expire at once.
+func (m *mockURLRepository) Expire(ctx context.Context, shortCode string) error {
+	if m.ExpireFunc != nil {
+		return m.ExpireFunc(ctx, shortCode)
+	}
+	return nil
+}
+
+// FindByGroup mocks finding the URLs of a group.
+func (m *mockURLRepository) FindByGroup(ctx context.Context, group string) ([]urlModel.URL, error) {
+	if m.FindByGroupFunc != nil {
+		return m.FindByGroupFunc(ctx, group)
+	}
+	return nil, nil
+}
+
+// Count mocks counting the live URLs.
+func (m *mockURLRepository) Count(ctx context.Context) (int64, error) {
+	if m.CountFunc != nil {
+		return m.CountFunc(ctx)
+	}
+	return 0, nil
+}
+
+// IndexDestination mocks indexing a URL by its original URL.
+func (m *mockURLRepository) IndexDestination(ctx context.Context, owner string, url urlModel.URL) error {
+	if m.IndexDestinationFunc != nil {
+		return m.IndexDestinationFunc(ctx, owner, url)
+	}
+	return nil
+}
+
+// FindByDestination mocks finding the indexed URL of an original URL.
+func (m *mockURLRepository) FindByDestination(ctx context.Context, owner, originalURL string) (*urlModel.URL, error) {
+	if m.FindByDestinationFunc != nil {
+		return m.FindByDestinationFunc(ctx, owner, originalURL)
+	}
+	return nil, urlModel.ErrURLNotFound
+}
+
+// SetPageMetadata mocks storing the page metadata of a URL.
+func (m *mockURLRepository) SetPageMetadata(ctx context.Context, shortCode, originalURL string, page urlModel.PageMetadata) error {
+	if m.SetPageMetadataFunc != nil {
+		return m.SetPageMetadataFunc(ctx, shortCode, originalURL, page)
+	}
+	return nil
+}
+
 // newTestContext is a helper to create a Gin context and HTTP recorder for testing handlers.
 func newTestContext(method, path string, body []byte) (*gin.Context, *httptest.ResponseRecorder) {
 	w := httptest.NewRecorder()
@@ -126,6 +293,58 @@ func TestHandleHomePage(t *testing.T) {
 	}
 }
 
+// TestHandleHomePage_Filters tests that the listed URLs can be filtered by tag and expiry.
+func TestHandleHomePage_Filters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	now := time.Now()
+	repo := &mockURLRepository{
+		IterateFunc: func(ctx context.Context, fn func(urlModel.URL) error) error {
+			for _, url := range []urlModel.URL{
+				{ShortCode: "late", OriginalURL: "https://example.com/late", Expiry: now.Add(48 * time.Hour), Tags: []string{"launch"}},
+				{ShortCode: "soon", OriginalURL: "https://example.com/soon", Expiry: now.Add(time.Hour), Tags: []string{"launch", "blog"}},
+				{ShortCode: "untagged", OriginalURL: "https://example.com/untagged", Expiry: now.Add(time.Hour)},
+				{ShortCode: "scheduled", OriginalURL: "https://example.com/scheduled", Expiry: now.Add(72 * time.Hour), ActivatesAt: now.Add(24 * time.Hour), Tags: []string{"launch"}},
+			} {
+				if err := fn(url); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	h := NewHandler(application.NewURLService(repo))
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCodes []string
+	}{
+		{name: "tag", query: "?tag=launch", wantCodes: []string{"soon", "late", "scheduled"}},
+		{name: "tag in another case", query: "?tag=BLOG", wantCodes: []string{"soon"}},
+		{name: "tag and expiry", query: "?tag=launch&expiring_before=" + now.Add(2*time.Hour).UTC().Format(time.RFC3339), wantCodes: []string{"soon"}},
+		{name: "unknown tag", query: "?tag=missing", wantCodes: nil},
+		{name: "pending", query: "?pending=true", wantCodes: []string{"scheduled"}},
+		{name: "active with tag", query: "?pending=false&tag=launch", wantCodes: []string{"soon", "late"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newTestContext(http.MethodGet, "/url/display"+tt.query, nil)
+			h.HandleHomePage(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			var got []urlModel.URLMapping
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			var codes []string
+			for _, mapping := range got {
+				codes = append(codes, mapping.ShortCode)
+			}
+			assert.Equal(t, tt.wantCodes, codes)
+		})
+	}
+}
+
 // TestHandleAddLink tests the handler that creates a new shortened URL.
 func TestHandleAddLink(t *testing.T) {
 	gin.SetMode(gin.TestMode)
@@ -164,13 +383,29 @@ func TestHandleAddLink(t *testing.T) {
 			},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name: "custom short code with a slash",
+			body: []byte(`{"original_url":"https://example.com","custom_short_code":"a/b"}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{}
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "custom short code with a preview suffix",
+			body: []byte(`{"original_url":"https://example.com","custom_short_code":"abc+"}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{}
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name: "custom short code success",
 			body: []byte(`{"original_url":"https://example.com","custom_short_code":"mycode"}`),
 			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
 				return &mockURLRepository{
 					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
-					StoreFunc: func(ctx context.Context, url urlModel.URL) error {
+					StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
 						*stored = url
 						return nil
 					},
@@ -184,15 +419,38 @@ func TestHandleAddLink(t *testing.T) {
 				err := json.Unmarshal(w.Body.Bytes(), &resp)
 				assert.NoError(t, err)
 				assert.Contains(t, resp["shortened_url"], "mycode")
+				assert.Equal(t, "http://localhost:9000/api/v1/qr/mycode", resp["qr_code_url"])
 			},
 		},
 		{
-			name: "generate short code",
+			name: "custom short code taken by another instance",
+			body: []byte(`{"original_url":"https://example.com","custom_short_code":"mycode"}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+					StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+						return urlModel.ErrShortCodeTaken
+					},
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "generated short code taken by another instance",
 			body: []byte(`{"original_url":"https://example.com"}`),
 			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				taken := ""
 				return &mockURLRepository{
 					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
-					StoreFunc: func(ctx context.Context, url urlModel.URL) error {
+					StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+						// The first short code is taken after the uniqueness check, so another one is generated
+						if taken == "" {
+							taken = url.ShortCode
+							return urlModel.ErrShortCodeTaken
+						}
+						if url.ShortCode == taken {
+							return errors.New("short code stored twice")
+						}
 						*stored = url
 						return nil
 					},
@@ -200,96 +458,1418 @@ func TestHandleAddLink(t *testing.T) {
 			},
 			expectedStatus: http.StatusOK,
 			validate: func(t *testing.T, w *httptest.ResponseRecorder, stored urlModel.URL) {
-				// Check that the response contains the generated short code
-				var resp map[string]interface{}
-				err := json.Unmarshal(w.Body.Bytes(), &resp)
-				assert.NoError(t, err)
-				assert.Contains(t, resp["shortened_url"], stored.ShortCode)
+				assert.NotEmpty(t, stored.ShortCode)
 			},
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			var stored urlModel.URL
-			repo := tt.repoSetup(&stored)
-			service := application.NewURLService(repo)
-			h := NewHandler(service)
-
-			// Create a test context and call the handler
-			c, w := newTestContext(http.MethodPost, "/url/add", tt.body)
-			h.HandleAddLink(c)
-
-			// Assert the status code
-			assert.Equal(t, tt.expectedStatus, w.Code)
-			if tt.validate != nil {
-				tt.validate(t, w, stored)
-			}
-		})
-	}
-}
-
-// TestHandleRedirectToOriginalLink tests the handler that redirects to the original URL given a short code.
-func TestHandleRedirectToOriginalLink(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-
-	tests := []struct {
-		name           string
-		path           string
-		shortcode      string
-		repo           *mockURLRepository
-		expectedStatus int
-		expectedLoc    string
-	}{
 		{
-			name:           "missing param",
-			path:           "/redirect/",
-			shortcode:      "",
-			repo:           &mockURLRepository{},
+			name: "canonicalized url",
+			body: []byte(`{"original_url":" HTTPS://Example.com:443/a?b=1 "}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+						*stored = url
+						return nil
+					},
+				}
+			},
+			expectedStatus: http.StatusOK,
+			validate: func(t *testing.T, w *httptest.ResponseRecorder, stored urlModel.URL) {
+				// The canonical URL is stored and returned, along with the submitted one
+				assert.Equal(t, "https://example.com/a?b=1", stored.OriginalURL)
+				assert.Equal(t, " HTTPS://Example.com:443/a?b=1 ", stored.InputURL)
+				var resp urlModel.AddSuccessResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, "https://example.com/a?b=1", resp.OriginalURL)
+				assert.Equal(t, " HTTPS://Example.com:443/a?b=1 ", resp.InputURL)
+			},
+		},
+		{
+			name: "metadata",
+			body: []byte(`{"original_url":"https://example.com","title":" Launch post ","tags":["Launch","blog","launch"],"notes":"Shared on social"}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+						*stored = url
+						return nil
+					},
+				}
+			},
+			expectedStatus: http.StatusOK,
+			validate: func(t *testing.T, w *httptest.ResponseRecorder, stored urlModel.URL) {
+				// The title is trimmed and the tags are lowercased and deduplicated
+				assert.Equal(t, "Launch post", stored.Title)
+				assert.Equal(t, []string{"launch", "blog"}, stored.Tags)
+				assert.Equal(t, "Shared on social", stored.Notes)
+				assert.False(t, stored.CreatedAt.IsZero())
+				assert.Equal(t, stored.CreatedAt, stored.UpdatedAt)
+			},
+		},
+		{
+			name: "invalid tag",
+			body: []byte(`{"original_url":"https://example.com","tags":["not a tag"]}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{}
+			},
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
-			name:      "not found",
-			path:      "/redirect/abc",
-			shortcode: "abc",
-			repo: &mockURLRepository{
-				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
-					return nil, errors.New("not found")
-				},
+			name: "password",
+			body: []byte(`{"original_url":"https://example.com","password":"correct horse"}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+					StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+						*stored = url
+						return nil
+					},
+				}
+			},
+			expectedStatus: http.StatusOK,
+			validate: func(t *testing.T, w *httptest.ResponseRecorder, stored urlModel.URL) {
+				// Only the hash of the password is stored, and it is never returned
+				assert.True(t, stored.Protected())
+				assert.NotContains(t, stored.PasswordHash, "correct horse")
+				assert.NotContains(t, w.Body.String(), "correct horse")
+				assert.NotContains(t, w.Body.String(), stored.PasswordHash)
 			},
-			expectedStatus: http.StatusNotFound,
 		},
 		{
-			name:      "success",
-			path:      "/redirect/abc",
-			shortcode: "abc",
-			repo: &mockURLRepository{
-				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
-					return &urlModel.URL{OriginalURL: "https://example.com"}, nil
-				},
+			name: "password too long",
+			body: []byte(`{"original_url":"https://example.com","password":"` + strings.Repeat("a", 73) + `"}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{}
 			},
-			expectedStatus: http.StatusTemporaryRedirect,
-			expectedLoc:    "https://example.com",
+			expectedStatus: http.StatusBadRequest,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			service := application.NewURLService(tt.repo)
-			h := NewHandler(service)
-
-			// Create a test context and set the shortcode param if needed
-			c, w := newTestContext(http.MethodGet, tt.path, nil)
-			if tt.shortcode != "" {
-				c.Params = gin.Params{{Key: "shortcode", Value: tt.shortcode}}
-			}
-			h.HandleRedirectToOriginalLink(c)
-
-			// Assert the status code and redirect location if expected
-			assert.Equal(t, tt.expectedStatus, w.Code)
-			if tt.expectedLoc != "" {
-				assert.Equal(t, tt.expectedLoc, w.Header().Get("Location"))
-			}
-		})
-	}
+		{
+			name: "scheduled",
+			body: []byte(`{"original_url":"https://example.com","activates_at":"` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+					StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+						*stored = url
+						return nil
+					},
+				}
+			},
+			expectedStatus: http.StatusOK,
+			validate: func(t *testing.T, w *httptest.ResponseRecorder, stored urlModel.URL) {
+				assert.True(t, stored.Pending(time.Now()))
+			},
+		},
+		{
+			name: "routing rules",
+			body: []byte(`{"original_url":"https://example.com","rules":[{"os":"iOS","url":"https://apps.apple.com/app/id1"},{"bot":true,"url":"https://example.com/bots"}]}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+					StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+						*stored = url
+						return nil
+					},
+				}
+			},
+			expectedStatus: http.StatusOK,
+			validate: func(t *testing.T, w *httptest.ResponseRecorder, stored urlModel.URL) {
+				if assert.Len(t, stored.Rules, 2) {
+					assert.Equal(t, urlModel.OSIOS, stored.Rules[0].OS, "The operating system should be lowercased")
+					assert.Equal(t, "https://example.com/bots", stored.Rules[1].URL)
+				}
+			},
+		},
+		{
+			name: "routing rule with unknown device",
+			body: []byte(`{"original_url":"https://example.com","rules":[{"device":"watch","url":"https://example.com/watch"}]}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "routing rule without criteria",
+			body: []byte(`{"original_url":"https://example.com","rules":[{"url":"https://example.com/all"}]}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "routing rule to another scheme",
+			body: []byte(`{"original_url":"https://example.com","rules":[{"os":"ios","url":"javascript://example.com/%0aalert(1)"}]}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "variants",
+			body: []byte(`{"original_url":"https://example.com","variants":[{"url":"https://Example.com/a","weight":70},{"url":"https://example.com/b","weight":30}]}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+					StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+						*stored = url
+						return nil
+					},
+				}
+			},
+			expectedStatus: http.StatusOK,
+			validate: func(t *testing.T, w *httptest.ResponseRecorder, stored urlModel.URL) {
+				assert.Equal(t, []urlModel.Variant{{URL: "https://example.com/a", Weight: 70}, {URL: "https://example.com/b", Weight: 30}}, stored.Variants)
+			},
+		},
+		{
+			name: "single variant",
+			body: []byte(`{"original_url":"https://example.com","variants":[{"url":"https://example.com/a","weight":1}]}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "variants without weight",
+			body: []byte(`{"original_url":"https://example.com","variants":[{"url":"https://example.com/a"},{"url":"https://example.com/b"}]}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "repeated variant",
+			body: []byte(`{"original_url":"https://example.com","variants":[{"url":"https://example.com/a","weight":1},{"url":"https://EXAMPLE.com/a","weight":1}]}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "forwarding and redirect type",
+			body: []byte(`{"original_url":"https://example.com","forward_query":"Override","forward_path":true,"redirect_type":"Interstitial"}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+					StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+						*stored = url
+						return nil
+					},
+				}
+			},
+			expectedStatus: http.StatusOK,
+			validate: func(t *testing.T, w *httptest.ResponseRecorder, stored urlModel.URL) {
+				assert.Equal(t, urlModel.ForwardQueryOverride, stored.ForwardQuery)
+				assert.True(t, stored.ForwardPath)
+				assert.Equal(t, urlModel.RedirectInterstitial, stored.RedirectType)
+			},
+		},
+		{
+			name: "unknown query forwarding",
+			body: []byte(`{"original_url":"https://example.com","forward_query":"merge"}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "unknown redirect type",
+			body: []byte(`{"original_url":"https://example.com","redirect_type":"303"}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "activation after the expiry",
+			body: []byte(`{"original_url":"https://example.com","expiry":"` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `","activates_at":"` + time.Now().Add(2*time.Hour).UTC().Format(time.RFC3339) + `"}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "generate short code",
+			body: []byte(`{"original_url":"https://example.com"}`),
+			repoSetup: func(stored *urlModel.URL) *mockURLRepository {
+				return &mockURLRepository{
+					IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+					StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+						*stored = url
+						return nil
+					},
+				}
+			},
+			expectedStatus: http.StatusOK,
+			validate: func(t *testing.T, w *httptest.ResponseRecorder, stored urlModel.URL) {
+				// Check that the response contains the generated short code
+				var resp map[string]interface{}
+				err := json.Unmarshal(w.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Contains(t, resp["shortened_url"], stored.ShortCode)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored urlModel.URL
+			repo := tt.repoSetup(&stored)
+			service := application.NewURLService(repo)
+			h := NewHandler(service)
+
+			// Create a test context and call the handler
+			c, w := newTestContext(http.MethodPost, "/url/add", tt.body)
+			h.HandleAddLink(c)
+
+			// Assert the status code
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.validate != nil {
+				tt.validate(t, w, stored)
+			}
+		})
+	}
+}
+
+// TestHandleAddLink_Deduplication tests that repeat submissions return the owner's existing link.
+func TestHandleAddLink_Deduplication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	existing := &urlModel.URL{ShortCode: "existing", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}
+
+	tests := []struct {
+		name          string
+		body          []byte
+		wantShortCode string
+		wantStored    bool
+	}{
+		{
+			name:          "repeat submission",
+			body:          []byte(`{"original_url":"https://example.com"}`),
+			wantShortCode: "existing",
+			wantStored:    false,
+		},
+		{
+			name:       "force new",
+			body:       []byte(`{"original_url":"https://example.com","force_new":true}`),
+			wantStored: true,
+		},
+		{
+			name:          "custom short code",
+			body:          []byte(`{"original_url":"https://example.com","custom_short_code":"mycode"}`),
+			wantShortCode: "mycode",
+			wantStored:    true,
+		},
+		{
+			name:       "new destination",
+			body:       []byte(`{"original_url":"https://example.org"}`),
+			wantStored: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored, indexed *urlModel.URL
+			repo := &mockURLRepository{
+				StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+					stored = &url
+					return nil
+				},
+				FindByDestinationFunc: func(ctx context.Context, owner, originalURL string) (*urlModel.URL, error) {
+					assert.Equal(t, "owner1", owner)
+					if originalURL == existing.OriginalURL {
+						return existing, nil
+					}
+					return nil, urlModel.ErrURLNotFound
+				},
+				IndexDestinationFunc: func(ctx context.Context, owner string, url urlModel.URL) error {
+					assert.Equal(t, "owner1", owner)
+					indexed = &url
+					return nil
+				},
+			}
+			h := NewHandler(application.NewURLService(repo, application.WithDeduplication()))
+
+			c, w := newTestContext(http.MethodPost, "/url/add", tt.body)
+			c.Set(ownerContextKey, "owner1")
+			h.HandleAddLink(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			var resp urlModel.AddSuccessResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			if tt.wantShortCode != "" {
+				assert.True(t, strings.HasSuffix(resp.ShortenedURL, "/"+tt.wantShortCode))
+			}
+			assert.Equal(t, tt.wantStored, stored != nil)
+			if tt.wantStored {
+				assert.Equal(t, stored, indexed, "A new link should be indexed by its destination")
+			}
+		})
+	}
+}
+
+// stubPageFetcher returns the same page metadata for every URL.
+type stubPageFetcher struct {
+	page urlModel.PageMetadata
+}
+
+// Fetch returns the stubbed page metadata.
+func (f stubPageFetcher) Fetch(ctx context.Context, url string) (*urlModel.PageMetadata, error) {
+	page := f.page
+	return &page, nil
+}
+
+// TestHandleAddLink_PageMetadata tests that the target page of a new link is fetched in the background after it is created.
+func TestHandleAddLink_PageMetadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type pageUpdate struct {
+		shortCode, originalURL string
+		page                   urlModel.PageMetadata
+	}
+	updates := make(chan pageUpdate, 1)
+	repo := &mockURLRepository{
+		SetPageMetadataFunc: func(ctx context.Context, shortCode, originalURL string, page urlModel.PageMetadata) error {
+			updates <- pageUpdate{shortCode, originalURL, page}
+			return nil
+		},
+	}
+	worker := application.NewPageMetadataWorker(repo, stubPageFetcher{page: urlModel.PageMetadata{Title: "Example Domain"}}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.Run(ctx, 1)
+	h := NewHandler(application.NewURLService(repo, application.WithPageMetadataWorker(worker)))
+
+	c, w := newTestContext(http.MethodPost, "/url/add", []byte(`{"original_url":"https://example.com","custom_short_code":"page1"}`))
+	h.HandleAddLink(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	select {
+	case update := <-updates:
+		assert.Equal(t, "page1", update.shortCode)
+		assert.Equal(t, "https://example.com", update.originalURL)
+		assert.Equal(t, "Example Domain", update.page.Title)
+	case <-time.After(5 * time.Second):
+		t.Fatal("The page metadata was not stored")
+	}
+}
+
+// TestHandleBulkAddLinks tests the handler that creates several shortened URLs at once.
+func TestHandleBulkAddLinks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           []byte
+		repo           *mockURLRepository
+		expectedStatus int
+		wantSucceeded  int
+		wantFailed     int
+	}{
+		{
+			name:           "empty list",
+			body:           []byte(`[]`),
+			repo:           &mockURLRepository{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "too many items",
+			body:           []byte(`[{"original_url":"https://a.com"},{"original_url":"https://b.com"},{"original_url":"https://c.com"}]`),
+			repo:           &mockURLRepository{},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "all succeed with the same url twice",
+			body:           []byte(`[{"original_url":"https://a.com"},{"original_url":"https://a.com"}]`),
+			repo:           &mockURLRepository{},
+			expectedStatus: http.StatusOK,
+			wantSucceeded:  2,
+		},
+		{
+			name:           "partial success",
+			body:           []byte(`[{"original_url":"https://a.com","custom_short_code":"dup"},{"original_url":"https://b.com","custom_short_code":"dup"}]`),
+			repo:           &mockURLRepository{},
+			expectedStatus: http.StatusMultiStatus,
+			wantSucceeded:  1,
+			wantFailed:     1,
+		},
+		{
+			name:           "invalid custom short code",
+			body:           []byte(`[{"original_url":"https://a.com","custom_short_code":"a b*"},{"original_url":"https://b.com"}]`),
+			repo:           &mockURLRepository{},
+			expectedStatus: http.StatusMultiStatus,
+			wantSucceeded:  1,
+			wantFailed:     1,
+		},
+		{
+			name: "all fail",
+			body: []byte(`[{"original_url":"invalid"},{"original_url":"https://b.com","custom_short_code":"taken"}]`),
+			repo: &mockURLRepository{
+				StoreBatchFunc: func(ctx context.Context, urls []urlModel.URL) ([]error, error) {
+					return []error{urlModel.ErrShortCodeTaken}, nil
+				},
+			},
+			expectedStatus: http.StatusBadRequest,
+			wantFailed:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := application.NewURLService(tt.repo)
+			h := NewHandler(service, WithBulkLimit(2))
+
+			c, w := newTestContext(http.MethodPost, "/url/bulk", tt.body)
+			h.HandleBulkAddLinks(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.wantSucceeded+tt.wantFailed > 0 {
+				var resp urlModel.BulkAddURLResponse
+				err := json.Unmarshal(w.Body.Bytes(), &resp)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantSucceeded, resp.Succeeded)
+				assert.Equal(t, tt.wantFailed, resp.Failed)
+				// Every succeeded item must have its own short code
+				seen := map[string]bool{}
+				for _, result := range resp.Results {
+					if result.Error == "" {
+						assert.False(t, seen[result.ShortenedURL], "Short codes within a batch must be unique")
+						seen[result.ShortenedURL] = true
+					}
+				}
+			}
+		})
+	}
+}
+
+// testCountries locates the IP addresses it maps to a country.
+type testCountries map[string]string
+
+func (c testCountries) Country(ip net.IP) string {
+	return c[ip.String()]
+}
+
+// TestHandleRedirectToOriginalLink tests the handler that redirects to the original URL given a short code.
+func TestHandleRedirectToOriginalLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		path           string
+		shortcode      string
+		trailingPath   string
+		userAgent      string
+		forwardedFor   string
+		repo           *mockURLRepository
+		opts           []HandlerOption
+		expectedStatus int
+		expectedLoc    string
+		expectedBody   []string
+		expectedHeader map[string]string
+	}{
+		{
+			name:           "missing param",
+			path:           "/redirect/",
+			shortcode:      "",
+			repo:           &mockURLRepository{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "not found",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return nil, errors.New("not found")
+				},
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:      "success",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{OriginalURL: "https://example.com"}, nil
+				},
+			},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedLoc:    "https://example.com",
+		},
+		{
+			name:      "within the click limit",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com", MaxClicks: 3}, nil
+				},
+				IncrementClicksUpToFunc: func(ctx context.Context, shortCode string, maxClicks int64) (int64, error) {
+					assert.Equal(t, int64(3), maxClicks)
+					return 3, nil
+				},
+			},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedLoc:    "https://example.com",
+		},
+		{
+			name:      "click limit reached",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com", MaxClicks: 3}, nil
+				},
+				IncrementClicksUpToFunc: func(ctx context.Context, shortCode string, maxClicks int64) (int64, error) {
+					return 0, urlModel.ErrClickLimitReached
+				},
+			},
+			expectedStatus: http.StatusGone,
+		},
+		{
+			name:      "routed by operating system",
+			path:      "/redirect/app",
+			shortcode: "app",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "app", OriginalURL: "https://example.com/app", Rules: []urlModel.RoutingRule{
+						{OS: urlModel.OSIOS, URL: "https://apps.apple.com/app/id1"},
+						{OS: urlModel.OSAndroid, Device: urlModel.DeviceMobile, URL: "https://play.google.com/store/apps/details?id=app"},
+					}}, nil
+				},
+			},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedLoc:    "https://apps.apple.com/app/id1",
+		},
+		{
+			name:      "routed by operating system and device",
+			path:      "/redirect/app",
+			shortcode: "app",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Mobile Safari/537.36",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "app", OriginalURL: "https://example.com/app", Rules: []urlModel.RoutingRule{
+						{OS: urlModel.OSIOS, URL: "https://apps.apple.com/app/id1"},
+						{OS: urlModel.OSAndroid, Device: urlModel.DeviceMobile, URL: "https://play.google.com/store/apps/details?id=app"},
+					}}, nil
+				},
+			},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedLoc:    "https://play.google.com/store/apps/details?id=app",
+		},
+		{
+			name:      "no matching rule",
+			path:      "/redirect/app",
+			shortcode: "app",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "app", OriginalURL: "https://example.com/app", Rules: []urlModel.RoutingRule{
+						{OS: urlModel.OSIOS, URL: "https://apps.apple.com/app/id1"},
+						{OS: urlModel.OSAndroid, Device: urlModel.DeviceMobile, URL: "https://play.google.com/store/apps/details?id=app"},
+					}}, nil
+				},
+			},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedLoc:    "https://example.com/app",
+		},
+		{
+			name:         "routed by country",
+			path:         "/redirect/portal",
+			shortcode:    "portal",
+			forwardedFor: "203.0.113.7",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "portal", OriginalURL: "https://example.com", Rules: []urlModel.RoutingRule{
+						{Countries: []string{"DE", "AT"}, URL: "https://example.de"},
+					}}, nil
+				},
+				CountClickCountryFunc: func(ctx context.Context, shortCode, country string) error {
+					assert.Equal(t, "DE", country)
+					return nil
+				},
+			},
+			opts:           []HandlerOption{WithCountryLocator(testCountries{"203.0.113.7": "DE"})},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedLoc:    "https://example.de",
+		},
+		{
+			name:         "unknown country",
+			path:         "/redirect/portal",
+			shortcode:    "portal",
+			forwardedFor: "198.51.100.1",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "portal", OriginalURL: "https://example.com", Rules: []urlModel.RoutingRule{
+						{Countries: []string{"DE", "AT"}, URL: "https://example.de"},
+					}}, nil
+				},
+				CountClickCountryFunc: func(ctx context.Context, shortCode, country string) error {
+					t.Error("A click from an unknown country should not be counted per country")
+					return nil
+				},
+			},
+			opts:           []HandlerOption{WithCountryLocator(testCountries{"203.0.113.7": "DE"})},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedLoc:    "https://example.com",
+		},
+		{
+			name:      "pending link",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com/launch", Expiry: time.Now().Add(48 * time.Hour), ActivatesAt: time.Now().Add(time.Hour)}, nil
+				},
+				IncrementClicksFunc: func(ctx context.Context, shortCode string) (int64, error) {
+					t.Error("A pending link should not count a click")
+					return 0, nil
+				},
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   []string{"not available yet"},
+		},
+		{
+			name:      "pending link without the page",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com/launch", Expiry: time.Now().Add(48 * time.Hour), ActivatesAt: time.Now().Add(time.Hour)}, nil
+				},
+				IncrementClicksFunc: func(ctx context.Context, shortCode string) (int64, error) {
+					t.Error("A pending link should not count a click")
+					return 0, nil
+				},
+			},
+			opts:           []HandlerOption{WithPendingLinkPage(false)},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   []string{"No original URL exists for the given short code"},
+		},
+		{
+			name:      "preview of a pending link",
+			path:      "/redirect/abc+",
+			shortcode: "abc+",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com/launch", Expiry: time.Now().Add(48 * time.Hour), ActivatesAt: time.Now().Add(time.Hour)}, nil
+				},
+				IncrementClicksFunc: func(ctx context.Context, shortCode string) (int64, error) {
+					t.Error("A pending link should not count a click")
+					return 0, nil
+				},
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   []string{"not available yet"},
+		},
+		{
+			name:      "preview with plus",
+			path:      "/redirect/abc+",
+			shortcode: "abc+",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					assert.Equal(t, "abc", code)
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com/a?b=1", Title: "<Example>", CreatedBy: "Ops team", Expiry: time.Now().Add(time.Hour)}, nil
+				},
+				IncrementClicksFunc: func(ctx context.Context, shortCode string) (int64, error) {
+					t.Error("A preview should not count as a click")
+					return 0, nil
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{"https://example.com/a?b=1", "&lt;Example&gt;", "Ops team", "/api/v1/redirect/abc\""},
+		},
+		{
+			name:      "preview parameter",
+			path:      "/redirect/abc?preview=1",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					page := &urlModel.PageMetadata{Title: "Fetched title", SiteName: "Example"}
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com", Page: page, Expiry: time.Now().Add(time.Hour)}, nil
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{"Fetched title", "Example", "Continue to example.com"},
+		},
+		{
+			name:      "preview not found",
+			path:      "/redirect/abc+",
+			shortcode: "abc+",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return nil, urlModel.ErrURLNotFound
+				},
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "preview without short code",
+			path:           "/redirect/+",
+			shortcode:      "+",
+			repo:           &mockURLRepository{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "default redirect type",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com", Expiry: time.Now().Add(48 * time.Hour)}, nil
+				},
+			},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedLoc:    "https://example.com",
+			expectedHeader: map[string]string{"Cache-Control": "public, no-cache"},
+		},
+		{
+			name:      "permanent redirect",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com", RedirectType: urlModel.RedirectMovedPermanently, Expiry: time.Now().Add(48 * time.Hour)}, nil
+				},
+			},
+			expectedStatus: http.StatusMovedPermanently,
+			expectedLoc:    "https://example.com",
+			expectedHeader: map[string]string{"Cache-Control": "public, max-age=86400"},
+		},
+		{
+			name:      "permanent redirect expiring soon",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com", RedirectType: urlModel.RedirectPermanent, Expiry: time.Now().Add(time.Hour)}, nil
+				},
+			},
+			expectedStatus: http.StatusPermanentRedirect,
+			expectedLoc:    "https://example.com",
+			expectedHeader: map[string]string{"Cache-Control": "public, max-age=3599"},
+		},
+		{
+			name:      "permanent redirect of a routed link",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com", RedirectType: urlModel.RedirectPermanent, Expiry: time.Now().Add(48 * time.Hour), Rules: []urlModel.RoutingRule{{OS: urlModel.OSIOS, URL: "https://apps.apple.com"}}}, nil
+				},
+			},
+			expectedStatus: http.StatusPermanentRedirect,
+			expectedLoc:    "https://example.com",
+			expectedHeader: map[string]string{"Cache-Control": "private, max-age=86400", "Vary": "User-Agent"},
+		},
+		{
+			name:      "permanent redirect of a limited link",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com", RedirectType: urlModel.RedirectMovedPermanently, Expiry: time.Now().Add(48 * time.Hour), MaxClicks: 10}, nil
+				},
+			},
+			expectedStatus: http.StatusMovedPermanently,
+			expectedLoc:    "https://example.com",
+			expectedHeader: map[string]string{"Cache-Control": "public, no-cache"},
+		},
+		{
+			name:      "redirect type of the server",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com", Expiry: time.Now().Add(48 * time.Hour)}, nil
+				},
+			},
+			opts:           []HandlerOption{WithRedirectType(urlModel.RedirectFound)},
+			expectedStatus: http.StatusFound,
+			expectedLoc:    "https://example.com",
+			expectedHeader: map[string]string{"Cache-Control": "public, no-cache"},
+		},
+		{
+			name:      "redirect type of the link over the server",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com", RedirectType: urlModel.RedirectTemporary, Expiry: time.Now().Add(48 * time.Hour)}, nil
+				},
+			},
+			opts:           []HandlerOption{WithRedirectType(urlModel.RedirectFound)},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedLoc:    "https://example.com",
+			expectedHeader: map[string]string{"Cache-Control": "public, no-cache"},
+		},
+		{
+			name:      "interstitial",
+			path:      "/redirect/abc",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com/a?b=1&c=2", RedirectType: urlModel.RedirectInterstitial, Expiry: time.Now().Add(48 * time.Hour)}, nil
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   []string{`<meta http-equiv="refresh" content="0; url=https://example.com/a?b=1&amp;c=2">`, `window.location.replace("https://example.com/a?b=1\u0026c=2")`, `<a href="https://example.com/a?b=1&amp;c=2">`},
+			expectedHeader: map[string]string{"Cache-Control": "public, no-cache", "Location": ""},
+		},
+		{
+			name:      "query dropped by default",
+			path:      "/redirect/abc?ref=mail",
+			shortcode: "abc",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com/docs?lang=en"}, nil
+				},
+			},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedLoc:    "https://example.com/docs?lang=en",
+		},
+		{
+			name:         "query and path forwarded",
+			path:         "/redirect/abc/guide/a%20b?ref=mail&lang=de",
+			shortcode:    "abc",
+			trailingPath: "/guide/a b",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com/docs?lang=en", ForwardQuery: urlModel.ForwardQueryOverride, ForwardPath: true}, nil
+				},
+			},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedLoc:    "https://example.com/docs/guide/a%20b?ref=mail&lang=de",
+		},
+		{
+			name:         "path not forwarded",
+			path:         "/redirect/abc/guide",
+			shortcode:    "abc",
+			trailingPath: "/guide",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com/docs", ForwardQuery: urlModel.ForwardQueryAppend}, nil
+				},
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:         "path climbing out of the destination",
+			path:         "/redirect/abc/../admin",
+			shortcode:    "abc",
+			trailingPath: "/../admin",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com/docs?lang=en", ForwardQuery: urlModel.ForwardQueryOverride, ForwardPath: true}, nil
+				},
+				IncrementClicksFunc: func(ctx context.Context, shortCode string) (int64, error) {
+					t.Error("An invalid path should not count a click")
+					return 0, nil
+				},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "trailing slash only",
+			path:         "/redirect/abc/",
+			shortcode:    "abc",
+			trailingPath: "/",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com"}, nil
+				},
+			},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedLoc:    "https://example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := application.NewURLService(tt.repo)
+			h := NewHandler(service, tt.opts...)
+
+			// Create a test context and set the shortcode param if needed
+			c, w := newTestContext(http.MethodGet, tt.path, nil)
+			if tt.shortcode != "" {
+				c.Params = gin.Params{{Key: "shortcode", Value: tt.shortcode}}
+			}
+			if tt.trailingPath != "" {
+				c.Params = append(c.Params, gin.Param{Key: "path", Value: tt.trailingPath})
+			}
+			if tt.userAgent != "" {
+				c.Request.Header.Set("User-Agent", tt.userAgent)
+			}
+			c.Request.RemoteAddr = "127.0.0.1:41000"
+			if tt.forwardedFor != "" {
+				c.Request.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			h.HandleRedirectToOriginalLink(c)
+
+			// Assert the status code and redirect location if expected
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedLoc != "" {
+				assert.Equal(t, tt.expectedLoc, w.Header().Get("Location"))
+			}
+			for _, expected := range tt.expectedBody {
+				assert.Contains(t, w.Body.String(), expected)
+			}
+			for key, expected := range tt.expectedHeader {
+				assert.Equal(t, expected, w.Header().Get(key), key)
+			}
+			assert.NotContains(t, w.Body.String(), "https://example.com/launch", "A pending link should not reveal where it leads")
+		})
+	}
+}
+
+// TestHandleRedirectToOriginalLink_Variants tests that visitors are split between the variants of a link by their weight,
+// and keep their variant thanks to a cookie.
+func TestHandleRedirectToOriginalLink_Variants(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	counted := map[string]int{}
+	repo := &mockURLRepository{
+		FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+			return &urlModel.URL{ShortCode: "ab", OriginalURL: "https://example.com", Variants: []urlModel.Variant{
+				{URL: "https://example.com/a", Weight: 3},
+				{URL: "https://example.com/b", Weight: 1},
+				{URL: "https://example.com/paused", Weight: 0},
+			}}, nil
+		},
+		CountClickVariantFunc: func(ctx context.Context, shortCode, variantURL string) error {
+			counted[variantURL]++
+			return nil
+		},
+	}
+	h := NewHandler(application.NewURLService(repo))
+	follow := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		c, w := newTestContext(http.MethodGet, "/redirect/ab", nil)
+		c.Params = gin.Params{{Key: "shortcode", Value: "ab"}}
+		if cookie != nil {
+			c.Request.AddCookie(cookie)
+		}
+		h.HandleRedirectToOriginalLink(c)
+		return w
+	}
+
+	// A new visitor is given an ID, and keeps its variant with it
+	w := follow(nil)
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, visitorCookie, cookies[0].Name)
+		assert.True(t, cookies[0].HttpOnly)
+		first := w.Header().Get("Location")
+		for i := 0; i < 10; i++ {
+			again := follow(&http.Cookie{Name: visitorCookie, Value: cookies[0].Value})
+			assert.Equal(t, first, again.Header().Get("Location"), "A visitor should keep its variant")
+			assert.Empty(t, again.Result().Cookies(), "A known visitor should keep its ID")
+		}
+	}
+	assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+
+	// New visitors are split by weight, and never sent to a paused variant
+	counted = map[string]int{}
+	for i := 0; i < 2000; i++ {
+		follow(nil)
+	}
+	assert.Equal(t, 2000, counted["https://example.com/a"]+counted["https://example.com/b"])
+	assert.InDelta(t, 1500, counted["https://example.com/a"], 100)
+	assert.Zero(t, counted["https://example.com/paused"])
+}
+
+// TestHandleUpdateLink tests the handler that updates an existing shortened URL.
+func TestHandleUpdateLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	existing := func(ctx context.Context, code string) (*urlModel.URL, error) {
+		return &urlModel.URL{ShortCode: code, OriginalURL: "https://old.example.com", Expiry: time.Now().Add(time.Hour)}, nil
+	}
+
+	tests := []struct {
+		name           string
+		body           []byte
+		repo           *mockURLRepository
+		expectedStatus int
+		expectedURL    string
+	}{
+		{
+			name:           "invalid url",
+			body:           []byte(`{"original_url":"invalid"}`),
+			repo:           &mockURLRepository{FindByShortCodeFunc: existing},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "expiry in the past",
+			body:           []byte(`{"expiry":"2000-01-01T00:00:00Z"}`),
+			repo:           &mockURLRepository{FindByShortCodeFunc: existing},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "not found",
+			body: []byte(`{"original_url":"https://new.example.com"}`),
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return nil, urlModel.ErrURLNotFound
+				},
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "success",
+			body:           []byte(`{"original_url":"https://new.example.com"}`),
+			repo:           &mockURLRepository{FindByShortCodeFunc: existing},
+			expectedStatus: http.StatusOK,
+			expectedURL:    "https://new.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := application.NewURLService(tt.repo)
+			h := NewHandler(service)
+
+			c, w := newTestContext(http.MethodPut, "/url/abc", tt.body)
+			c.Params = gin.Params{{Key: "shortcode", Value: "abc"}}
+			h.HandleUpdateLink(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedURL != "" {
+				var got urlModel.URLMapping
+				err := json.Unmarshal(w.Body.Bytes(), &got)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedURL, got.OriginalURL)
+			}
+		})
+	}
+}
+
+// TestHandleDeleteLink tests the handler that deletes a shortened URL.
+func TestHandleGetLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name            string
+		url             urlModel.URL
+		clicks          int64
+		expectedMapping string
+	}{
+		{name: "unlimited", url: urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com"}},
+		{name: "remaining clicks", url: urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com", MaxClicks: 5}, clicks: 2, expectedMapping: `"remaining_clicks": 3`},
+		{name: "exhausted", url: urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com", MaxClicks: 5}, clicks: 7, expectedMapping: `"remaining_clicks": 0`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					url := tt.url
+					return &url, nil
+				},
+				GetClicksFunc: func(ctx context.Context, shortCode string) (int64, error) {
+					return tt.clicks, nil
+				},
+			}
+			h := NewHandler(application.NewURLService(repo))
+
+			c, w := newTestContext(http.MethodGet, "/url/abc", nil)
+			c.Params = gin.Params{{Key: "shortcode", Value: "abc"}}
+			h.HandleGetLink(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			if tt.expectedMapping == "" {
+				assert.NotContains(t, w.Body.String(), "remaining_clicks")
+			} else {
+				assert.Contains(t, w.Body.String(), tt.expectedMapping)
+			}
+		})
+	}
+}
+
+func TestHandleDeleteLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		repo           *mockURLRepository
+		expectedStatus int
+	}{
+		{
+			name: "not found",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return nil, urlModel.ErrURLNotFound
+				},
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "success",
+			repo: &mockURLRepository{
+				FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+					return &urlModel.URL{ShortCode: code, OriginalURL: "https://example.com"}, nil
+				},
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := application.NewURLService(tt.repo)
+			h := NewHandler(service)
+
+			c, w := newTestContext(http.MethodDelete, "/url/abc", nil)
+			c.Params = gin.Params{{Key: "shortcode", Value: "abc"}}
+			h.HandleDeleteLink(c)
+			// c.Status only sets the status on the writer, so flush the headers for the recorder to see it.
+			c.Writer.WriteHeaderNow()
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestHandleLinkQRCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := &mockURLRepository{
+		FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+			if code != "abc" {
+				return nil, urlModel.ErrURLNotFound
+			}
+			return &urlModel.URL{ShortCode: code, OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}, nil
+		},
+	}
+	h := NewHandler(application.NewURLService(repo), WithPublicBaseURL("https://sho.rt"))
+
+	tests := []struct {
+		name                string
+		shortCode           string
+		query               string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{name: "png by default", shortCode: "abc", expectedStatus: http.StatusOK, expectedContentType: "image/png", expectedBody: "\x89PNG"},
+		{name: "svg", shortCode: "abc", query: "?format=svg&size=128&margin=2&level=h&fg=%231f6feb&bg=ffffff00", expectedStatus: http.StatusOK, expectedContentType: "image/svg+xml", expectedBody: `width="128"`},
+		{name: "unknown format", shortCode: "abc", query: "?format=gif", expectedStatus: http.StatusBadRequest},
+		{name: "size out of range", shortCode: "abc", query: "?size=4096", expectedStatus: http.StatusBadRequest},
+		{name: "size not a number", shortCode: "abc", query: "?size=big", expectedStatus: http.StatusBadRequest},
+		{name: "invalid level", shortCode: "abc", query: "?level=X", expectedStatus: http.StatusBadRequest},
+		{name: "invalid color", shortCode: "abc", query: "?fg=red", expectedStatus: http.StatusBadRequest},
+		{name: "not found", shortCode: "missing", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newTestContext(http.MethodGet, "/url/"+tt.shortCode+"/qr"+tt.query, nil)
+			c.Params = gin.Params{{Key: "shortcode", Value: tt.shortCode}}
+			h.HandleLinkQRCode(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+				assert.True(t, strings.Contains(w.Body.String(), tt.expectedBody))
+			}
+		})
+	}
+}
+
+// memoryAttemptCounter counts failed attempts in memory, without windows. A failing counter fails every call.
+type memoryAttemptCounter struct {
+	mu      sync.Mutex
+	counts  map[string]int64
+	failing bool
+}
+
+func newMemoryAttemptCounter() *memoryAttemptCounter {
+	return &memoryAttemptCounter{counts: make(map[string]int64)}
+}
+
+func (m *memoryAttemptCounter) Failures(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failing {
+		return 0, errors.New("connection refused")
+	}
+	return m.counts[key], nil
+}
+
+func (m *memoryAttemptCounter) AddFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failing {
+		return 0, errors.New("connection refused")
+	}
+	m.counts[key]++
+	return m.counts[key], nil
+}
+
+func (m *memoryAttemptCounter) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failing {
+		return errors.New("connection refused")
+	}
+	delete(m.counts, key)
+	return nil
+}
+
+func TestHandleUnlockLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	hash, err := application.HashPassword("correct horse")
+	assert.NoError(t, err)
+	link := urlModel.URL{ShortCode: "abc", OriginalURL: "https://example.com/secret", Expiry: time.Now().Add(time.Hour), PasswordHash: hash}
+	clicks := 0
+	repo := &mockURLRepository{
+		FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+			if code != link.ShortCode {
+				return nil, urlModel.ErrURLNotFound
+			}
+			found := link
+			return &found, nil
+		},
+		IncrementClicksFunc: func(ctx context.Context, shortCode string) (int64, error) {
+			clicks++
+			return int64(clicks), nil
+		},
+	}
+	service := application.NewURLService(repo, application.WithPasswordThrottle(newMemoryAttemptCounter(), 3, time.Minute))
+	h := NewHandler(service, WithLinkUnlock("test secret", time.Hour))
+
+	// get follows the short code with the given cookies, as the redirect route does
+	get := func(path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		c, w := newTestContext(http.MethodGet, path, nil)
+		for _, cookie := range cookies {
+			c.Request.AddCookie(cookie)
+		}
+		c.Params = gin.Params{{Key: "shortcode", Value: strings.TrimPrefix(strings.Split(path, "?")[0], "/api/v1/redirect/")}}
+		h.HandleRedirectToOriginalLink(c)
+		return w
+	}
+	// post enters a password in the prompt of the short code, from the given client address
+	post := func(path, password, client string) *httptest.ResponseRecorder {
+		c, w := newTestContext(http.MethodPost, path, nil)
+		c.Request.Body = io.NopCloser(strings.NewReader(url.Values{"password": {password}}.Encode()))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		c.Request.RemoteAddr = client + ":1234"
+		c.Params = gin.Params{{Key: "shortcode", Value: strings.TrimPrefix(path, "/api/v1/redirect/")}}
+		h.HandleUnlockLink(c)
+		// A redirect without a body only sets the status on the writer, so flush the headers for the recorder to see it.
+		c.Writer.WriteHeaderNow()
+		return w
+	}
+
+	// Without the password, the link and its preview show the prompt and do not reveal the destination
+	for _, path := range []string{"/api/v1/redirect/abc", "/api/v1/redirect/abc+"} {
+		w := get(path)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, path)
+		assert.Contains(t, w.Body.String(), `type="password"`, path)
+		assert.NotContains(t, w.Body.String(), "example.com/secret", path)
+	}
+	assert.Equal(t, 0, clicks)
+
+	// A wrong password shows the prompt again
+	w := post("/api/v1/redirect/abc", "wrong", "10.0.0.1")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Wrong password")
+	assert.Empty(t, w.Result().Cookies())
+
+	// The right password sets the unlock cookie and goes back to the link
+	w = post("/api/v1/redirect/abc", "correct horse", "10.0.0.1")
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/api/v1/redirect/abc", w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, "shortener_unlock_abc", cookies[0].Name)
+		assert.True(t, cookies[0].HttpOnly)
+	}
+
+	// With the cookie, the link redirects and counts the click
+	w = get("/api/v1/redirect/abc", cookies...)
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, link.OriginalURL, w.Header().Get("Location"))
+	assert.Equal(t, 1, clicks)
+	w = get("/api/v1/redirect/abc+", cookies...)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "example.com/secret")
+
+	// A tampered cookie, or one from before the password changed, does not unlock the link
+	tampered := *cookies[0]
+	tampered.Value = strings.Replace(tampered.Value, ".", "0.", 1)
+	assert.Equal(t, http.StatusUnauthorized, get("/api/v1/redirect/abc", &tampered).Code)
+	link.PasswordHash, err = application.HashPassword("battery staple")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, get("/api/v1/redirect/abc", cookies...).Code)
+
+	// After too many wrong passwords, the client cannot try again, even with the right one; other clients still can
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, post("/api/v1/redirect/abc", "wrong", "10.0.0.2").Code)
+	}
+	w = post("/api/v1/redirect/abc", "battery staple", "10.0.0.2")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Empty(t, w.Result().Cookies())
+	assert.Equal(t, http.StatusSeeOther, post("/api/v1/redirect/abc", "battery staple", "10.0.0.3").Code)
+
+	// Unknown short codes are not found
+	assert.Equal(t, http.StatusNotFound, post("/api/v1/redirect/xyz", "battery staple", "10.0.0.1").Code)
+}
+
+// TestUnlockURL_Throttle tests that wrong passwords sent at the same time cannot get past the throttle,
+// and that the throttle fails closed when the attempts cannot be counted.
+func TestUnlockURL_Throttle(t *testing.T) {
+	hash, err := application.HashPassword("correct horse")
+	assert.NoError(t, err)
+	repo := &mockURLRepository{
+		FindByShortCodeFunc: func(ctx context.Context, code string) (*urlModel.URL, error) {
+			return &urlModel.URL{ShortCode: code, OriginalURL: "https://example.com/secret", Expiry: time.Now().Add(time.Hour), PasswordHash: hash}, nil
+		},
+	}
+	counter := newMemoryAttemptCounter()
+	service := application.NewURLService(repo, application.WithPasswordThrottle(counter, 3, time.Minute))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = service.UnlockURL(ctx, "abc", "wrong", "10.0.0.1")
+		}(i)
+	}
+	wg.Wait()
+	wrong := 0
+	for _, err := range errs {
+		if errors.Is(err, urlModel.ErrWrongPassword) {
+			wrong++
+		} else {
+			assert.ErrorIs(t, err, urlModel.ErrTooManyAttempts)
+		}
+	}
+	assert.Equal(t, 3, wrong, "Only 3 passwords should be checked")
+
+	counter.failing = true
+	_, err = service.UnlockURL(ctx, "abc", "correct horse", "10.0.0.2")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, urlModel.ErrWrongPassword)
 }
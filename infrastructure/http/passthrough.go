@@ -0,0 +1,137 @@
+package http
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// errDotSegment is returned for a trailing path with "." or ".." segments, which could climb out of the destination path.
+var errDotSegment = errors.New("the path must not contain . or .. segments")
+
+// reservedParams are the query parameters of the redirect route itself, which are never forwarded.
+var reservedParams = map[string]bool{"preview": true}
+
+// trailingPath escapes the path that follows the short code in a redirect, as given by the catch-all route parameter.
+// Every segment is escaped on its own, so that characters such as "?" and "#" stay part of the path;
+// the segments are the decoded ones, so an escaped "/" separates segments like a plain one.
+// It returns an empty path when nothing follows the short code but a slash.
+func trailingPath(path string) (string, error) {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return "", nil
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "." || segment == ".." {
+			return "", errDotSegment
+		}
+		segments[i] = url.PathEscape(segment)
+	}
+	return "/" + strings.Join(segments, "/"), nil
+}
+
+// forward carries the escaped trailing path and the raw query of a redirect over to its destination,
+// as far as the link forwards them. The destination is returned as it is when there is nothing to forward,
+// or when it cannot be parsed, which does not happen with canonical destinations.
+func forward(destination string, link urlModel.URL, path, rawQuery string) string {
+	if !link.ForwardPath {
+		path = ""
+	}
+	var incoming []queryParam
+	if link.ForwardQuery != "" {
+		for _, param := range parseQuery(rawQuery) {
+			if !reservedParams[param.key] {
+				incoming = append(incoming, param.escaped())
+			}
+		}
+	}
+	if path == "" && len(incoming) == 0 {
+		return destination
+	}
+	target, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+
+	if path != "" {
+		// The escaped path is kept as RawPath, so that escapes such as %2F in the destination survive
+		rawPath := strings.TrimSuffix(target.EscapedPath(), "/") + path
+		decoded, err := url.PathUnescape(rawPath)
+		if err != nil {
+			return destination
+		}
+		target.Path, target.RawPath = decoded, rawPath
+	}
+	if len(incoming) > 0 {
+		target.RawQuery = mergeQuery(parseQuery(target.RawQuery), incoming, link.ForwardQuery)
+	}
+	return target.String()
+}
+
+// queryParam is a parameter of a query string: its decoded key, and the raw key=value pair it was read from.
+type queryParam struct {
+	key   string
+	value string
+	raw   string
+}
+
+// escaped returns the parameter with its raw pair rebuilt from the decoded key and value,
+// so that incoming parameters are forwarded with a well-formed encoding. A key without "=" stays without it.
+func (p queryParam) escaped() queryParam {
+	raw := url.QueryEscape(p.key)
+	if strings.Contains(p.raw, "=") {
+		raw += "=" + url.QueryEscape(p.value)
+	}
+	return queryParam{key: p.key, value: p.value, raw: raw}
+}
+
+// parseQuery splits a raw query string into its parameters, in order.
+// Parameters whose key or value cannot be decoded, or without a key, are dropped.
+func parseQuery(rawQuery string) []queryParam {
+	var params []queryParam
+	for _, pair := range strings.Split(rawQuery, "&") {
+		rawKey, rawValue, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil || key == "" {
+			continue
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			continue
+		}
+		params = append(params, queryParam{key: key, value: value, raw: pair})
+	}
+	return params
+}
+
+// mergeQuery merges the incoming parameters into those of the destination with a forwarding policy,
+// keeping the order of both and the encoding of the destination.
+func mergeQuery(destination, incoming []queryParam, policy string) string {
+	has := func(params []queryParam, key string) bool {
+		for _, param := range params {
+			if param.key == key {
+				return true
+			}
+		}
+		return false
+	}
+
+	var pairs []string
+	for _, param := range destination {
+		// Overridden parameters are dropped from the destination, whatever the number of their values
+		if policy == urlModel.ForwardQueryOverride && has(incoming, param.key) {
+			continue
+		}
+		pairs = append(pairs, param.raw)
+	}
+	for _, param := range incoming {
+		if policy == urlModel.ForwardQueryPreserve && has(destination, param.key) {
+			continue
+		}
+		pairs = append(pairs, param.raw)
+	}
+	return strings.Join(pairs, "&")
+}
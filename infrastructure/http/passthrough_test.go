@@ -0,0 +1,116 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// TestForward tests how the trailing path and the query of a redirect are carried over to the destination.
+func TestForward(t *testing.T) {
+	tests := []struct {
+		name         string
+		destination  string
+		forwardQuery string
+		forwardPath  bool
+		path         string
+		rawQuery     string
+		expected     string
+		expectedErr  bool
+	}{
+		{
+			name:        "nothing to forward",
+			destination: "https://example.com/docs?lang=en",
+			rawQuery:    "ref=mail",
+			path:        "/guide",
+			expected:    "https://example.com/docs?lang=en",
+		},
+		{
+			name:         "preserve keeps the destination values",
+			destination:  "https://example.com/docs?lang=en&v=1",
+			forwardQuery: urlModel.ForwardQueryPreserve,
+			rawQuery:     "lang=de&ref=mail",
+			expected:     "https://example.com/docs?lang=en&v=1&ref=mail",
+		},
+		{
+			name:         "override replaces every value of the destination",
+			destination:  "https://example.com/docs?tag=a&lang=en&tag=b",
+			forwardQuery: urlModel.ForwardQueryOverride,
+			rawQuery:     "tag=c&ref=mail",
+			expected:     "https://example.com/docs?lang=en&tag=c&ref=mail",
+		},
+		{
+			name:         "append keeps both",
+			destination:  "https://example.com/docs?tag=a",
+			forwardQuery: urlModel.ForwardQueryAppend,
+			rawQuery:     "tag=b",
+			expected:     "https://example.com/docs?tag=a&tag=b",
+		},
+		{
+			name:         "incoming parameters are re-encoded",
+			destination:  "https://example.com/docs",
+			forwardQuery: urlModel.ForwardQueryAppend,
+			rawQuery:     "q=a+b%26c&x=<script>&flag&=novalue&bad=%zz",
+			expected:     "https://example.com/docs?q=a+b%26c&x=%3Cscript%3E&flag",
+		},
+		{
+			name:         "preview is not forwarded",
+			destination:  "https://example.com",
+			forwardQuery: urlModel.ForwardQueryAppend,
+			rawQuery:     "preview=false&ref=mail",
+			expected:     "https://example.com?ref=mail",
+		},
+		{
+			name:         "fragment of the destination stays last",
+			destination:  "https://example.com/docs#intro",
+			forwardQuery: urlModel.ForwardQueryPreserve,
+			forwardPath:  true,
+			path:         "/guide",
+			rawQuery:     "ref=mail",
+			expected:     "https://example.com/docs/guide?ref=mail#intro",
+		},
+		{
+			name:        "path segments are escaped",
+			destination: "https://example.com/files/",
+			forwardPath: true,
+			path:        "/a b/100%/what?/#top",
+			expected:    "https://example.com/files/a%20b/100%25/what%3F/%23top",
+		},
+		{
+			name:        "escapes of the destination survive",
+			destination: "https://example.com/a%2Fb",
+			forwardPath: true,
+			path:        "/c",
+			expected:    "https://example.com/a%2Fb/c",
+		},
+		{
+			name:        "destination without a path",
+			destination: "https://example.com",
+			forwardPath: true,
+			path:        "/c/",
+			expected:    "https://example.com/c/",
+		},
+		{
+			name:        "dot segments are refused",
+			destination: "https://example.com/docs",
+			forwardPath: true,
+			path:        "/guide/../../admin",
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := trailingPath(tt.path)
+			if tt.expectedErr {
+				assert.ErrorIs(t, err, errDotSegment)
+				return
+			}
+			assert.NoError(t, err)
+
+			link := urlModel.URL{OriginalURL: tt.destination, ForwardQuery: tt.forwardQuery, ForwardPath: tt.forwardPath}
+			assert.Equal(t, tt.expected, forward(tt.destination, link, path, tt.rawQuery))
+		})
+	}
+}
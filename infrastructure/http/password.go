@@ -0,0 +1,151 @@
+package http
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// unlockCookiePrefix starts the name of the cookie that unlocks a protected link; the short code follows it.
+const unlockCookiePrefix = "shortener_unlock_"
+
+// unlockCookiePath limits the unlock cookies to the redirect route.
+const unlockCookiePath = "/api/v1/redirect/"
+
+// defaultUnlockTTL is how long a visitor can follow a protected link again without its password, unless set with WithLinkUnlock.
+const defaultUnlockTTL = time.Hour
+
+// WithLinkUnlock sets the secret that signs the cookies issued to visitors who entered the password of a protected link,
+// and how long a cookie lets them follow the link again without the password.
+// Every instance of the service must use the same secret; without one, each instance signs with a random secret of its own.
+func WithLinkUnlock(secret string, ttl time.Duration) HandlerOption {
+	return func(h *Handler) {
+		if secret != "" {
+			h.unlockSecret = []byte(secret)
+		}
+		if ttl > 0 {
+			h.unlockTTL = ttl
+		}
+	}
+}
+
+// newUnlockSecret returns a random secret for signing unlock cookies.
+func newUnlockSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("failed to generate the unlock cookie secret: %v", err))
+	}
+	return secret
+}
+
+// passwordPage is the data rendered by the password.html template.
+type passwordPage struct {
+	ShortCode    string
+	ShortenedURL string
+	Error        string
+}
+
+// HandleUnlockLink checks the password entered in the prompt of a protected link.
+// @Summary Checks the password entered for a protected link.
+// @Description NOTE 1: Following a password-protected link shows a prompt, which posts the password here. When it matches, a signed cookie unlocks the link for a while (1 hour by default) and the client is sent back to the link, or to its preview.
+// @Description NOTE 2: Wrong passwords are counted per link and client. After too many, the password cannot be entered from that client until the attempts expire.
+// @Tags REDIRECT
+// @Accept x-www-form-urlencoded
+// @Param shortcode path string true "Short Code, optionally followed by + for a preview"
+// @Param password formData string true "Password of the link"
+// @Produce html
+// @Success 303 {string} string "Unlocked - redirected back to the link"
+// @Failure 400 {string} string "Parameter missing - enter the short code in the URL path"
+// @Failure 401 {string} string "Wrong password - the prompt is shown again"
+// @Failure 404 {string} string "No original URL exists for the given short code"
+// @Failure 429 {string} string "Too many wrong passwords"
+// @Router /redirect/{shortcode} [post]
+func (h *Handler) HandleUnlockLink(c *gin.Context) {
+	shortCode := strings.TrimSuffix(c.Param("shortcode"), "+")
+	if shortCode == "" {
+		c.String(http.StatusBadRequest, "Parameter missing - enter the short code in the URL path")
+		return
+	}
+
+	link, err := h.service.UnlockURL(c, shortCode, c.PostForm("password"), c.ClientIP())
+	switch {
+	case errors.Is(err, urlModel.ErrURLNotFound):
+		c.String(http.StatusNotFound, "No original URL exists for the given short code: %v", err)
+		return
+	case errors.Is(err, urlModel.ErrWrongPassword):
+		h.renderPasswordPrompt(c, http.StatusUnauthorized, shortCode, "Wrong password, please try again.")
+		return
+	case errors.Is(err, urlModel.ErrTooManyAttempts):
+		h.renderPasswordPrompt(c, http.StatusTooManyRequests, shortCode, "Too many wrong passwords. Please try again later.")
+		return
+	case err != nil:
+		c.String(http.StatusInternalServerError, "Failed to check the password: %v", err)
+		return
+	}
+
+	if link.Protected() {
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     unlockCookiePrefix + link.ShortCode,
+			Value:    h.signUnlock(*link, time.Now().Add(h.unlockTTL)),
+			Path:     unlockCookiePath,
+			MaxAge:   int(h.unlockTTL / time.Second),
+			Secure:   strings.HasPrefix(h.publicBaseURL, "https://"),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	// Go back to the page that asked for the password, which is the redirect or the preview
+	c.Redirect(http.StatusSeeOther, c.Request.URL.RequestURI())
+}
+
+// isUnlocked reports whether the request carries a valid unlock cookie for the protected link.
+func (h *Handler) isUnlocked(c *gin.Context, link urlModel.URL) bool {
+	value, err := c.Cookie(unlockCookiePrefix + link.ShortCode)
+	if err != nil {
+		return false
+	}
+	expiresText, _, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(expiresText, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(value), []byte(h.signUnlock(link, time.Unix(expires, 0))))
+}
+
+// signUnlock returns the value of an unlock cookie for the link: its expiry and a signature of it.
+// The signature covers the password hash too, so that changing the password of a link locks it again.
+func (h *Handler) signUnlock(link urlModel.URL, expires time.Time) string {
+	expiresText := strconv.FormatInt(expires.Unix(), 10)
+	mac := hmac.New(sha256.New, h.unlockSecret)
+	mac.Write([]byte(link.ShortCode + "\x00" + expiresText + "\x00" + link.PasswordHash))
+	return expiresText + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// renderPasswordPrompt responds with the page asking for the password of a protected link, with an optional error message.
+func (h *Handler) renderPasswordPrompt(c *gin.Context, status int, shortCode, message string) {
+	page := passwordPage{ShortCode: shortCode, ShortenedURL: h.shortenedURL(h.linkBaseURL(c), shortCode), Error: message}
+	var body bytes.Buffer
+	if err := pageTemplates.ExecuteTemplate(&body, "password.html", page); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to render the password prompt: %v", err)
+		return
+	}
+	// The form posts back to the same URL, and the page must not be framed by other sites
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("Cache-Control", "no-store")
+	c.Data(status, "text/html; charset=utf-8", body.Bytes())
+}
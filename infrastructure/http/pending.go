@@ -0,0 +1,42 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WithPendingLinkPage sets whether scheduled links respond with a "not yet available" page before their activation time.
+// When it is turned off they respond with a plain 404, as if they did not exist yet. The page is shown by default.
+func WithPendingLinkPage(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.pendingPage = enabled
+	}
+}
+
+// pendingPage is the data rendered by the pending.html template.
+type pendingPage struct {
+	ShortCode    string
+	ShortenedURL string
+}
+
+// renderPending responds to a scheduled link that is not active yet with a 404 status.
+// Neither the destination nor the activation time is revealed, so a link can be shared before a launch without spoiling it.
+func (h *Handler) renderPending(c *gin.Context, shortCode string) {
+	c.Header("Cache-Control", "no-store")
+	if !h.pendingPage {
+		c.String(http.StatusNotFound, "No original URL exists for the given short code")
+		return
+	}
+
+	page := pendingPage{ShortCode: shortCode, ShortenedURL: h.shortenedURL(h.linkBaseURL(c), shortCode)}
+	var body bytes.Buffer
+	if err := pageTemplates.ExecuteTemplate(&body, "pending.html", page); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to render the pending page: %v", err)
+		return
+	}
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Data(http.StatusNotFound, "text/html; charset=utf-8", body.Bytes())
+}
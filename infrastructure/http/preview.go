@@ -0,0 +1,101 @@
+package http
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// previewTimeFormat is how times are shown on the preview page.
+const previewTimeFormat = "2006-01-02 15:04 MST"
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// pageTemplates renders the HTML pages of the redirect route, such as the preview and the password prompt.
+// The templates are embedded, so the binary stays self-contained.
+var pageTemplates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// previewPage is the data rendered by the preview.html template.
+type previewPage struct {
+	ShortCode    string
+	ShortenedURL string
+	OriginalURL  string
+	Host         string
+	Title        string
+	Description  string
+	Image        string
+	SiteName     string
+	CreatedBy    string
+	CreatedAt    string
+	Expiry       string
+}
+
+// isPreviewRequest reports whether the redirect route was asked for the preview page with the preview query parameter.
+func isPreviewRequest(c *gin.Context) bool {
+	preview, _ := strconv.ParseBool(c.Query("preview"))
+	return preview
+}
+
+// renderPreview responds with an HTML page describing where a short code leads, instead of redirecting to it.
+// The title and description set on the link take precedence over those fetched from the target page.
+// Viewing a preview does not count as a click; the page links to the redirect, which does.
+// The preview of a protected link would reveal where it leads, so it asks for the password first.
+// A scheduled link is not previewed before its activation time either.
+func (h *Handler) renderPreview(c *gin.Context, shortCode string) {
+	link, err := h.service.ResolveURL(c, shortCode)
+	if err != nil {
+		c.String(http.StatusNotFound, "No original URL exists for the given short code: %v", err)
+		return
+	}
+	if link.Pending(time.Now()) {
+		h.renderPending(c, shortCode)
+		return
+	}
+	if link.Protected() && !h.isUnlocked(c, *link) {
+		h.renderPasswordPrompt(c, http.StatusUnauthorized, shortCode, "")
+		return
+	}
+
+	page := previewPage{
+		ShortCode:    link.ShortCode,
+		ShortenedURL: h.shortenedURL(h.linkBaseURL(c), link.ShortCode),
+		OriginalURL:  link.OriginalURL,
+		Title:        link.Title,
+		Description:  link.Description,
+		CreatedBy:    link.CreatedBy,
+		Expiry:       link.Expiry.UTC().Format(previewTimeFormat),
+	}
+	if parsed, err := url.Parse(link.OriginalURL); err == nil {
+		page.Host = parsed.Host
+	}
+	if !link.CreatedAt.IsZero() {
+		page.CreatedAt = link.CreatedAt.UTC().Format(previewTimeFormat)
+	}
+	if link.Page != nil {
+		page.Image, page.SiteName = link.Page.Image, link.Page.SiteName
+		if page.Title == "" {
+			page.Title = link.Page.Title
+		}
+		if page.Description == "" {
+			page.Description = link.Page.Description
+		}
+	}
+
+	var body bytes.Buffer
+	if err := pageTemplates.ExecuteTemplate(&body, "preview.html", page); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to render the preview: %v", err)
+		return
+	}
+	// The page only needs its inline styles and the image of the target page
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; img-src https: http:")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
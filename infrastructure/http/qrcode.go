@@ -0,0 +1,102 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+	"github.com/terenzio/URL-Shortening-Service/infrastructure/qr"
+)
+
+// qrContentTypes maps the formats of QR codes to the Content-Type of the response.
+var qrContentTypes = map[qr.Format]string{
+	qr.PNG: "image/png",
+	qr.SVG: "image/svg+xml",
+}
+
+// HandleLinkQRCode renders a QR code that encodes the public shortened URL of a short code.
+// @Summary Renders a QR code for a shortened URL.
+// @Description NOTE 1: The code encodes the public shortened URL, so scanning it counts a click like following the link does.
+// @Description NOTE 2: /qr/{shortcode} is public like the redirects, and resolves the short code in the workspace of the domain it is sent to. It is the "qr_code_url" returned by /url/add.
+// @Tags URL
+// @Param shortcode path string true "Short Code"
+// @Param format query string false "Image format: png or svg (default png)"
+// @Param size query int false "Width and height in pixels, between 64 and 2048 (default 256)"
+// @Param margin query int false "Quiet zone around the code in modules, between 0 and 16 (default 4)"
+// @Param level query string false "Error-correction level: L, M, Q or H (default M)"
+// @Param fg query string false "Color of the dark modules as hex, e.g. 000000 or #1f6feb80 (default black)"
+// @Param bg query string false "Color of the light modules as hex (default white)"
+// @Produce png
+// @Produce image/svg+xml
+// @Success 200 {file} binary "QR code"
+// @Failure 400 {object} map[string]string "Invalid rendering options"
+// @Failure 404 {object} map[string]string "No original URL exists for the given short code"
+// @Security ApiKeyAuth
+// @Router /url/{shortcode}/qr [get]
+// @Router /qr/{shortcode} [get]
+func (h *Handler) HandleLinkQRCode(c *gin.Context) {
+	format := qr.Format(c.DefaultQuery("format", string(qr.PNG)))
+	contentType, ok := qrContentTypes[format]
+	if !ok {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Invalid request - format must be png or svg"})
+		return
+	}
+	opts, err := qrOptions(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Invalid request - " + err.Error()})
+		return
+	}
+
+	url, err := h.service.GetURL(c, c.Param("shortcode"))
+	if errors.Is(err, urlModel.ErrURLNotFound) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "No original URL exists for the given short code"})
+		return
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to fetch URL: %v", err)})
+		return
+	}
+
+	image, err := qr.Render(h.shortenedURL(h.linkBaseURL(c), url.ShortCode), format, opts)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Invalid request - " + err.Error()})
+		return
+	}
+	// The shortened URL of a short code never changes, so the image can be cached until the link expires
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Data(http.StatusOK, contentType, image)
+}
+
+// qrOptions reads the rendering options from the query parameters, starting from qr.DefaultOptions.
+func qrOptions(c *gin.Context) (qr.Options, error) {
+	opts := qr.DefaultOptions()
+	var err error
+	if value, ok := c.GetQuery("size"); ok {
+		if opts.Size, err = strconv.Atoi(value); err != nil {
+			return opts, errors.New("size must be a number of pixels")
+		}
+	}
+	if value, ok := c.GetQuery("margin"); ok {
+		if opts.Margin, err = strconv.Atoi(value); err != nil {
+			return opts, errors.New("margin must be a number of modules")
+		}
+	}
+	if value, ok := c.GetQuery("level"); ok {
+		if opts.Level, err = qr.ParseLevel(value); err != nil {
+			return opts, err
+		}
+	}
+	if value, ok := c.GetQuery("fg"); ok {
+		if opts.Foreground, err = qr.ParseColor(value); err != nil {
+			return opts, err
+		}
+	}
+	if value, ok := c.GetQuery("bg"); ok {
+		if opts.Background, err = qr.ParseColor(value); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
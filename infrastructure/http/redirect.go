@@ -0,0 +1,97 @@
+package http
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// permanentRedirectMaxAge is the longest time clients may cache a permanent redirect.
+// Browsers otherwise keep 301 and 308 redirects for good, so a link that is updated or deleted would keep leading to its old destination.
+const permanentRedirectMaxAge = 24 * time.Hour
+
+// WithRedirectType sets how links without a redirect type of their own redirect, e.g. "301" or "interstitial".
+// It must be a valid type; links redirect with a 307 status by default.
+func WithRedirectType(redirectType string) HandlerOption {
+	return func(h *Handler) {
+		h.redirectType = redirectType
+	}
+}
+
+// interstitialPage is the data rendered by the interstitial.html template.
+type interstitialPage struct {
+	Destination string
+	Host        string
+	Nonce       string
+}
+
+// redirect sends the client of a link to its destination, with the redirect type of the link or the default one,
+// and with caching headers that match: permanent redirects may be cached for a while, the others are revalidated every time.
+// Links whose destination depends on the visitor or on a password are only cached privately,
+// and links with a maximum number of clicks are never cached, so that every click is counted.
+func (h *Handler) redirect(c *gin.Context, link urlModel.URL, destination string) {
+	redirectType := link.RedirectType
+	if redirectType == "" {
+		redirectType = h.redirectType
+	}
+
+	visibility := "public"
+	if len(link.Rules) > 0 || len(link.Variants) > 0 || link.Protected() {
+		visibility = "private"
+	}
+	if len(link.Rules) > 0 || len(link.Variants) > 0 {
+		c.Header("Vary", "User-Agent")
+	}
+	maxAge := time.Until(link.Expiry)
+	if maxAge > permanentRedirectMaxAge || link.Expiry.IsZero() {
+		maxAge = permanentRedirectMaxAge
+	}
+	if urlModel.PermanentRedirect(redirectType) && link.MaxClicks == 0 && maxAge >= time.Second {
+		c.Header("Cache-Control", fmt.Sprintf("%s, max-age=%d", visibility, int(maxAge/time.Second)))
+	} else {
+		c.Header("Cache-Control", visibility+", no-cache")
+	}
+
+	if redirectType == urlModel.RedirectInterstitial {
+		h.renderInterstitial(c, destination)
+		return
+	}
+	status, err := strconv.Atoi(redirectType)
+	if err != nil {
+		status = http.StatusTemporaryRedirect
+	}
+	c.Redirect(status, destination)
+}
+
+// renderInterstitial responds with an HTML page that redirects to the destination with a meta refresh and a script,
+// for clients that drop the Location header of redirects, such as some in-app browsers and link scanners.
+// The page also links to the destination, in case neither is followed.
+func (h *Handler) renderInterstitial(c *gin.Context, destination string) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to render the redirect page: %v", err)
+		return
+	}
+	page := interstitialPage{Destination: destination, Nonce: hex.EncodeToString(nonce)}
+	if parsed, err := url.Parse(destination); err == nil {
+		page.Host = parsed.Host
+	}
+
+	var body bytes.Buffer
+	if err := pageTemplates.ExecuteTemplate(&body, "interstitial.html", page); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to render the redirect page: %v", err)
+		return
+	}
+	// Only the inline script carrying the nonce may run
+	c.Header("Content-Security-Policy", fmt.Sprintf("default-src 'none'; style-src 'unsafe-inline'; script-src 'nonce-%s'", page.Nonce))
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
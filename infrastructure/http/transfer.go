@@ -0,0 +1,269 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/terenzio/URL-Shortening-Service/application"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+const (
+	// importBatchSize is the number of CSV rows stored per repository round trip during an import.
+	importBatchSize = 100
+	// maxImportErrors is the number of row errors listed in an import report.
+	maxImportErrors = 1000
+	// exportFlushEvery is the number of rows written between two flushes of an export.
+	exportFlushEvery = 500
+)
+
+// csvHeader is the column layout used by both the import and the export.
+var csvHeader = []string{"short_code", "original_url", "expiry"}
+
+// HandleImportLinks imports URL mappings from a CSV file.
+// @Summary Imports URL mappings from a CSV file.
+// @Description NOTE 1: The CSV columns are short_code, original_url and expiry. A header row with these names is optional.
+// @Description NOTE 2: An empty short_code gets a generated one. An empty expiry defaults to 30 days from now; otherwise it must be an RFC 3339 time in the future, e.g. 2024-04-02T00:00:00Z.
+// @Description NOTE 3: Send the CSV as the raw request body, or as the "file" field of a multipart form. Set dry_run=true to validate the file without storing anything.
+// @Description NOTE 4: Existing short codes are never overwritten; such rows are reported as errors.
+// @Tags URL
+// @Accept text/csv
+// @Accept multipart/form-data
+// @Param dry_run query bool false "Validate only, without storing"
+// @Produce json
+// @Success 200 {object} urlModel.ImportReport "Validation report"
+// @Failure 400 {object} map[string]string "The file could not be read"
+// @Security ApiKeyAuth
+// @Router /url/import [post]
+func (h *Handler) HandleImportLinks(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	// Read the CSV from the multipart file, or from the raw body
+	body := io.Reader(c.Request.Body)
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Bad request - the multipart form must contain a \"file\" field"})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Bad request - failed to open the file: %v", err)})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	report := urlModel.ImportReport{DryRun: dryRun, Errors: []urlModel.ImportRowError{}}
+	addError := func(line int, shortCode string, err error) {
+		report.Failed++
+		if len(report.Errors) >= maxImportErrors {
+			report.Truncated = true
+			return
+		}
+		report.Errors = append(report.Errors, urlModel.ImportRowError{Line: line, ShortCode: shortCode, Error: err.Error()})
+	}
+
+	// Rows are stored in batches as they are read, so the whole file is never held in memory.
+	// A dry run only remembers the short codes it has seen, to report duplicates within the file.
+	var batch []urlModel.URL
+	var batchLines []int
+	seen := make(map[string]bool)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		errs, err := h.service.StoreURLs(c, batch)
+		if err != nil {
+			return err
+		}
+		for i, err := range errs {
+			if err != nil {
+				addError(batchLines[i], batch[i].ShortCode, err)
+			} else {
+				report.Accepted++
+			}
+		}
+		batch, batchLines = batch[:0], batchLines[:0]
+		return nil
+	}
+
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			report.Rows++
+			addError(parseErr.StartLine, "", err)
+			continue
+		} else if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Bad request - failed to read the CSV: %v", err)})
+			return
+		}
+
+		// Skip the optional header row
+		if first && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), csvHeader[0]) {
+			continue
+		}
+		report.Rows++
+
+		url, err := parseImportRecord(record)
+		if err != nil {
+			addError(line, url.ShortCode, err)
+			continue
+		}
+
+		if dryRun {
+			if url.ShortCode != "" {
+				if seen[url.ShortCode] || !h.service.IsUniqueShortCode(c, url.ShortCode) {
+					addError(line, url.ShortCode, fmt.Errorf("%w: %s", urlModel.ErrShortCodeTaken, url.ShortCode))
+					continue
+				}
+				seen[url.ShortCode] = true
+			}
+			report.Accepted++
+			continue
+		}
+
+		batch = append(batch, url)
+		batchLines = append(batchLines, line)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to import URLs: %v", err)})
+				return
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to import URLs: %v", err)})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, report)
+}
+
+// parseImportRecord validates a CSV record and converts it to a URL.
+// The returned URL carries the short code even when the record is invalid, for the error report.
+func parseImportRecord(record []string) (urlModel.URL, error) {
+	if len(record) < 2 || len(record) > len(csvHeader) {
+		return urlModel.URL{}, fmt.Errorf("expected %d columns (%s), got %d", len(csvHeader), strings.Join(csvHeader, ", "), len(record))
+	}
+
+	url := urlModel.URL{
+		ShortCode:   strings.TrimSpace(record[0]),
+		OriginalURL: strings.TrimSpace(record[1]),
+	}
+	if url.ShortCode != "" {
+		if err := application.CheckShortCode(url.ShortCode); err != nil {
+			return url, err
+		}
+	}
+	// Checked here as well as when the URL is stored, so that dry runs report it
+	if _, err := application.Canonicalize(url.OriginalURL, application.CanonicalOptions{}); err != nil {
+		return url, fmt.Errorf("%w: %v", urlModel.ErrInvalidURL, err)
+	}
+
+	var expiry time.Time
+	if len(record) == 3 && strings.TrimSpace(record[2]) != "" {
+		parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(record[2]))
+		if err != nil {
+			return url, fmt.Errorf("invalid expiry - expected an RFC 3339 time like 2024-04-02T00:00:00Z")
+		}
+		if !parsed.After(time.Now()) {
+			return url, fmt.Errorf("invalid expiry - %s is in the past", record[2])
+		}
+		expiry = parsed
+	}
+	url.Expiry = application.AdjustExpiry(expiry)
+
+	return url, nil
+}
+
+// HandleExportLinks streams every URL mapping as CSV or NDJSON.
+// @Summary Exports every URL mapping as CSV or NDJSON.
+// @Description NOTE: The mappings are streamed as they are read from the data store, so the export works for any number of links. The CSV layout is the same as the one accepted by /url/import.
+// @Tags URL
+// @Param format query string false "csv (default) or ndjson" Enums(csv, ndjson)
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Success 200 {string} string "URL mappings"
+// @Failure 400 {object} map[string]string "Unknown format"
+// @Security ApiKeyAuth
+// @Router /url/export [get]
+func (h *Handler) HandleExportLinks(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+
+	var write func(url urlModel.URL) error
+	var flush func() error
+	switch format {
+	case "csv":
+		writer := csv.NewWriter(c.Writer)
+		write = func(url urlModel.URL) error {
+			return writer.Write([]string{url.ShortCode, url.OriginalURL, url.Expiry.Format(time.RFC3339)})
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="links.csv"`)
+		c.Status(http.StatusOK)
+		if err := writer.Write(csvHeader); err != nil {
+			return
+		}
+	case "ndjson":
+		encoder := json.NewEncoder(c.Writer)
+		write = func(url urlModel.URL) error {
+			return encoder.Encode(urlModel.NewURLMapping(url))
+		}
+		flush = func() error { return nil }
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", `attachment; filename="links.ndjson"`)
+		c.Status(http.StatusOK)
+	default:
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Bad request - format must be csv or ndjson"})
+		return
+	}
+
+	// Flush regularly so the client receives the rows while the export is running
+	written := 0
+	err := h.service.EachURL(c, func(url urlModel.URL) error {
+		if err := write(url); err != nil {
+			return err
+		}
+		written++
+		if written%exportFlushEvery == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		// The status has already been sent, so the client only sees a truncated file
+		log.Printf("Error exporting URLs after %d rows: %v", written, err)
+		return
+	}
+	c.Writer.Flush()
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/application"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// TestHandleImportLinks tests the handler that imports URL mappings from CSV.
+func TestHandleImportLinks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	csvBody := strings.Join([]string{
+		"short_code,original_url,expiry",
+		"abc,https://example.com,2999-01-01T00:00:00Z",
+		",https://generated.com,",
+		"bad code,https://example.com,",
+		"def,not-a-url,",
+		"ghi,https://example.com,2000-01-01T00:00:00Z",
+		"abc,https://duplicate.com,",
+		"taken,https://example.com,",
+	}, "\n")
+
+	tests := []struct {
+		name         string
+		path         string
+		wantAccepted int
+		wantFailed   int
+		wantStored   int
+	}{
+		{
+			name:         "dry run",
+			path:         "/url/import?dry_run=true",
+			wantAccepted: 2,
+			wantFailed:   5,
+			wantStored:   0,
+		},
+		{
+			name:         "import",
+			path:         "/url/import",
+			wantAccepted: 2,
+			wantFailed:   5,
+			wantStored:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := 0
+			repo := &mockURLRepository{
+				IsUniqueFunc: func(ctx context.Context, code string) bool { return code != "taken" },
+				StoreBatchFunc: func(ctx context.Context, urls []urlModel.URL) ([]error, error) {
+					errs := make([]error, len(urls))
+					for i, url := range urls {
+						if url.ShortCode == "taken" {
+							errs[i] = urlModel.ErrShortCodeTaken
+							continue
+						}
+						stored++
+					}
+					return errs, nil
+				},
+			}
+			h := NewHandler(application.NewURLService(repo))
+
+			c, w := newTestContext(http.MethodPost, tt.path, []byte(csvBody))
+			c.Request.Header.Set("Content-Type", "text/csv")
+			h.HandleImportLinks(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			var report urlModel.ImportReport
+			err := json.Unmarshal(w.Body.Bytes(), &report)
+			assert.NoError(t, err)
+			assert.Equal(t, 7, report.Rows)
+			assert.Equal(t, tt.wantAccepted, report.Accepted)
+			assert.Equal(t, tt.wantFailed, report.Failed)
+			assert.Len(t, report.Errors, tt.wantFailed)
+			assert.Equal(t, tt.wantStored, stored)
+		})
+	}
+}
+
+// TestHandleExportLinks tests the handler that streams all URL mappings.
+func TestHandleExportLinks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	expiry := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	repo := &mockURLRepository{
+		IterateFunc: func(ctx context.Context, fn func(urlModel.URL) error) error {
+			for _, code := range []string{"abc", "def"} {
+				if err := fn(urlModel.URL{ShortCode: code, OriginalURL: "https://example.com/" + code, Expiry: expiry}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "csv",
+			path:           "/url/export",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "short_code,original_url,expiry\nabc,https://example.com/abc,2030-01-02T03:04:05Z\ndef,https://example.com/def,2030-01-02T03:04:05Z\n",
+		},
+		{
+			name:           "ndjson",
+			path:           "/url/export?format=ndjson",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"short_code":"abc","original_url":"https://example.com/abc","expiry":"2030-01-02T03:04:05Z"}` + "\n" +
+				`{"short_code":"def","original_url":"https://example.com/def","expiry":"2030-01-02T03:04:05Z"}` + "\n",
+		},
+		{
+			name:           "unknown format",
+			path:           "/url/export?format=xml",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(application.NewURLService(repo))
+
+			c, w := newTestContext(http.MethodGet, tt.path, nil)
+			h.HandleExportLinks(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// HandleListUTMPresets lists the UTM presets that links can be tagged with.
+// @Summary Lists the UTM presets, sorted by name.
+// @Tags UTM
+// @Produce json
+// @Success 200 {array} urlModel.UTMPreset "Presets"
+// @Security ApiKeyAuth
+// @Router /utm-presets [get]
+func (h *Handler) HandleListUTMPresets(c *gin.Context) {
+	presets, err := h.service.ListUTMPresets(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to fetch UTM presets: %v", err)})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, presets)
+}
+
+// HandleGetUTMPreset displays a single UTM preset.
+// @Summary Displays a UTM preset.
+// @Tags UTM
+// @Param name path string true "Preset name"
+// @Produce json
+// @Success 200 {object} urlModel.UTMPreset "Preset"
+// @Failure 404 {object} map[string]string "No preset exists for the given name"
+// @Security ApiKeyAuth
+// @Router /utm-presets/{name} [get]
+func (h *Handler) HandleGetUTMPreset(c *gin.Context) {
+	preset, err := h.service.GetUTMPreset(c, c.Param("name"))
+	if errors.Is(err, urlModel.ErrUTMPresetNotFound) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "No UTM preset exists for the given name"})
+		return
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to fetch UTM preset: %v", err)})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, preset)
+}
+
+// HandleSaveUTMPreset creates or replaces a UTM preset.
+// @Summary Creates or replaces a UTM preset.
+// @Description NOTE 1: The name is lowercased and may use up to 64 letters, digits, "_", "-" and ".". Links are tagged with the preset by setting "utm_preset" to its name when they are added.
+// @Description NOTE 2: Every parameter is optional, so that a preset can leave some to each link, e.g. the content. Source, medium and campaign are lowercased.
+// @Description NOTE 3: Links already tagged with a preset keep their parameters when it is changed.
+// @Tags UTM
+// @Accept json
+// @Param name path string true "Preset name"
+// @Param request body urlModel.UTM true "Source, Medium, Campaign, Term and Content"
+// @Produce json
+// @Success 200 {object} urlModel.UTMPreset "Saved preset"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Security ApiKeyAuth
+// @Router /utm-presets/{name} [put]
+func (h *Handler) HandleSaveUTMPreset(c *gin.Context) {
+	var utm urlModel.UTM
+	if err := c.BindJSON(&utm); err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Bad request - a JSON object of UTM parameters is required"})
+		return
+	}
+	if utm.IsZero() {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Bad request - at least one UTM parameter is required"})
+		return
+	}
+
+	preset, err := h.service.SaveUTMPreset(c, c.Param("name"), utm)
+	if errors.Is(err, urlModel.ErrInvalidUTM) {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Invalid request - %v", err)})
+		return
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to save UTM preset: %v", err)})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, preset)
+}
+
+// HandleDeleteUTMPreset removes a UTM preset.
+// @Summary Deletes a UTM preset.
+// @Description NOTE: Links already tagged with the preset keep their parameters.
+// @Tags UTM
+// @Param name path string true "Preset name"
+// @Success 204 "Deleted"
+// @Failure 404 {object} map[string]string "No preset exists for the given name"
+// @Security ApiKeyAuth
+// @Router /utm-presets/{name} [delete]
+func (h *Handler) HandleDeleteUTMPreset(c *gin.Context) {
+	err := h.service.DeleteUTMPreset(c, c.Param("name"))
+	if errors.Is(err, urlModel.ErrUTMPresetNotFound) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "No UTM preset exists for the given name"})
+		return
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to delete UTM preset: %v", err)})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,186 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/application"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// mockUTMPresetRepository keeps UTM presets in memory.
+type mockUTMPresetRepository map[string]urlModel.UTMPreset
+
+func (m mockUTMPresetRepository) SavePreset(ctx context.Context, preset urlModel.UTMPreset) error {
+	m[preset.Name] = preset
+	return nil
+}
+
+func (m mockUTMPresetRepository) FindPreset(ctx context.Context, name string) (*urlModel.UTMPreset, error) {
+	preset, ok := m[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", urlModel.ErrUTMPresetNotFound, name)
+	}
+	return &preset, nil
+}
+
+func (m mockUTMPresetRepository) ListPresets(ctx context.Context) ([]urlModel.UTMPreset, error) {
+	var presets []urlModel.UTMPreset
+	for _, preset := range m {
+		presets = append(presets, preset)
+	}
+	return presets, nil
+}
+
+func (m mockUTMPresetRepository) DeletePreset(ctx context.Context, name string) error {
+	if _, ok := m[name]; !ok {
+		return fmt.Errorf("%w: %s", urlModel.ErrUTMPresetNotFound, name)
+	}
+	delete(m, name)
+	return nil
+}
+
+// TestHandleUTMPresets tests saving, displaying, listing and deleting UTM presets.
+func TestHandleUTMPresets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	presets := mockUTMPresetRepository{}
+	h := NewHandler(application.NewURLService(&mockURLRepository{}, application.WithUTMPresets(presets)))
+
+	save := func(name, body string) (int, urlModel.UTMPreset) {
+		c, w := newTestContext(http.MethodPut, "/utm-presets/"+name, []byte(body))
+		c.Params = gin.Params{{Key: "name", Value: name}}
+		h.HandleSaveUTMPreset(c)
+		var preset urlModel.UTMPreset
+		_ = json.Unmarshal(w.Body.Bytes(), &preset)
+		return w.Code, preset
+	}
+
+	code, preset := save("Spring", `{"source":" Newsletter ","medium":"email","campaign":"Spring_Sale","term":"Running Shoes"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "spring", preset.Name)
+	assert.Equal(t, urlModel.UTM{Source: "newsletter", Medium: "email", Campaign: "spring_sale", Term: "Running Shoes"}, preset.UTM)
+
+	code, _ = save("spring sale", `{"source":"newsletter"}`)
+	assert.Equal(t, http.StatusBadRequest, code, "A name with a space should be refused")
+	code, _ = save("empty", `{}`)
+	assert.Equal(t, http.StatusBadRequest, code, "A preset without parameters should be refused")
+	code, _ = save("control", `{"source":"news\u0000letter"}`)
+	assert.Equal(t, http.StatusBadRequest, code, "Control characters should be refused")
+	code, _ = save("autumn", `{"source":"newsletter","campaign":"autumn"}`)
+	assert.Equal(t, http.StatusOK, code)
+
+	c, w := newTestContext(http.MethodGet, "/utm-presets", nil)
+	h.HandleListUTMPresets(c)
+	var listed []urlModel.UTMPreset
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	if assert.Len(t, listed, 2) {
+		assert.Equal(t, "autumn", listed[0].Name)
+		assert.Equal(t, "spring", listed[1].Name)
+	}
+
+	c, w = newTestContext(http.MethodGet, "/utm-presets/SPRING", nil)
+	c.Params = gin.Params{{Key: "name", Value: "SPRING"}}
+	h.HandleGetUTMPreset(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, expected := range []int{http.StatusNoContent, http.StatusNotFound} {
+		c, w = newTestContext(http.MethodDelete, "/utm-presets/autumn", nil)
+		c.Params = gin.Params{{Key: "name", Value: "autumn"}}
+		h.HandleDeleteUTMPreset(c)
+		c.Writer.WriteHeaderNow()
+		assert.Equal(t, expected, w.Code)
+	}
+}
+
+// TestHandleAddLink_UTM tests that the UTM parameters of a request and of its preset are added to the original URL,
+// after it is canonicalized.
+func TestHandleAddLink_UTM(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	presets := mockUTMPresetRepository{
+		"spring": {Name: "spring", UTM: urlModel.UTM{Source: "newsletter", Medium: "email", Campaign: "spring_sale"}},
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		canonical      application.CanonicalOptions
+		expectedStatus int
+		expectedURL    string
+	}{
+		{
+			name:           "parameters",
+			body:           `{"original_url":"https://example.com/shop?id=1","utm":{"source":"Newsletter","medium":"email","campaign":"spring sale"}}`,
+			expectedStatus: http.StatusOK,
+			expectedURL:    "https://example.com/shop?id=1&utm_source=newsletter&utm_medium=email&utm_campaign=spring+sale",
+		},
+		{
+			name:           "preset",
+			body:           `{"original_url":"https://example.com/shop","utm_preset":"Spring"}`,
+			expectedStatus: http.StatusOK,
+			expectedURL:    "https://example.com/shop?utm_source=newsletter&utm_medium=email&utm_campaign=spring_sale",
+		},
+		{
+			name:           "parameters over the preset",
+			body:           `{"original_url":"https://example.com/shop","utm_preset":"spring","utm":{"medium":"social","content":"Banner A"}}`,
+			expectedStatus: http.StatusOK,
+			expectedURL:    "https://example.com/shop?utm_source=newsletter&utm_medium=social&utm_campaign=spring_sale&utm_content=Banner+A",
+		},
+		{
+			name:           "parameters already in the URL",
+			body:           `{"original_url":"https://example.com/shop?utm_source=old&utm_term=shoes#top","utm_preset":"spring"}`,
+			expectedStatus: http.StatusOK,
+			expectedURL:    "https://example.com/shop?utm_term=shoes&utm_source=newsletter&utm_medium=email&utm_campaign=spring_sale#top",
+		},
+		{
+			name:           "parameters kept when stripping tracking parameters",
+			body:           `{"original_url":"https://example.com/shop?fbclid=abc&utm_source=old&id=1","utm_preset":"spring"}`,
+			canonical:      application.CanonicalOptions{StripParams: []string{"utm_*", "fbclid"}},
+			expectedStatus: http.StatusOK,
+			expectedURL:    "https://example.com/shop?id=1&utm_source=newsletter&utm_medium=email&utm_campaign=spring_sale",
+		},
+		{
+			name:           "parameters sorted with the query",
+			body:           `{"original_url":"https://example.com/shop?utm_term=shoes&id=1","utm_preset":"spring"}`,
+			canonical:      application.CanonicalOptions{SortQuery: true, StripParams: []string{"utm_*"}},
+			expectedStatus: http.StatusOK,
+			expectedURL:    "https://example.com/shop?id=1&utm_campaign=spring_sale&utm_medium=email&utm_source=newsletter",
+		},
+		{
+			name:           "missing campaign",
+			body:           `{"original_url":"https://example.com/shop","utm":{"source":"newsletter","medium":"email"}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown preset",
+			body:           `{"original_url":"https://example.com/shop","utm_preset":"autumn"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored urlModel.URL
+			repo := &mockURLRepository{
+				IsUniqueFunc: func(ctx context.Context, code string) bool { return true },
+				StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+					stored = url
+					return nil
+				},
+			}
+			h := NewHandler(application.NewURLService(repo, application.WithUTMPresets(presets), application.WithCanonicalOptions(tt.canonical)))
+
+			c, w := newTestContext(http.MethodPost, "/url/add", []byte(tt.body))
+			h.HandleAddLink(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedURL != "" {
+				assert.Equal(t, tt.expectedURL, stored.OriginalURL)
+			}
+		})
+	}
+}
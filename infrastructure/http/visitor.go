@@ -0,0 +1,72 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+	"github.com/terenzio/URL-Shortening-Service/infrastructure/useragent"
+)
+
+// visitorCookie is the name of the cookie that identifies a visitor, so that it keeps getting the same variant of a link.
+const visitorCookie = "shortener_visitor"
+
+// visitorCookiePath limits the visitor cookie to the redirect route, where the variants are chosen.
+const visitorCookiePath = "/api/v1/redirect/"
+
+// visitorCookieTTL is how long a visitor keeps its identity, and so its variants.
+const visitorCookieTTL = 365 * 24 * time.Hour
+
+// visitorIDPattern matches the visitor IDs issued in the cookie.
+var visitorIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// CountryLocator finds the country of an IP address, as an ISO 3166-1 alpha-2 code, or an empty string if it is not known.
+type CountryLocator interface {
+	Country(ip net.IP) string
+}
+
+// WithCountryLocator locates the visitors of links by their IP address, for routing rules on countries and for click analytics.
+func WithCountryLocator(locator CountryLocator) HandlerOption {
+	return func(h *Handler) {
+		h.countries = locator
+	}
+}
+
+// visitor describes the client of a request for the routing rules, from its User-Agent header and its IP address.
+// The address is the one gin reports, which only trusts the X-Forwarded-For header of requests from trusted proxies.
+func (h *Handler) visitor(c *gin.Context) urlModel.Visitor {
+	visitor := useragent.Parse(c.Request.UserAgent())
+	if h.countries != nil {
+		visitor.Country = h.countries.Country(net.ParseIP(c.ClientIP()))
+	}
+	return visitor
+}
+
+// visitorID returns the ID of the visitor from its cookie, or issues a new one in the cookie.
+// Clients that do not keep cookies get a new ID, and so possibly another variant, on every visit.
+func (h *Handler) visitorID(c *gin.Context) string {
+	if id, err := c.Cookie(visitorCookie); err == nil && visitorIDPattern.MatchString(id) {
+		return id
+	}
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return ""
+	}
+	id := hex.EncodeToString(random)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     visitorCookie,
+		Value:    id,
+		Path:     visitorCookiePath,
+		MaxAge:   int(visitorCookieTTL / time.Second),
+		Secure:   strings.HasPrefix(h.publicBaseURL, "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/terenzio/URL-Shortening-Service/application"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+type WebhookHandler struct {
+	service *application.WebhookService
+}
+
+// NewWebhookHandler creates a new instance of WebhookHandler
+func NewWebhookHandler(service *application.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// HandleAddWebhook registers a new webhook subscriber for link lifecycle events.
+// @Summary Registers a webhook subscriber for link lifecycle events.
+// @Description NOTE 1: The "events" list is optional. Leave it empty to receive every event: link.created, link.updated, link.deleted, link.expired and link.first_clicked.
+// @Description NOTE 2: The "secret" is optional. When it is left out a secret is generated. The secret is only returned in this response.
+// @Description NOTE 3: Each delivery is signed in the X-Webhook-Signature header as "sha256=" + hex(HMAC-SHA256(secret, X-Webhook-Timestamp + "." + body)).
+// @Tags WEBHOOK
+// @Accept json
+// @Param request body urlModel.AddWebhookRequest true "Subscriber URL, Secret (optional), Events (optional)"
+// @Produce json
+// @Success 201 {object} urlModel.WebhookSubscriber "Registered subscriber"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Security ApiKeyAuth
+// @Router /webhooks [post]
+func (h *WebhookHandler) HandleAddWebhook(c *gin.Context) {
+	var req urlModel.AddWebhookRequest
+	if err := c.BindJSON(&req); err != nil || req.URL == "" {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "Bad request - url is required"})
+		return
+	}
+
+	subscriber, err := h.service.RegisterSubscriber(c, req)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Invalid request - %v", err)})
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, subscriber)
+}
+
+// HandleListWebhooks lists all registered webhook subscribers.
+// @Summary Lists all registered webhook subscribers.
+// @Tags WEBHOOK
+// @Produce json
+// @Success 200 {array} urlModel.WebhookSubscriber "Subscribers"
+// @Security ApiKeyAuth
+// @Router /webhooks [get]
+func (h *WebhookHandler) HandleListWebhooks(c *gin.Context) {
+	subscribers, err := h.service.ListSubscribers(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to fetch webhooks: %v", err)})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, subscribers)
+}
+
+// HandleDeleteWebhook removes a webhook subscriber.
+// @Summary Deletes a webhook subscriber.
+// @Tags WEBHOOK
+// @Param id path string true "Subscriber ID"
+// @Success 204 "Deleted"
+// @Failure 404 {object} map[string]string "No subscriber exists for the given ID"
+// @Security ApiKeyAuth
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) HandleDeleteWebhook(c *gin.Context) {
+	err := h.service.DeleteSubscriber(c, c.Param("id"))
+	if errors.Is(err, urlModel.ErrWebhookNotFound) {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "No subscriber exists for the given ID"})
+		return
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to delete webhook: %v", err)})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
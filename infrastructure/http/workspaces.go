@@ -0,0 +1,135 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// Workspaces sends every request to the handlers of its workspace. Each workspace has handlers of its own,
+// built on the repositories of its keyspace, so the routes are registered once with method expressions, e.g.
+// workspaces.Manage((*Handler).HandleAddLink).
+type Workspaces struct {
+	fallback     workspaceHandlers
+	byID         map[string]workspaceHandlers
+	byDomain     map[string]workspaceHandlers
+	registry     urlModel.DomainRepository
+	sharedDomain string
+}
+
+// workspaceHandlers are the handlers of a workspace.
+type workspaceHandlers struct {
+	links    *Handler
+	webhooks *WebhookHandler
+}
+
+// NewWorkspaces creates a new instance of Workspaces with the handlers of the default workspace,
+// which receive the requests of no other workspace.
+func NewWorkspaces(links *Handler, webhooks *WebhookHandler) *Workspaces {
+	return &Workspaces{
+		fallback: workspaceHandlers{links: links, webhooks: webhooks},
+		byID:     make(map[string]workspaceHandlers),
+		byDomain: make(map[string]workspaceHandlers),
+	}
+}
+
+// Add registers the handlers of a workspace. Redirects sent to its domain, if any, go to the workspace.
+func (w *Workspaces) Add(id, domain string, links *Handler, webhooks *WebhookHandler) {
+	handlers := workspaceHandlers{links: links, webhooks: webhooks}
+	w.byID[id] = handlers
+	if domain != "" {
+		w.byDomain[strings.ToLower(domain)] = handlers
+	}
+}
+
+// UseDomainRegistry makes redirects sent to the verified custom domains of the registry go to their workspace.
+// The domains of the workspaces given to Add take precedence; redirects sent to the shared domain of the service
+// are never looked up in the registry. The shared domain is given as a Host header, possibly with a port.
+func (w *Workspaces) UseDomainRegistry(registry urlModel.DomainRepository, sharedDomain string) {
+	w.registry = registry
+	w.sharedDomain = requestDomain(sharedDomain)
+}
+
+// Manage returns a gin handler that calls the handler method of the workspace of the request's API key.
+func (w *Workspaces) Manage(handle func(*Handler, *gin.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if handlers, ok := w.managing(c); ok {
+			handle(handlers.links, c)
+		}
+	}
+}
+
+// ManageWebhooks is like Manage for the webhook handlers.
+func (w *Workspaces) ManageWebhooks(handle func(*WebhookHandler, *gin.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if handlers, ok := w.managing(c); ok {
+			handle(handlers.webhooks, c)
+		}
+	}
+}
+
+// Redirect returns a gin handler that calls the handler method of the workspace whose domain is the Host of the request,
+// so that a short code is resolved within the workspace of its domain. Requests sent to any other host go to the default workspace.
+func (w *Workspaces) Redirect(handle func(*Handler, *gin.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if handlers, ok := w.redirecting(c); ok {
+			handle(handlers.links, c)
+		}
+	}
+}
+
+// redirecting returns the handlers of the workspace of the request's Host.
+// It responds with a 500 status if the registry of custom domains cannot be read, and with a 404 status if the workspace
+// of a verified domain is not loaded, rather than resolving the short code in another workspace than that of the domain.
+func (w *Workspaces) redirecting(c *gin.Context) (workspaceHandlers, bool) {
+	host := requestDomain(c.Request.Host)
+	if handlers, ok := w.byDomain[host]; ok {
+		return handlers, true
+	}
+	if w.registry == nil || host == w.sharedDomain {
+		return w.fallback, true
+	}
+
+	customDomain, err := w.registry.FindDomain(c, host)
+	if errors.Is(err, urlModel.ErrDomainNotFound) {
+		return w.fallback, true
+	} else if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Failed to resolve the domain: %v", err)})
+		return workspaceHandlers{}, false
+	}
+	if !customDomain.Verified || customDomain.Workspace == "" {
+		return w.fallback, true
+	}
+	handlers, ok := w.byID[customDomain.Workspace]
+	if !ok {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "The domain has no workspace"})
+	}
+	return handlers, ok
+}
+
+// managing returns the handlers of the workspace of the request's API key.
+// It responds with a 500 status if the workspace has no handlers, which means the keys and workspaces are out of sync.
+func (w *Workspaces) managing(c *gin.Context) (workspaceHandlers, bool) {
+	id := requestWorkspace(c)
+	if id == "" {
+		return w.fallback, true
+	}
+	handlers, ok := w.byID[id]
+	if !ok {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Unknown workspace %q", id)})
+	}
+	return handlers, ok
+}
+
+// requestDomain returns the domain of a Host header: lowercased, without the port and the trailing dot.
+func requestDomain(host string) string {
+	if domain, _, err := net.SplitHostPort(host); err == nil {
+		host = domain
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,271 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/application"
+	urlModel "github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// TestWorkspaces tests that management requests go to the workspace of their API key,
+// and redirects to the workspace of their domain.
+func TestWorkspaces(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newWorkspace := func(workspace urlModel.Workspace, destination string) (*Handler, *WebhookHandler) {
+		repo := &mockURLRepository{
+			FindByShortCodeFunc: func(ctx context.Context, shortCode string) (*urlModel.URL, error) {
+				return &urlModel.URL{ShortCode: shortCode, OriginalURL: destination}, nil
+			},
+			CountFunc: func(ctx context.Context) (int64, error) {
+				return int64(len(destination)), nil
+			},
+		}
+		service := application.NewURLService(repo, application.WithWorkspace(workspace))
+		return NewHandler(service), nil
+	}
+	workspaces := NewWorkspaces(newWorkspace(urlModel.Workspace{}, "https://default.example.com"))
+	acme := urlModel.Workspace{ID: "acme", Name: "Acme", Domain: "go.acme.com", Quota: urlModel.Quota{MaxLinks: 100}}
+	acmeLinks, acmeWebhooks := newWorkspace(acme, "https://acme.example.com")
+	workspaces.Add(acme.ID, acme.Domain, acmeLinks, acmeWebhooks)
+
+	router := gin.New()
+	management := router.Group("", WorkspaceAPIKeyAuth(map[string]string{"default-key": "", "acme-key": "acme", "lost-key": "lost"}))
+	management.GET("/workspace", workspaces.Manage((*Handler).HandleWorkspace))
+	management.GET("/url/:shortcode/qr", workspaces.Manage((*Handler).HandleLinkQRCode))
+	router.GET("/redirect/:shortcode", workspaces.Redirect((*Handler).HandleRedirectToOriginalLink))
+	router.GET("/qr/:shortcode", workspaces.Redirect((*Handler).HandleLinkQRCode))
+
+	t.Run("management", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			apiKey         string
+			expectedStatus int
+			expected       urlModel.WorkspaceUsage
+		}{
+			{
+				name:           "default workspace",
+				apiKey:         "default-key",
+				expectedStatus: http.StatusOK,
+				expected:       urlModel.WorkspaceUsage{Links: 27},
+			},
+			{
+				name:           "workspace of the key",
+				apiKey:         "acme-key",
+				expectedStatus: http.StatusOK,
+				expected:       urlModel.WorkspaceUsage{ID: "acme", Name: "Acme", Domain: "go.acme.com", Links: 24, MaxLinks: 100},
+			},
+			{
+				name:           "workspace without handlers",
+				apiKey:         "lost-key",
+				expectedStatus: http.StatusInternalServerError,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, "/workspace", nil)
+				req.Header.Set(APIKeyHeader, tt.apiKey)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tt.expectedStatus, w.Code)
+				if tt.expectedStatus == http.StatusOK {
+					var usage urlModel.WorkspaceUsage
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &usage))
+					assert.Equal(t, tt.expected, usage)
+				}
+			})
+		}
+	})
+
+	t.Run("redirects", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			host     string
+			expected string
+		}{
+			{name: "domain of a workspace", host: "go.acme.com", expected: "https://acme.example.com"},
+			{name: "domain with port and case", host: "Go.Acme.com:8443", expected: "https://acme.example.com"},
+			{name: "fully qualified domain", host: "go.acme.com.", expected: "https://acme.example.com"},
+			{name: "other domain", host: "localhost:9000", expected: "https://default.example.com"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, "/redirect/abc123", nil)
+				req.Host = tt.host
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+				assert.Equal(t, tt.expected, w.Header().Get("Location"))
+			})
+		}
+	})
+
+	t.Run("QR codes", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			path           string
+			expectedStatus int
+		}{
+			{name: "public", path: "/qr/abc123", expectedStatus: http.StatusOK},
+			{name: "management without a key", path: "/url/abc123/qr", expectedStatus: http.StatusUnauthorized},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+				req.Host = "go.acme.com"
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tt.expectedStatus, w.Code)
+				if tt.expectedStatus == http.StatusOK {
+					assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+				}
+			})
+		}
+	})
+}
+
+// TestHandleAddLink_Quota tests that links are refused once the workspace holds its maximum number of links.
+func TestHandleAddLink_Quota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	workspace := urlModel.Workspace{ID: "acme", APIKeys: []string{"acme-key"}, Quota: urlModel.Quota{MaxLinks: 3}}
+
+	tests := []struct {
+		name           string
+		links          int64
+		path           string
+		body           string
+		expectedStatus int
+		wantSucceeded  int
+		wantFailed     int
+	}{
+		{
+			name:           "within the quota",
+			links:          2,
+			path:           "/url/add",
+			body:           `{"original_url":"https://example.com"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "quota used up",
+			links:          3,
+			path:           "/url/add",
+			body:           `{"original_url":"https://example.com"}`,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "bulk beyond the quota",
+			links:          2,
+			path:           "/url/bulk",
+			body:           `[{"original_url":"https://a.com"},{"original_url":"https://b.com"}]`,
+			expectedStatus: http.StatusMultiStatus,
+			wantSucceeded:  1,
+			wantFailed:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockURLRepository{
+				CountFunc: func(ctx context.Context) (int64, error) {
+					return tt.links, nil
+				},
+			}
+			h := NewHandler(application.NewURLService(repo, application.WithWorkspace(workspace)))
+
+			c, w := newTestContext(http.MethodPost, tt.path, []byte(tt.body))
+			if tt.path == "/url/bulk" {
+				h.HandleBulkAddLinks(c)
+			} else {
+				h.HandleAddLink(c)
+			}
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.wantSucceeded+tt.wantFailed > 0 {
+				var resp urlModel.BulkAddURLResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.wantSucceeded, resp.Succeeded)
+				assert.Equal(t, tt.wantFailed, resp.Failed)
+			}
+		})
+	}
+}
+
+// TestWorkspaces_ShortenedURL tests that the shortened URL of a link created in a workspace redirects within that workspace,
+// even when another workspace uses the same short code.
+func TestWorkspaces_ShortenedURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newWorkspace := func(workspace urlModel.Workspace) *Handler {
+		links := make(map[string]urlModel.URL)
+		repo := &mockURLRepository{
+			IsUniqueFunc: func(ctx context.Context, code string) bool {
+				_, ok := links[code]
+				return !ok
+			},
+			StoreNewFunc: func(ctx context.Context, url urlModel.URL) error {
+				links[url.ShortCode] = url
+				return nil
+			},
+			FindByShortCodeFunc: func(ctx context.Context, shortCode string) (*urlModel.URL, error) {
+				url, ok := links[shortCode]
+				if !ok {
+					return nil, urlModel.ErrURLNotFound
+				}
+				return &url, nil
+			},
+		}
+		return NewHandler(application.NewURLService(repo, application.WithWorkspace(workspace)), WithPublicBaseURL("https://sho.rt"))
+	}
+	workspaces := NewWorkspaces(newWorkspace(urlModel.Workspace{}), nil)
+	acme := urlModel.Workspace{ID: "acme", APIKeys: []string{"acme-key"}, Domain: "go.acme.com"}
+	workspaces.Add(acme.ID, acme.Domain, newWorkspace(acme), nil)
+
+	router := gin.New()
+	management := router.Group("/api/v1", WorkspaceAPIKeyAuth(map[string]string{"default-key": "", "acme-key": "acme"}))
+	management.POST("/url/add", workspaces.Manage((*Handler).HandleAddLink))
+	router.GET("/api/v1/redirect/:shortcode", workspaces.Redirect((*Handler).HandleRedirectToOriginalLink))
+
+	addLink := func(apiKey, destination string) string {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/url/add", strings.NewReader(`{"original_url":"`+destination+`","custom_short_code":"spring"}`))
+		req.Header.Set(APIKeyHeader, apiKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp urlModel.AddSuccessResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.ShortenedURL
+	}
+	follow := func(shortenedURL string) string {
+		target, err := url.Parse(shortenedURL)
+		if err != nil {
+			t.Fatalf("invalid shortened URL %q: %v", shortenedURL, err)
+		}
+		req := httptest.NewRequest(http.MethodGet, target.Path, nil)
+		req.Host = target.Host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		return w.Header().Get("Location")
+	}
+
+	acmeURL := addLink("acme-key", "https://acme.example.com/spring")
+	defaultURL := addLink("default-key", "https://example.com/spring")
+	assert.Equal(t, "https://go.acme.com/api/v1/redirect/spring", acmeURL)
+	assert.Equal(t, "https://sho.rt/api/v1/redirect/spring", defaultURL)
+	assert.Equal(t, "https://acme.example.com/spring", follow(acmeURL))
+	assert.Equal(t, "https://example.com/spring", follow(defaultURL))
+}
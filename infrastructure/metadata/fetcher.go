@@ -0,0 +1,215 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+	"golang.org/x/net/html"
+)
+
+const (
+	// defaultMaxBytes is the part of a page that is read when Options.MaxBytes is not set.
+	// The metadata is in the <head>, so large pages do not need to be read to the end.
+	defaultMaxBytes = 1 << 20
+	// maxFieldLength caps every stored field, in characters.
+	maxFieldLength = 500
+	// defaultUserAgent identifies the fetcher to the sites it visits.
+	defaultUserAgent = "URL-Shortening-Service/1.0 (link preview)"
+)
+
+// ErrNoSnippet is returned for pages that ask robots not to index them or show snippets of them,
+// through a robots <meta> tag or an X-Robots-Tag header.
+var ErrNoSnippet = errors.New("page does not allow snippets")
+
+// Options configures a Fetcher.
+type Options struct {
+	// MaxBytes is the number of bytes of a page that are read at most. It defaults to 1 MiB.
+	MaxBytes int64
+	// UserAgent is sent with every request. It defaults to a name for the service.
+	UserAgent string
+}
+
+// Fetcher reads the <title>, the description and the Open Graph tags of web pages.
+// It implements domain.PageFetcher.
+type Fetcher struct {
+	client    *http.Client
+	maxBytes  int64
+	userAgent string
+}
+
+// NewFetcher creates a new instance of Fetcher that requests pages with the given HTTP client.
+// Use NewClient for a client that cannot reach private addresses.
+func NewFetcher(client *http.Client, opts Options) *Fetcher {
+	f := &Fetcher{client: client, maxBytes: opts.MaxBytes, userAgent: opts.UserAgent}
+	if f.maxBytes <= 0 {
+		f.maxBytes = defaultMaxBytes
+	}
+	if f.userAgent == "" {
+		f.userAgent = defaultUserAgent
+	}
+	return f
+}
+
+// Fetch requests an HTML page and returns what its <head> says about it.
+// It fails for responses other than 2xx, for documents that are not HTML, and with ErrNoSnippet
+// for pages that opt out of snippets.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*domain.PageMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q", req.URL.Scheme)
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	for _, value := range resp.Header.Values("X-Robots-Tag") {
+		if disallowsSnippets(value) {
+			return nil, ErrNoSnippet
+		}
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("not an HTML page: %q", mediaType)
+	}
+
+	// Relative image URLs are resolved against the page that was finally served, after redirects
+	page, err := parseHead(io.LimitReader(resp.Body, f.maxBytes), resp.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+	page.FetchedAt = time.Now()
+	return page, nil
+}
+
+// parseHead reads the metadata from the <head> of an HTML document, stopping at the <body>.
+// The Open Graph tags take precedence over the <title> and the description <meta> tag.
+func parseHead(r io.Reader, base *url.URL) (*domain.PageMetadata, error) {
+	var title, description string
+	og := map[string]string{}
+	tokenizer := html.NewTokenizer(r)
+	inTitle := false
+
+parse:
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// The end of the document, or of the part that was read
+			break parse
+		case html.TextToken:
+			if inTitle {
+				title += string(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break parse
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttrs := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = title == ""
+			case "body":
+				break parse
+			case "meta":
+				if !hasAttrs {
+					continue
+				}
+				attrs := attributes(tokenizer)
+				content := attrs["content"]
+				switch key := strings.ToLower(attrs["name"]); {
+				case key == "robots" && disallowsSnippets(content):
+					return nil, ErrNoSnippet
+				case key == "description" && description == "":
+					description = content
+				}
+				if property := strings.ToLower(attrs["property"]); strings.HasPrefix(property, "og:") && og[property] == "" {
+					og[property] = content
+				}
+			}
+		}
+	}
+
+	page := &domain.PageMetadata{
+		Title:       clean(firstNonEmpty(og["og:title"], title)),
+		Description: clean(firstNonEmpty(og["og:description"], description)),
+		SiteName:    clean(og["og:site_name"]),
+	}
+	if image, err := base.Parse(strings.TrimSpace(og["og:image"])); err == nil && og["og:image"] != "" &&
+		(image.Scheme == "http" || image.Scheme == "https") {
+		page.Image = truncate(image.String())
+	}
+	return page, nil
+}
+
+// attributes returns the attributes of the current tag, with lowercase names.
+func attributes(tokenizer *html.Tokenizer) map[string]string {
+	attrs := map[string]string{}
+	for {
+		key, value, more := tokenizer.TagAttr()
+		attrs[strings.ToLower(string(key))] = string(value)
+		if !more {
+			return attrs
+		}
+	}
+}
+
+// disallowsSnippets reports whether a robots directive list, from a <meta> tag or an X-Robots-Tag header,
+// forbids indexing the page or showing a snippet of it. Directives scoped to a user agent count too.
+func disallowsSnippets(directives string) bool {
+	for _, directive := range strings.FieldsFunc(strings.ToLower(directives), func(r rune) bool {
+		return r == ',' || r == ':' || r == ' '
+	}) {
+		switch directive {
+		case "noindex", "none", "nosnippet":
+			return true
+		}
+	}
+	return false
+}
+
+// firstNonEmpty returns the first value that is not blank.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// clean collapses the whitespace of a field and caps its length.
+func clean(value string) string {
+	return truncate(strings.Join(strings.Fields(value), " "))
+}
+
+// truncate caps a field at maxFieldLength characters.
+func truncate(value string) string {
+	if utf8.RuneCountInString(value) <= maxFieldLength {
+		return value
+	}
+	return string([]rune(value)[:maxFieldLength])
+}
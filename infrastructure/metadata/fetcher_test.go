@@ -0,0 +1,166 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// TestFetcher_Fetch tests that the metadata is read from the <head> of the served pages.
+func TestFetcher_Fetch(t *testing.T) {
+	pages := map[string]struct {
+		contentType string
+		robotsTag   string
+		body        string
+	}{
+		"/og": {
+			contentType: "text/html; charset=utf-8",
+			body: `<html><head><title>Fallback</title>
+				<meta property="og:title" content="Open Graph Title">
+				<meta property="og:description" content="Open Graph description">
+				<meta property="og:image" content="/images/cover.png">
+				<meta property="og:site_name" content="Example">
+				<meta name="description" content="Meta description">
+				</head><body><meta property="og:title" content="Ignored"></body></html>`,
+		},
+		"/plain": {
+			contentType: "text/html",
+			body: `<title>  A   plain
+				page </title><meta name="Description" content="Meta description">`,
+		},
+		"/noindex":    {contentType: "text/html", body: `<head><meta name="robots" content="noindex, follow"><title>Secret</title></head>`},
+		"/header":     {contentType: "text/html", robotsTag: "googlebot: nosnippet", body: `<title>Secret</title>`},
+		"/json":       {contentType: "application/json", body: `{"title":"Not a page"}`},
+		"/large":      {contentType: "text/html", body: `<head>` + strings.Repeat("<!-- padding -->", 100) + `<title>Too far</title></head>`},
+		"/long-title": {contentType: "text/html", body: `<title>` + strings.Repeat("a", 2*maxFieldLength) + `</title>`},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/og", http.StatusFound)
+			return
+		}
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", page.contentType)
+		if page.robotsTag != "" {
+			w.Header().Set("X-Robots-Tag", page.robotsTag)
+		}
+		w.Write([]byte(page.body))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(server.Client(), Options{MaxBytes: 1000})
+
+	tests := []struct {
+		name     string
+		path     string
+		wantPage *domain.PageMetadata
+		wantErr  error
+	}{
+		{
+			name: "open graph",
+			path: "/og",
+			wantPage: &domain.PageMetadata{
+				Title:       "Open Graph Title",
+				Description: "Open Graph description",
+				Image:       server.URL + "/images/cover.png",
+				SiteName:    "Example",
+			},
+		},
+		{
+			name: "redirected",
+			path: "/redirect",
+			wantPage: &domain.PageMetadata{
+				Title:       "Open Graph Title",
+				Description: "Open Graph description",
+				Image:       server.URL + "/images/cover.png",
+				SiteName:    "Example",
+			},
+		},
+		{
+			name:     "title and description",
+			path:     "/plain",
+			wantPage: &domain.PageMetadata{Title: "A plain page", Description: "Meta description"},
+		},
+		{
+			name:     "beyond the size limit",
+			path:     "/large",
+			wantPage: &domain.PageMetadata{},
+		},
+		{
+			name:     "long title",
+			path:     "/long-title",
+			wantPage: &domain.PageMetadata{Title: strings.Repeat("a", maxFieldLength)},
+		},
+		{name: "robots meta tag", path: "/noindex", wantErr: ErrNoSnippet},
+		{name: "robots header", path: "/header", wantErr: ErrNoSnippet},
+		{name: "not html", path: "/json"},
+		{name: "not found", path: "/missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := fetcher.Fetch(context.Background(), server.URL+tt.path)
+			if tt.wantPage == nil {
+				assert.Error(t, err)
+				if tt.wantErr != nil {
+					assert.ErrorIs(t, err, tt.wantErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.WithinDuration(t, time.Now(), page.FetchedAt, time.Minute)
+			page.FetchedAt = time.Time{}
+			assert.Equal(t, tt.wantPage, page)
+		})
+	}
+}
+
+// TestNewClient_DeniesPrivateAddresses tests that the client for fetching pages cannot reach the internal network.
+func TestNewClient_DeniesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<title>Internal</title>`))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(NewClient(time.Second), Options{})
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+
+	_, err = fetcher.Fetch(context.Background(), "file:///etc/passwd")
+	assert.Error(t, err)
+
+	tests := []struct {
+		addr       string
+		wantPublic bool
+	}{
+		{addr: "93.184.216.34", wantPublic: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", wantPublic: true},
+		{addr: "127.0.0.1"},
+		{addr: "10.1.2.3"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "169.254.169.254"},
+		{addr: "100.64.0.1"},
+		{addr: "0.0.0.0"},
+		{addr: "::1"},
+		{addr: "fd00::1"},
+		{addr: "fe80::1"},
+		{addr: "::ffff:10.0.0.1"},
+		{addr: "64:ff9b::a00:1"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.wantPublic, isPublic(netip.MustParseAddr(tt.addr)), tt.addr)
+	}
+}
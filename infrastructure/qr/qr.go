@@ -0,0 +1,199 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Format is the image format of a rendered QR code.
+type Format string
+
+const (
+	PNG Format = "png"
+	SVG Format = "svg"
+)
+
+// Level is the error-correction level of a QR code: the share of the code that can be damaged and still be read.
+type Level string
+
+const (
+	LevelLow      Level = "L" // about 7%
+	LevelMedium   Level = "M" // about 15%
+	LevelQuartile Level = "Q" // about 25%
+	LevelHigh     Level = "H" // about 30%
+)
+
+// Limits of the rendering options.
+const (
+	MinSize   = 64
+	MaxSize   = 2048
+	MaxMargin = 16
+)
+
+// recoveryLevels maps the levels to those of the encoder.
+var recoveryLevels = map[Level]qrcode.RecoveryLevel{
+	LevelLow:      qrcode.Low,
+	LevelMedium:   qrcode.Medium,
+	LevelQuartile: qrcode.High,
+	LevelHigh:     qrcode.Highest,
+}
+
+// Options configures how a QR code is rendered.
+type Options struct {
+	// Size is the width and height of the image in pixels, between MinSize and MaxSize.
+	Size int
+	// Margin is the width of the quiet zone around the code, in modules. Scanners expect at least 4.
+	Margin int
+	// Level is the error-correction level.
+	Level Level
+	// Foreground and Background are the colors of the dark and light modules.
+	Foreground color.NRGBA
+	Background color.NRGBA
+}
+
+// DefaultOptions returns options for a black on white, 256 pixel QR code with the standard margin and medium error correction.
+func DefaultOptions() Options {
+	return Options{
+		Size:       256,
+		Margin:     4,
+		Level:      LevelMedium,
+		Foreground: color.NRGBA{A: 0xff},
+		Background: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// Render encodes the content as a QR code and renders it in the given format.
+func Render(content string, format Format, opts Options) ([]byte, error) {
+	if opts.Size < MinSize || opts.Size > MaxSize {
+		return nil, fmt.Errorf("size must be between %d and %d pixels", MinSize, MaxSize)
+	}
+	if opts.Margin < 0 || opts.Margin > MaxMargin {
+		return nil, fmt.Errorf("margin must be between 0 and %d modules", MaxMargin)
+	}
+	level, ok := recoveryLevels[opts.Level]
+	if !ok {
+		return nil, fmt.Errorf("unknown error-correction level %q", opts.Level)
+	}
+
+	code, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+	// The margin is drawn here, so that its width can be chosen
+	code.DisableBorder = true
+	modules := code.Bitmap()
+	if len(modules)+2*opts.Margin > opts.Size {
+		return nil, fmt.Errorf("size is too small for the code, use at least %d pixels", len(modules)+2*opts.Margin)
+	}
+
+	switch format {
+	case PNG:
+		return renderPNG(modules, opts)
+	case SVG:
+		return renderSVG(modules, opts), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// renderPNG draws the modules at a whole number of pixels each, centered in an image of exactly opts.Size pixels.
+// The pixels left over by the rounding widen the margin.
+func renderPNG(modules [][]bool, opts Options) ([]byte, error) {
+	total := len(modules) + 2*opts.Margin
+	scale := opts.Size / total
+	offset := (opts.Size - scale*len(modules)) / 2
+
+	img := image.NewPaletted(image.Rect(0, 0, opts.Size, opts.Size), color.Palette{opts.Background, opts.Foreground})
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for py := 0; py < scale; py++ {
+				for px := 0; px < scale; px++ {
+					img.SetColorIndex(offset+x*scale+px, offset+y*scale+py, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderSVG draws the modules as a single path in a view box measured in modules, scaled to opts.Size pixels.
+// Runs of dark modules on a row are merged, which keeps the document small.
+func renderSVG(modules [][]bool, opts Options) []byte {
+	total := len(modules) + 2*opts.Margin
+	var path strings.Builder
+	for y, row := range modules {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", start+opts.Margin, y+opts.Margin, x-start, x-start)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		opts.Size, opts.Size, total, total)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" %s/>`+"\n", total, total, svgFill(opts.Background))
+	fmt.Fprintf(&buf, `<path d="%s" %s/>`+"\n", path.String(), svgFill(opts.Foreground))
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
+
+// svgFill returns the fill attributes of a color, with its opacity when it is not opaque.
+func svgFill(c color.NRGBA) string {
+	fill := fmt.Sprintf(`fill="#%02x%02x%02x"`, c.R, c.G, c.B)
+	if c.A != 0xff {
+		fill += fmt.Sprintf(` fill-opacity="%s"`, strconv.FormatFloat(float64(c.A)/0xff, 'f', 3, 64))
+	}
+	return fill
+}
+
+// ParseColor parses a hex color: "rgb", "rrggbb" or "rrggbbaa", with or without a leading "#".
+func ParseColor(value string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q: expected a hex color like #1f6feb", value)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q: expected a hex color like #1f6feb", value)
+	}
+	return color.NRGBA{R: uint8(n >> 24), G: uint8(n >> 16), B: uint8(n >> 8), A: uint8(n)}, nil
+}
+
+// ParseLevel parses an error-correction level: L, M, Q or H, in any case.
+func ParseLevel(value string) (Level, error) {
+	level := Level(strings.ToUpper(value))
+	if _, ok := recoveryLevels[level]; !ok {
+		return "", errors.New("invalid error-correction level: expected L, M, Q or H")
+	}
+	return level, nil
+}
@@ -0,0 +1,105 @@
+package qr
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testContent encodes as a version 4 code of 33 modules at the default error-correction level.
+const testContent = "http://localhost:9000/api/v1/redirect/abc123"
+
+// TestRender_PNG tests that PNG codes have the requested size, margin and colors.
+func TestRender_PNG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Size = 300
+	opts.Foreground = color.NRGBA{R: 0x1f, G: 0x6f, B: 0xeb, A: 0xff}
+
+	data, err := Render(testContent, PNG, opts)
+	assert.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, 300, img.Bounds().Dx())
+	assert.Equal(t, 300, img.Bounds().Dy())
+
+	// The code has 33 modules: with the margin, 41 modules of 7 pixels, centered with the 13 spare pixels
+	offset := (300 - 33*7) / 2
+	rgba := func(x, y int) color.NRGBA { return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA) }
+	assert.Equal(t, opts.Background, rgba(0, 0), "The margin should have the background color")
+	assert.Equal(t, opts.Background, rgba(offset-1, offset-1), "The margin should be at least 4 modules wide")
+	assert.Equal(t, opts.Foreground, rgba(offset, offset), "The finder pattern should start after the margin")
+}
+
+// TestRender_SVG tests that SVG codes are scaled to the requested size and use the requested colors.
+func TestRender_SVG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Margin = 0
+	opts.Background = color.NRGBA{R: 0xff, G: 0xff, B: 0xff}
+
+	data, err := Render(testContent, SVG, opts)
+	assert.NoError(t, err)
+	svg := string(data)
+	assert.Contains(t, svg, `width="256" height="256" viewBox="0 0 33 33"`)
+	assert.Contains(t, svg, `fill="#ffffff" fill-opacity="0.000"`, "A transparent background should keep its opacity")
+	assert.Contains(t, svg, `<path d="M0 0h7v1h-7z`, "The finder pattern should start at the corner without a margin")
+	assert.True(t, strings.HasSuffix(svg, "</svg>\n"))
+}
+
+// TestRender_InvalidOptions tests that options out of range are refused.
+func TestRender_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		modify func(*Options)
+	}{
+		{name: "too small", format: PNG, modify: func(o *Options) { o.Size = MinSize - 1 }},
+		{name: "too large", format: PNG, modify: func(o *Options) { o.Size = MaxSize + 1 }},
+		{name: "negative margin", format: SVG, modify: func(o *Options) { o.Margin = -1 }},
+		{name: "wide margin", format: SVG, modify: func(o *Options) { o.Margin = MaxMargin + 1 }},
+		{name: "no room for the code", format: PNG, modify: func(o *Options) { o.Size, o.Margin = MinSize, MaxMargin }},
+		{name: "unknown level", format: PNG, modify: func(o *Options) { o.Level = "X" }},
+		{name: "unknown format", format: "gif", modify: func(o *Options) {}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			tt.modify(&opts)
+			_, err := Render(testContent, tt.format, opts)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestParseColor tests the accepted spellings of colors.
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    color.NRGBA
+		wantErr bool
+	}{
+		{value: "#1f6feb", want: color.NRGBA{R: 0x1f, G: 0x6f, B: 0xeb, A: 0xff}},
+		{value: "1F6FEB", want: color.NRGBA{R: 0x1f, G: 0x6f, B: 0xeb, A: 0xff}},
+		{value: "#fff", want: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+		{value: "#00000080", want: color.NRGBA{A: 0x80}},
+		{value: "red", wantErr: true},
+		{value: "#12345", wantErr: true},
+		{value: "#gggggg", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseColor(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// attemptKeyPrefix prefixes the keys of the failure counters.
+const attemptKeyPrefix = "attempts:"
+
+// addFailureScript increments a counter, and starts its window with the first failure.
+// KEYS[1] is the counter, ARGV[1] the window in milliseconds.
+var addFailureScript = redis.NewScript(`
+local failures = redis.call('INCR', KEYS[1])
+if failures == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return failures
+`)
+
+// AttemptCounter counts failed attempts in Redis, so that every instance of the service sees the same counts.
+// It implements domain.AttemptCounter with fixed windows: a counter expires with all its failures.
+type AttemptCounter struct {
+	client *redis.Client
+	keyspace
+}
+
+// NewAttemptCounter creates a new instance of AttemptCounter.
+func NewAttemptCounter(client *redis.Client, opts ...Option) *AttemptCounter {
+	return &AttemptCounter{client: client, keyspace: newKeyspace(opts)}
+}
+
+// Failures returns the number of failures counted for the key in its current window.
+func (a *AttemptCounter) Failures(ctx context.Context, key string) (int64, error) {
+	failures, err := a.client.Get(ctx, a.key(attemptKeyPrefix+key)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return failures, err
+}
+
+// AddFailure counts a failure for the key and returns the new count.
+func (a *AttemptCounter) AddFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return addFailureScript.Run(ctx, a.client, []string{a.key(attemptKeyPrefix + key)}, window.Milliseconds()).Int64()
+}
+
+// Reset forgets the failures counted for the key.
+func (a *AttemptCounter) Reset(ctx context.Context, key string) error {
+	return a.client.Del(ctx, a.key(attemptKeyPrefix+key)).Err()
+}
@@ -0,0 +1,63 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAttemptCounter tests that failures are counted within a window that starts with the first one
+func TestAttemptCounter(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	counter := NewAttemptCounter(rdb)
+	ctx := context.Background()
+
+	failures, err := counter.Failures(ctx, "password:abc:10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), failures)
+
+	for i := 1; i <= 3; i++ {
+		failures, err = counter.AddFailure(ctx, "password:abc:10.0.0.1", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(i), failures)
+		// Later failures do not extend the window
+		mr.FastForward(10 * time.Second)
+	}
+	failures, err = counter.Failures(ctx, "password:abc:10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), failures)
+
+	// Other keys are counted apart
+	failures, err = counter.Failures(ctx, "password:abc:10.0.0.2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), failures)
+
+	// The failures expire with the window
+	mr.FastForward(30 * time.Second)
+	failures, err = counter.Failures(ctx, "password:abc:10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), failures)
+
+	// Resetting forgets the failures
+	_, err = counter.AddFailure(ctx, "password:abc:10.0.0.1", time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, counter.Reset(ctx, "password:abc:10.0.0.1"))
+	failures, err = counter.Failures(ctx, "password:abc:10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), failures)
+}
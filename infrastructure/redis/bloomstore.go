@@ -0,0 +1,78 @@
+package redis
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// bloomSnapshotKey holds the last persisted Bloom filter of short codes, prefixed with the time it covers.
+	bloomSnapshotKey = "bloom:snapshot"
+	// bloomJournalKey is the sorted set of short codes written since the snapshot, scored by write time (Unix milliseconds).
+	bloomJournalKey = "bloom:journal"
+)
+
+// BloomStore persists the Bloom filter of short codes in Redis, together with a journal of the short codes
+// written since, so that an instance can start from the snapshot without scanning the whole keyspace.
+type BloomStore struct {
+	client *redis.Client
+	keyspace
+}
+
+// NewBloomStore creates a new instance of BloomStore.
+func NewBloomStore(client *redis.Client, opts ...Option) *BloomStore {
+	return &BloomStore{client: client, keyspace: newKeyspace(opts)}
+}
+
+// SaveSnapshot persists the filter data as covering every short code written before coveredUntil,
+// and drops the journal entries older than that from the journal in the same transaction.
+func (s *BloomStore) SaveSnapshot(ctx context.Context, data []byte, coveredUntil time.Time) error {
+	value := binary.BigEndian.AppendUint64(nil, uint64(coveredUntil.UnixMilli()))
+	value = append(value, data...)
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.key(bloomSnapshotKey), value, 0)
+		pipe.ZRemRangeByScore(ctx, s.key(bloomJournalKey), "-inf", "("+strconv.FormatInt(coveredUntil.UnixMilli(), 10))
+		return nil
+	})
+	return err
+}
+
+// LoadSnapshot returns the persisted filter data and the time it covers. The data is nil if nothing was saved yet.
+func (s *BloomStore) LoadSnapshot(ctx context.Context) ([]byte, time.Time, error) {
+	value, err := s.client.Get(ctx, s.key(bloomSnapshotKey)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, time.Time{}, nil
+	} else if err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(value) < 8 {
+		return nil, time.Time{}, errors.New("corrupted Bloom filter snapshot")
+	}
+	return value[8:], time.UnixMilli(int64(binary.BigEndian.Uint64(value))), nil
+}
+
+// Record adds short codes that are about to be written to the journal.
+func (s *BloomStore) Record(ctx context.Context, shortCodes ...string) error {
+	if len(shortCodes) == 0 {
+		return nil
+	}
+	score := float64(time.Now().UnixMilli())
+	members := make([]*redis.Z, len(shortCodes))
+	for i, shortCode := range shortCodes {
+		members[i] = &redis.Z{Score: score, Member: shortCode}
+	}
+	return s.client.ZAdd(ctx, s.key(bloomJournalKey), members...).Err()
+}
+
+// RecordedSince returns the short codes added to the journal at or after the given time.
+func (s *BloomStore) RecordedSince(ctx context.Context, since time.Time) ([]string, error) {
+	return s.client.ZRangeByScore(ctx, s.key(bloomJournalKey), &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+}
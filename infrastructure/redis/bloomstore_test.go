@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBloomStore tests that saving a snapshot trims the journal of the short codes it covers
+func TestBloomStore(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	store := NewBloomStore(rdb)
+	ctx := context.Background()
+
+	// Nothing was saved yet
+	data, _, err := store.LoadSnapshot(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+
+	start := time.Now()
+	assert.NoError(t, store.Record(ctx, "abc", "def"))
+	recorded, err := store.RecordedSince(ctx, start.Add(-time.Second))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"abc", "def"}, recorded)
+
+	// A snapshot covering the recorded short codes drops them from the journal
+	coveredUntil := time.Now().Add(time.Second)
+	assert.NoError(t, store.SaveSnapshot(ctx, []byte("filter"), coveredUntil))
+	recorded, err = store.RecordedSince(ctx, time.Time{})
+	assert.NoError(t, err)
+	assert.Empty(t, recorded)
+
+	data, loadedUntil, err := store.LoadSnapshot(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("filter"), data)
+	assert.Equal(t, coveredUntil.UnixMilli(), loadedUntil.UnixMilli())
+}
@@ -0,0 +1,128 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// domainsKey is the hash of the verified custom domains, by name.
+const domainsKey = "domains"
+
+// claimsKeyPrefix is the prefix of the hashes of the unverified custom domains of each workspace, by name.
+const claimsKeyPrefix = "domainclaims:"
+
+// DomainRepository stores the registry of custom domains. Unlike the other repositories it has no workspace option:
+// the registry maps domains to workspaces, so every workspace shares it.
+type DomainRepository struct {
+	client *redis.Client
+}
+
+// NewDomainRepository creates a new instance of DomainRepository.
+func NewDomainRepository(client *redis.Client) *DomainRepository {
+	return &DomainRepository{client: client}
+}
+
+// SaveDomain stores a custom domain. A verified domain replaces the domain of the same name of any workspace
+// and the claim of its own workspace, at once; an unverified one is stored as a claim of its workspace.
+func (r *DomainRepository) SaveDomain(ctx context.Context, customDomain domain.CustomDomain) error {
+	payload, err := json.Marshal(customDomain)
+	if err != nil {
+		return err
+	}
+	if !customDomain.Verified {
+		return r.client.HSet(ctx, claimsKey(customDomain.Workspace), customDomain.Domain, payload).Err()
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, domainsKey, customDomain.Domain, payload)
+		pipe.HDel(ctx, claimsKey(customDomain.Workspace), customDomain.Domain)
+		return nil
+	})
+	return err
+}
+
+// FindDomain retrieves a verified custom domain by its name.
+func (r *DomainRepository) FindDomain(ctx context.Context, name string) (*domain.CustomDomain, error) {
+	return r.find(ctx, domainsKey, name)
+}
+
+// FindClaim retrieves an unverified custom domain of a workspace by its name.
+func (r *DomainRepository) FindClaim(ctx context.Context, workspace, name string) (*domain.CustomDomain, error) {
+	return r.find(ctx, claimsKey(workspace), name)
+}
+
+// find retrieves a custom domain from a hash by its name.
+func (r *DomainRepository) find(ctx context.Context, key, name string) (*domain.CustomDomain, error) {
+	payload, err := r.client.HGet(ctx, key, name).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrDomainNotFound, name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var customDomain domain.CustomDomain
+	if err := json.Unmarshal([]byte(payload), &customDomain); err != nil {
+		return nil, err
+	}
+	return &customDomain, nil
+}
+
+// ListDomains retrieves the verified custom domains of every workspace, in no particular order.
+func (r *DomainRepository) ListDomains(ctx context.Context) ([]domain.CustomDomain, error) {
+	return r.list(ctx, domainsKey)
+}
+
+// ListClaims retrieves the unverified custom domains of a workspace, in no particular order.
+func (r *DomainRepository) ListClaims(ctx context.Context, workspace string) ([]domain.CustomDomain, error) {
+	return r.list(ctx, claimsKey(workspace))
+}
+
+// list retrieves the custom domains of a hash.
+func (r *DomainRepository) list(ctx context.Context, key string) ([]domain.CustomDomain, error) {
+	payloads, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	domains := make([]domain.CustomDomain, 0, len(payloads))
+	for _, payload := range payloads {
+		var customDomain domain.CustomDomain
+		if err := json.Unmarshal([]byte(payload), &customDomain); err != nil {
+			return nil, err
+		}
+		domains = append(domains, customDomain)
+	}
+	return domains, nil
+}
+
+// DeleteDomain removes a verified custom domain.
+func (r *DomainRepository) DeleteDomain(ctx context.Context, name string) error {
+	return r.delete(ctx, domainsKey, name)
+}
+
+// DeleteClaim removes an unverified custom domain of a workspace.
+func (r *DomainRepository) DeleteClaim(ctx context.Context, workspace, name string) error {
+	return r.delete(ctx, claimsKey(workspace), name)
+}
+
+// delete removes a custom domain from a hash.
+func (r *DomainRepository) delete(ctx context.Context, key, name string) error {
+	deleted, err := r.client.HDel(ctx, key, name).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrDomainNotFound, name)
+	}
+	return nil
+}
+
+// claimsKey returns the key of the hash of the unverified custom domains of a workspace.
+func claimsKey(workspace string) string {
+	return claimsKeyPrefix + workspace
+}
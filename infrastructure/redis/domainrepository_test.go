@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// TestDomainRepository tests claiming, verifying and deleting custom domains
+func TestDomainRepository(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewDomainRepository(rdb)
+	ctx := context.Background()
+
+	_, err = repo.FindDomain(ctx, "go.acme.com")
+	assert.ErrorIs(t, err, domain.ErrDomainNotFound)
+
+	// Unverified domains are claims of their workspace, and several workspaces can claim the same domain
+	claim := domain.CustomDomain{Domain: "go.acme.com", Workspace: "acme", Token: "abc", CreatedAt: time.Now().UTC()}
+	assert.NoError(t, repo.SaveDomain(ctx, claim))
+	assert.NoError(t, repo.SaveDomain(ctx, domain.CustomDomain{Domain: "go.acme.com", Workspace: "other", Token: "def"}))
+	assert.True(t, mr.Exists("domainclaims:acme"))
+	found, err := repo.FindClaim(ctx, "other", "go.acme.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "def", found.Token)
+	_, err = repo.FindDomain(ctx, "go.acme.com")
+	assert.ErrorIs(t, err, domain.ErrDomainNotFound)
+
+	// Verifying a claim makes it the domain of the workspace
+	claim.Verified = true
+	assert.NoError(t, repo.SaveDomain(ctx, claim))
+	found, err = repo.FindDomain(ctx, "go.acme.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", found.Workspace)
+	assert.True(t, found.Verified)
+	_, err = repo.FindClaim(ctx, "acme", "go.acme.com")
+	assert.ErrorIs(t, err, domain.ErrDomainNotFound)
+	claims, err := repo.ListClaims(ctx, "other")
+	assert.NoError(t, err)
+	assert.Len(t, claims, 1)
+
+	// The verified domains are shared by every workspace
+	assert.True(t, mr.Exists("domains"))
+	all, err := repo.ListDomains(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+
+	assert.NoError(t, repo.DeleteDomain(ctx, "go.acme.com"))
+	assert.ErrorIs(t, repo.DeleteDomain(ctx, "go.acme.com"), domain.ErrDomainNotFound)
+	assert.NoError(t, repo.DeleteClaim(ctx, "other", "go.acme.com"))
+	assert.ErrorIs(t, repo.DeleteClaim(ctx, "other", "go.acme.com"), domain.ErrDomainNotFound)
+}
@@ -0,0 +1,86 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// groupsKey is the hash of the link groups, by ID. The links of each group are in a set of their own, see groupLinksPrefix.
+const groupsKey = "groups"
+
+type GroupRepository struct {
+	client *redis.Client
+	keyspace
+}
+
+// NewGroupRepository creates a new instance of GroupRepository.
+func NewGroupRepository(client *redis.Client, opts ...Option) *GroupRepository {
+	return &GroupRepository{client: client, keyspace: newKeyspace(opts)}
+}
+
+// SaveGroup stores a group, replacing any group with the same ID.
+func (r *GroupRepository) SaveGroup(ctx context.Context, group domain.Group) error {
+	payload, err := json.Marshal(group)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(ctx, r.key(groupsKey), group.ID, payload).Err()
+}
+
+// FindGroup retrieves a group by its ID.
+func (r *GroupRepository) FindGroup(ctx context.Context, id string) (*domain.Group, error) {
+	payload, err := r.client.HGet(ctx, r.key(groupsKey), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrGroupNotFound, id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var group domain.Group
+	if err := json.Unmarshal([]byte(payload), &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ListGroups retrieves all groups, in no particular order.
+func (r *GroupRepository) ListGroups(ctx context.Context) ([]domain.Group, error) {
+	payloads, err := r.client.HGetAll(ctx, r.key(groupsKey)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]domain.Group, 0, len(payloads))
+	for _, payload := range payloads {
+		var group domain.Group
+		if err := json.Unmarshal([]byte(payload), &group); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// DeleteGroup removes a group with its member set. The links that are still members keep the ID of the group,
+// so they should be removed from it first.
+func (r *GroupRepository) DeleteGroup(ctx context.Context, id string) error {
+	var deleted *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.HDel(ctx, r.key(groupsKey), id)
+		pipe.Del(ctx, r.key(groupLinksPrefix+id))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if deleted.Val() == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrGroupNotFound, id)
+	}
+	return nil
+}
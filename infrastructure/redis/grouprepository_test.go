@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// TestGroupRepository tests saving, listing and deleting link groups
+func TestGroupRepository(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewGroupRepository(rdb)
+	ctx := context.Background()
+
+	_, err = repo.FindGroup(ctx, "spring")
+	assert.ErrorIs(t, err, domain.ErrGroupNotFound)
+
+	group := domain.Group{ID: "spring", Name: "Spring sale", CreatedAt: time.Now().UTC()}
+	assert.NoError(t, repo.SaveGroup(ctx, group))
+	found, err := repo.FindGroup(ctx, "spring")
+	assert.NoError(t, err)
+	assert.Equal(t, "Spring sale", found.Name)
+	assert.True(t, group.CreatedAt.Equal(found.CreatedAt))
+
+	// Saving a group again replaces it
+	group.Description = "Links of the spring newsletter"
+	assert.NoError(t, repo.SaveGroup(ctx, group))
+	all, err := repo.ListGroups(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, all, 1) {
+		assert.Equal(t, group.Description, all[0].Description)
+	}
+
+	// Deleting a group also deletes its member set
+	assert.NoError(t, NewURLRepository(rdb).Store(ctx, domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour), Group: "spring"}))
+	assert.True(t, mr.Exists("grouplinks:spring"))
+	assert.NoError(t, repo.DeleteGroup(ctx, "spring"))
+	assert.False(t, mr.Exists("grouplinks:spring"))
+	assert.ErrorIs(t, repo.DeleteGroup(ctx, "spring"), domain.ErrGroupNotFound)
+}
@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// invalidationChannel is the Pub/Sub channel that carries invalidated short codes between instances.
+const invalidationChannel = "shortener:invalidate"
+
+// pingInterval is how long a subscription waits for a message before checking that its connection is alive.
+const pingInterval = 30 * time.Second
+
+// Invalidator shares invalidated short codes between the instances of the service through Redis Pub/Sub.
+// Pub/Sub does not store messages, so an instance that is disconnected misses invalidations;
+// caches must therefore still expire their entries on their own.
+type Invalidator struct {
+	client *redis.Client
+	keyspace
+}
+
+// NewInvalidator creates a new instance of Invalidator.
+func NewInvalidator(client *redis.Client, opts ...Option) *Invalidator {
+	return &Invalidator{client: client, keyspace: newKeyspace(opts)}
+}
+
+// Publish announces that the given short codes have changed.
+// The short codes are sent in a single newline-separated message.
+func (i *Invalidator) Publish(ctx context.Context, shortCodes ...string) error {
+	return i.client.Publish(ctx, i.key(invalidationChannel), strings.Join(shortCodes, "\n")).Err()
+}
+
+// Subscribe calls fn for every short code announced by any instance, including this one, until the context is cancelled
+// or the connection fails. It calls subscribed, if set, every time Redis confirms the subscription, before any message
+// that follows it: invalidations published before were missed.
+// A connection that stays quiet for pingInterval is pinged, and fails if the ping is not answered within pingInterval either.
+func (i *Invalidator) Subscribe(ctx context.Context, subscribed func(), fn func(shortCode string)) error {
+	pubsub := i.client.Subscribe(ctx, i.key(invalidationChannel))
+	defer pubsub.Close()
+	// Receiving only notices a cancelled context once its read returns, so close the connection to end it
+	stop := context.AfterFunc(ctx, func() { _ = pubsub.Close() })
+	defer stop()
+
+	pinged := false
+	for {
+		received, err := pubsub.ReceiveTimeout(ctx, pingInterval)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() && !pinged {
+			if err := pubsub.Ping(ctx); err != nil {
+				return err
+			}
+			pinged = true
+			continue
+		} else if err != nil {
+			return err
+		}
+		pinged = false
+
+		switch received := received.(type) {
+		case *redis.Subscription:
+			if subscribed != nil {
+				subscribed()
+			}
+		case *redis.Message:
+			for _, shortCode := range strings.Split(received.Payload, "\n") {
+				if shortCode != "" {
+					fn(shortCode)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package redis
+
+// workspaceKeyPrefix prefixes the keys of every workspace but the default one, followed by the workspace ID and ":".
+const workspaceKeyPrefix = "ws:"
+
+// keyspace is the part of Redis a repository stores its data in: the keys starting with its prefix.
+// The default workspace has an empty prefix, so its keys are those of earlier versions.
+type keyspace struct {
+	prefix string
+}
+
+// key returns the Redis key of the given name in the keyspace.
+func (k keyspace) key(name string) string {
+	return k.prefix + name
+}
+
+// Option configures optional behaviour of the Redis repositories.
+type Option func(*keyspace)
+
+// InWorkspace stores the data of a repository in the keyspace of a workspace, e.g. ws:<id>:short:<code>,
+// so that workspaces can use the same short codes without colliding. An empty ID is the default workspace.
+func InWorkspace(id string) Option {
+	return func(k *keyspace) {
+		if id != "" {
+			k.prefix = workspaceKeyPrefix + id + ":"
+		}
+	}
+}
+
+// newKeyspace returns the keyspace configured by the options.
+func newKeyspace(opts []Option) keyspace {
+	var k keyspace
+	for _, opt := range opts {
+		opt(&k)
+	}
+	return k
+}
@@ -0,0 +1,111 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// TestKeyspace tests that the repositories of different workspaces keep their data apart
+func TestKeyspace(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	ctx := context.Background()
+	defaultRepo := NewURLRepository(rdb)
+	acmeRepo := NewURLRepository(rdb, InWorkspace("acme"))
+	expiry := time.Now().Add(time.Hour)
+
+	// The same short code can be used in every workspace
+	assert.NoError(t, defaultRepo.Store(ctx, domain.URL{ShortCode: "abc123", OriginalURL: "https://default.example.com", Expiry: expiry}))
+	assert.True(t, acmeRepo.IsUnique(ctx, "abc123"))
+	assert.NoError(t, acmeRepo.Store(ctx, domain.URL{ShortCode: "abc123", OriginalURL: "https://acme.example.com", Expiry: expiry, Group: "spring"}))
+	assert.NoError(t, acmeRepo.Store(ctx, domain.URL{ShortCode: "xyz789", OriginalURL: "https://acme.example.com/2", Expiry: expiry}))
+	assert.True(t, mr.Exists("short:abc123"))
+	assert.True(t, mr.Exists("ws:acme:short:abc123"))
+	assert.True(t, mr.Exists("ws:acme:grouplinks:spring"))
+
+	found, err := defaultRepo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://default.example.com", found.OriginalURL)
+	found, err = acmeRepo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://acme.example.com", found.OriginalURL)
+
+	// Listing, counting and clicks stay within the workspace
+	all, err := acmeRepo.FetchAll(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+	count, err := defaultRepo.Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	count, err = acmeRepo.Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	_, err = acmeRepo.IncrementClicks(ctx, "abc123")
+	assert.NoError(t, err)
+	clicks, err := defaultRepo.GetClicks(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), clicks)
+
+	// Deleting a link of a workspace leaves the others alone
+	assert.NoError(t, acmeRepo.Delete(ctx, "abc123"))
+	assert.False(t, mr.Exists("ws:acme:short:abc123"))
+	_, err = defaultRepo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+
+	// The other repositories are namespaced too
+	assert.NoError(t, NewGroupRepository(rdb, InWorkspace("acme")).SaveGroup(ctx, domain.Group{ID: "spring"}))
+	groups, err := NewGroupRepository(rdb).ListGroups(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, groups)
+	assert.True(t, mr.Exists("ws:acme:groups"))
+}
+
+// TestURLRepository_Count tests that only the links that have not expired are counted
+func TestURLRepository_Count(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+	ctx := context.Background()
+
+	count, err := repo.Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	assert.NoError(t, repo.Store(ctx, domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}))
+	assert.NoError(t, repo.Store(ctx, domain.URL{ShortCode: "def456", OriginalURL: "https://example.org", Expiry: time.Now().Add(time.Hour)}))
+	count, err = repo.Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// Expired links no longer count, even before they are swept
+	assert.NoError(t, repo.Expire(ctx, "abc123"))
+	count, err = repo.Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
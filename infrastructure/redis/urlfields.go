@@ -0,0 +1,129 @@
+package redis
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// Fields of the hash a URL is stored in. Empty values are not stored.
+const (
+	fieldURL          = "url"
+	fieldInputURL     = "input_url"
+	fieldTitle        = "title"
+	fieldDescription  = "description"
+	fieldTags         = "tags" // a JSON array
+	fieldNotes        = "notes"
+	fieldCreatedBy    = "created_by"
+	fieldCreatedAt    = "created_at" // RFC 3339 with nanoseconds
+	fieldUpdatedAt    = "updated_at"
+	fieldPage         = "page"     // a JSON object
+	fieldPassword     = "password" // a bcrypt hash
+	fieldMaxClicks    = "max_clicks"
+	fieldActivatesAt  = "activates_at" // RFC 3339 with nanoseconds; the expiry is the TTL of the key
+	fieldRules        = "rules"        // a JSON array
+	fieldVariants     = "variants"     // a JSON array
+	fieldForwardQuery = "forward_query"
+	fieldForwardPath  = "forward_path" // "1" when set
+	fieldRedirectType = "redirect_type"
+	fieldGroup        = "group" // also indexed in the grouplinks:<group> set
+)
+
+// urlFields returns the field/value pairs of the hash a URL is stored in.
+// The short code and the expiry are not part of it, since they are the key and its TTL.
+func urlFields(url domain.URL) []interface{} {
+	fields := []interface{}{fieldURL, url.OriginalURL}
+	add := func(field, value string) {
+		if value != "" {
+			fields = append(fields, field, value)
+		}
+	}
+	add(fieldInputURL, url.InputURL)
+	add(fieldTitle, url.Title)
+	add(fieldDescription, url.Description)
+	if len(url.Tags) > 0 {
+		tags, _ := json.Marshal(url.Tags)
+		add(fieldTags, string(tags))
+	}
+	add(fieldNotes, url.Notes)
+	add(fieldCreatedBy, url.CreatedBy)
+	if !url.CreatedAt.IsZero() {
+		add(fieldCreatedAt, url.CreatedAt.UTC().Format(time.RFC3339Nano))
+	}
+	if !url.UpdatedAt.IsZero() {
+		add(fieldUpdatedAt, url.UpdatedAt.UTC().Format(time.RFC3339Nano))
+	}
+	if url.Page != nil {
+		page, _ := json.Marshal(url.Page)
+		add(fieldPage, string(page))
+	}
+	add(fieldPassword, url.PasswordHash)
+	if !url.ActivatesAt.IsZero() {
+		add(fieldActivatesAt, url.ActivatesAt.UTC().Format(time.RFC3339Nano))
+	}
+	if url.MaxClicks > 0 {
+		add(fieldMaxClicks, strconv.FormatInt(url.MaxClicks, 10))
+	}
+	if len(url.Rules) > 0 {
+		rules, _ := json.Marshal(url.Rules)
+		add(fieldRules, string(rules))
+	}
+	if len(url.Variants) > 0 {
+		variants, _ := json.Marshal(url.Variants)
+		add(fieldVariants, string(variants))
+	}
+	add(fieldForwardQuery, url.ForwardQuery)
+	if url.ForwardPath {
+		add(fieldForwardPath, "1")
+	}
+	add(fieldRedirectType, url.RedirectType)
+	add(fieldGroup, url.Group)
+	return fields
+}
+
+// urlFromFields builds a URL from the fields of its hash. Fields that cannot be decoded are left empty.
+func urlFromFields(shortCode string, fields map[string]string) domain.URL {
+	url := domain.URL{
+		ShortCode:    shortCode,
+		OriginalURL:  fields[fieldURL],
+		InputURL:     fields[fieldInputURL],
+		Title:        fields[fieldTitle],
+		Description:  fields[fieldDescription],
+		Notes:        fields[fieldNotes],
+		CreatedBy:    fields[fieldCreatedBy],
+		PasswordHash: fields[fieldPassword],
+		ForwardQuery: fields[fieldForwardQuery],
+		ForwardPath:  fields[fieldForwardPath] == "1",
+		RedirectType: fields[fieldRedirectType],
+		Group:        fields[fieldGroup],
+	}
+	if tags := fields[fieldTags]; tags != "" {
+		_ = json.Unmarshal([]byte(tags), &url.Tags)
+	}
+	url.MaxClicks, _ = strconv.ParseInt(fields[fieldMaxClicks], 10, 64)
+	url.CreatedAt, _ = time.Parse(time.RFC3339Nano, fields[fieldCreatedAt])
+	url.UpdatedAt, _ = time.Parse(time.RFC3339Nano, fields[fieldUpdatedAt])
+	url.ActivatesAt, _ = time.Parse(time.RFC3339Nano, fields[fieldActivatesAt])
+	if rules := fields[fieldRules]; rules != "" {
+		_ = json.Unmarshal([]byte(rules), &url.Rules)
+	}
+	if variants := fields[fieldVariants]; variants != "" {
+		_ = json.Unmarshal([]byte(variants), &url.Variants)
+	}
+	if page := fields[fieldPage]; page != "" {
+		url.Page = &domain.PageMetadata{}
+		if json.Unmarshal([]byte(page), url.Page) != nil {
+			url.Page = nil
+		}
+	}
+	return url
+}
+
+// isWrongType reports whether a Redis error is caused by a key of another type,
+// such as a link stored as a plain string by earlier versions.
+func isWrongType(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE")
+}
@@ -2,9 +2,13 @@ package redis
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,17 +17,91 @@ import (
 	"github.com/go-redis/redis/v8"
 )
 
+// expiriesKey is the sorted set indexing every short code by its expiry time (Unix seconds).
+// It lets the service find links that have expired, since Redis drops the keys silently.
+const expiriesKey = "expiries"
+
+// replacedExpiriesKey is the list of short codes whose expired link was replaced by a new link before PopExpired
+// returned it, since the new link takes its place in the expiry index.
+const replacedExpiriesKey = "expiries:replaced"
+
+// groupLinksPrefix is the prefix of the sets holding the short codes of each group, followed by the group ID.
+// Links that expire are left in the set until the group is listed.
+const groupLinksPrefix = "grouplinks:"
+
 type URLRepository struct {
 	client *redis.Client
+	keyspace
 }
 
 // NewURLRepository creates a new instance of URLRepository.
-func NewURLRepository(client *redis.Client) *URLRepository {
-	return &URLRepository{client: client}
+func NewURLRepository(client *redis.Client, opts ...Option) *URLRepository {
+	return &URLRepository{client: client, keyspace: newKeyspace(opts)}
+}
+
+// storeScript writes a URL as a hash with its TTL and indexes it by expiry.
+// The hash is replaced as a whole, which also converts a link stored as a plain string by earlier versions.
+// In "nx" mode nothing is written if the short code exists, and 0 is returned.
+// A new link starts without clicks: the click counters of an expired link of the same short code that PopExpired
+// has not returned yet are deleted, and the short code is queued for PopExpired in the list of replaced expiries.
+// If the link is in the destination index, the index entry follows it: it is renewed with the link,
+// or dropped if the original URL changed, since the link no longer points to that destination.
+// The short code is moved from the member set of its previous group, if any, to that of its group.
+//
+// KEYS: short:<code>, expiries, destkey:<code>, input:<code> (the input URL of earlier versions),
+// clicks:<code>, countries:<code>, variants:<code>, expiries:replaced
+// ARGV: "nx" or "", TTL in milliseconds, expiry in Unix seconds, short code, ":" + destination hash,
+// group or "", prefix of the group member sets, followed by the field/value pairs of the hash
+var storeScript = redis.NewScript(`
+local kind = redis.call('TYPE', KEYS[1])['ok']
+if kind == 'none' then
+	redis.call('DEL', KEYS[5], KEYS[6], KEYS[7])
+	if redis.call('ZSCORE', KEYS[2], ARGV[4]) then
+		redis.call('RPUSH', KEYS[8], ARGV[4])
+	end
+elseif ARGV[1] == 'nx' then
+	return 0
+end
+local previous = false
+if kind == 'hash' then
+	previous = redis.call('HGET', KEYS[1], 'group')
+end
+if previous and previous ~= ARGV[6] then
+	redis.call('SREM', ARGV[7] .. previous, ARGV[4])
+end
+redis.call('DEL', KEYS[1], KEYS[4])
+redis.call('HSET', KEYS[1], unpack(ARGV, 8))
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[4])
+if ARGV[6] ~= '' then
+	redis.call('SADD', ARGV[7] .. ARGV[6], ARGV[4])
+end
+local dest = redis.call('GET', KEYS[3])
+if dest then
+	if redis.call('GET', dest) ~= ARGV[4] then
+		redis.call('DEL', KEYS[3])
+	elseif string.sub(dest, -string.len(ARGV[5])) == ARGV[5] then
+		redis.call('PEXPIRE', dest, ARGV[2])
+		redis.call('PEXPIRE', KEYS[3], ARGV[2])
+	else
+		redis.call('DEL', dest, KEYS[3])
+	end
+end
+return 1
+`)
+
+// storeArgs returns the keys and arguments of storeScript for a URL.
+func (r *URLRepository) storeArgs(url domain.URL, ttl time.Duration, mode string) ([]string, []interface{}) {
+	keys := []string{
+		r.key("short:" + url.ShortCode), r.key(expiriesKey), r.key("destkey:" + url.ShortCode), r.key("input:" + url.ShortCode),
+		r.key("clicks:" + url.ShortCode), r.key("countries:" + url.ShortCode), r.key("variants:" + url.ShortCode), r.key(replacedExpiriesKey),
+	}
+	args := []interface{}{mode, ttl.Milliseconds(), url.Expiry.Unix(), url.ShortCode, ":" + destinationHash(url.OriginalURL), url.Group, r.key(groupLinksPrefix)}
+	return keys, append(args, urlFields(url)...)
 }
 
 // Store saves a URL entity to Redis, setting an expiry based on the URL's Expiry field.
-// The short code is used as the key to store the original URL.
+// The short code is used as the key of a hash holding the original URL and the metadata.
 func (r *URLRepository) Store(ctx context.Context, url domain.URL) error {
 	// Calculate the TTL (time-to-live) for the Redis entry based on the URL's expiry.
 	// If the expiry is in the past, return an error.
@@ -32,30 +110,147 @@ func (r *URLRepository) Store(ctx context.Context, url domain.URL) error {
 		return fmt.Errorf("invalid expiry for URL %s", url.OriginalURL)
 	}
 
-	// Use the short code as the key to store the original URL.
-	status := r.client.Set(ctx, "short:"+url.ShortCode, url.OriginalURL, ttl)
-	if status.Err() != nil {
-		return status.Err()
+	// Write the hash and index the short code by its expiry time in the same round trip.
+	keys, args := r.storeArgs(url, ttl, "")
+	return storeScript.Run(ctx, r.client, keys, args...).Err()
+}
+
+// StoreNew saves a new URL entity to Redis like Store, in the same round trip as the check that its short code is unused,
+// so that two writers of the same short code can never overwrite each other.
+// It returns domain.ErrShortCodeTaken if the short code already exists.
+func (r *URLRepository) StoreNew(ctx context.Context, url domain.URL) error {
+	ttl := url.Expiry.Sub(time.Now())
+	if ttl <= 0 {
+		return fmt.Errorf("invalid expiry for URL %s", url.OriginalURL)
 	}
 
+	keys, args := r.storeArgs(url, ttl, "nx")
+	stored, err := storeScript.Run(ctx, r.client, keys, args...).Int()
+	if err != nil {
+		return err
+	}
+	if stored == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrShortCodeTaken, url.ShortCode)
+	}
 	return nil
 }
 
+// StoreBatch saves several URL entities to Redis in two round trips.
+// Unlike Store, it never overwrites an existing short code: a short code that is already taken fails with domain.ErrShortCodeTaken.
+// It returns one error per URL (nil when stored); the second return value is only set when Redis itself failed.
+func (r *URLRepository) StoreBatch(ctx context.Context, urls []domain.URL) ([]error, error) {
+	errs := make([]error, len(urls))
+	cmds := make([]*redis.Cmd, len(urls))
+	now := time.Now()
+
+	// Make sure the script is cached by Redis, so that the pipeline only sends its hash
+	if err := storeScript.Load(ctx, r.client).Err(); err != nil {
+		return nil, err
+	}
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, url := range urls {
+			ttl := url.Expiry.Sub(now)
+			if ttl <= 0 {
+				errs[i] = fmt.Errorf("invalid expiry for URL %s", url.OriginalURL)
+				continue
+			}
+			keys, args := r.storeArgs(url, ttl, "nx")
+			cmds[i] = storeScript.EvalSha(ctx, pipe, keys, args...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, url := range urls {
+		if cmds[i] == nil {
+			continue
+		}
+		if stored, _ := cmds[i].Int(); stored == 0 {
+			errs[i] = fmt.Errorf("%w: %s", domain.ErrShortCodeTaken, url.ShortCode)
+		}
+	}
+	return errs, nil
+}
+
 // FindByShortCode retrieves a URL by its short code from Redis.
+// The expiry is derived from the remaining TTL of the key.
 func (r *URLRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
-	result, err := r.client.Get(ctx, "short:"+shortCode).Result()
-	if errors.Is(err, redis.Nil) {
-		return nil, fmt.Errorf("short code not found: %s", shortCode)
-	} else if err != nil {
+	urls, err := r.findAll(ctx, []string{r.key("short:" + shortCode)})
+	if err != nil {
 		return nil, err
 	}
+	if urls[0] == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrURLNotFound, shortCode)
+	}
+	return urls[0], nil
+}
+
+// findAll reads the URLs stored at the given keys in one round trip, or two if some were stored as plain strings
+// by earlier versions. The result has one entry per key, nil for keys that do not exist.
+func (r *URLRepository) findAll(ctx context.Context, keys []string) ([]*domain.URL, error) {
+	hashes := make([]*redis.StringStringMapCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			hashes[i] = pipe.HGetAll(ctx, key)
+			ttls[i] = pipe.PTTL(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && !isWrongType(err) {
+		return nil, err
+	}
+
+	urls := make([]*domain.URL, len(keys))
+	var legacy []int
+	for i, key := range keys {
+		if isWrongType(hashes[i].Err()) {
+			legacy = append(legacy, i)
+			continue
+		}
+		if len(hashes[i].Val()) == 0 {
+			continue
+		}
+		url := urlFromFields(strings.TrimPrefix(key, r.key("short:")), hashes[i].Val())
+		urls[i] = &url
+	}
+
+	// Links stored as a plain string hold only the original URL, with the input URL in a key of its own
+	if len(legacy) > 0 {
+		gets := make([]*redis.StringCmd, len(keys))
+		inputs := make([]*redis.StringCmd, len(keys))
+		_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, i := range legacy {
+				gets[i] = pipe.Get(ctx, keys[i])
+				inputs[i] = pipe.Get(ctx, r.key("input:"+strings.TrimPrefix(keys[i], r.key("short:"))))
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		for _, i := range legacy {
+			if gets[i].Err() != nil {
+				continue
+			}
+			urls[i] = &domain.URL{ShortCode: strings.TrimPrefix(keys[i], r.key("short:")), OriginalURL: gets[i].Val(), InputURL: inputs[i].Val()}
+		}
+	}
 
-	return &domain.URL{ShortCode: shortCode, OriginalURL: result}, nil
+	now := time.Now()
+	for i, url := range urls {
+		if url != nil && ttls[i].Val() > 0 {
+			url.Expiry = now.Add(ttls[i].Val())
+		}
+	}
+	return urls, nil
 }
 
 // IsUnique checks if a short code is unique by attempting to find it in Redis.
 func (r *URLRepository) IsUnique(ctx context.Context, shortCode string) bool {
-	exists, err := r.client.Exists(ctx, "short:"+shortCode).Result()
+	exists, err := r.client.Exists(ctx, r.key("short:"+shortCode)).Result()
 	if err != nil {
 		log.Printf("Error checking uniqueness in Redis: %v", err)
 		return false
@@ -68,23 +263,393 @@ func (r *URLRepository) IsUnique(ctx context.Context, shortCode string) bool {
 // The original URL and expiry are retrieved for each short code.
 func (r *URLRepository) FetchAll(ctx context.Context) ([]domain.URL, error) {
 	var urls []domain.URL
-	iter := r.client.Scan(ctx, 0, "short:*", 0).Iterator()
-	for iter.Next(ctx) {
-		shortCode := iter.Val()
-		originalURL, err := r.client.Get(ctx, shortCode).Result()
+	err := r.Iterate(ctx, func(url domain.URL) error {
+		urls = append(urls, url)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+// iterateBatchSize is the number of keys requested per SCAN call by Iterate.
+const iterateBatchSize = 100
+
+// Iterate calls fn for every URL in Redis without loading them all into memory.
+// Keys are scanned in batches, and the URLs of each batch are retrieved in one pipeline.
+// Keys that expire while iterating are skipped. Iteration stops at the first error returned by fn.
+func (r *URLRepository) Iterate(ctx context.Context, fn func(domain.URL) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := r.client.Scan(ctx, cursor, r.key("short:*"), iterateBatchSize).Result()
 		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			urls, err := r.findAll(ctx, keys)
+			if err != nil {
+				return err
+			}
+			for _, url := range urls {
+				if url == nil {
+					continue
+				}
+				if err := fn(*url); err != nil {
+					return err
+				}
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// deleteScript removes a URL with its click counters, its expiry, its destination index entry and its group membership.
+// It returns 0 if the short code does not exist.
+//
+// KEYS: short:<code>, clicks:<code>, expiries, destkey:<code>, input:<code>, countries:<code>, variants:<code>
+// ARGV: short code, prefix of the group member sets
+var deleteScript = redis.NewScript(`
+local kind = redis.call('TYPE', KEYS[1])['ok']
+if kind == 'none' then
+	return 0
+end
+if kind == 'hash' then
+	local group = redis.call('HGET', KEYS[1], 'group')
+	if group then
+		redis.call('SREM', ARGV[2] .. group, ARGV[1])
+	end
+end
+redis.call('DEL', KEYS[1])
+redis.call('DEL', KEYS[2], KEYS[5], KEYS[6], KEYS[7])
+redis.call('ZREM', KEYS[3], ARGV[1])
+local dest = redis.call('GET', KEYS[4])
+if dest then
+	if redis.call('GET', dest) == ARGV[1] then
+		redis.call('DEL', dest)
+	end
+	redis.call('DEL', KEYS[4])
+end
+return 1
+`)
+
+// Delete removes a URL and its click counters from Redis.
+// It returns domain.ErrURLNotFound if the short code does not exist.
+func (r *URLRepository) Delete(ctx context.Context, shortCode string) error {
+	keys := []string{
+		r.key("short:" + shortCode), r.key("clicks:" + shortCode), r.key(expiriesKey), r.key("destkey:" + shortCode),
+		r.key("input:" + shortCode), r.key("countries:" + shortCode), r.key("variants:" + shortCode),
+	}
+	deleted, err := deleteScript.Run(ctx, r.client, keys, shortCode, r.key(groupLinksPrefix)).Int()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrURLNotFound, shortCode)
+	}
+	return nil
+}
+
+// expireScript removes a URL like deleteScript, but keeps its click counters and indexes it as expired at the given time,
+// so that PopExpired reports it and removes the counters like for a URL that reached its expiry.
+// It returns 0 if the short code does not exist.
+//
+// KEYS: short:<code>, expiries, destkey:<code>, input:<code>
+// ARGV: short code, expiry in Unix seconds, prefix of the group member sets
+var expireScript = redis.NewScript(`
+local kind = redis.call('TYPE', KEYS[1])['ok']
+if kind == 'none' then
+	return 0
+end
+if kind == 'hash' then
+	local group = redis.call('HGET', KEYS[1], 'group')
+	if group then
+		redis.call('SREM', ARGV[3] .. group, ARGV[1])
+	end
+end
+redis.call('DEL', KEYS[1], KEYS[4])
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[1])
+local dest = redis.call('GET', KEYS[3])
+if dest then
+	if redis.call('GET', dest) == ARGV[1] then
+		redis.call('DEL', dest)
+	end
+	redis.call('DEL', KEYS[3])
+end
+return 1
+`)
+
+// Expire makes a URL expire at once. Its click counters are kept until PopExpired returns it.
+// It returns domain.ErrURLNotFound if the short code does not exist.
+func (r *URLRepository) Expire(ctx context.Context, shortCode string) error {
+	keys := []string{r.key("short:" + shortCode), r.key(expiriesKey), r.key("destkey:" + shortCode), r.key("input:" + shortCode)}
+	expired, err := expireScript.Run(ctx, r.client, keys, shortCode, time.Now().Unix(), r.key(groupLinksPrefix)).Int()
+	if err != nil {
+		return err
+	}
+	if expired == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrURLNotFound, shortCode)
+	}
+	return nil
+}
+
+// FindByGroup retrieves the live URLs of a group from the member set of the group.
+// Members whose link has expired are removed from the set on the way.
+func (r *URLRepository) FindByGroup(ctx context.Context, group string) ([]domain.URL, error) {
+	codes, err := r.client.SMembers(ctx, r.key(groupLinksPrefix+group)).Result()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(codes))
+	for i, code := range codes {
+		keys[i] = r.key("short:" + code)
+	}
+	found, err := r.findAll(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := []domain.URL{}
+	var stale []interface{}
+	for i, url := range found {
+		if url == nil {
+			stale = append(stale, codes[i])
+			continue
+		}
+		// A link moved to another group by a write that raced with this read is no longer a member
+		if url.Group == group {
+			urls = append(urls, *url)
+		}
+	}
+	if len(stale) > 0 {
+		if err := r.client.SRem(ctx, r.key(groupLinksPrefix+group), stale...).Err(); err != nil {
 			return nil, err
 		}
-		ttl, err := r.client.TTL(ctx, shortCode).Result()
+	}
+	return urls, nil
+}
+
+// Count returns the number of live URLs from the expiry index, without scanning the keyspace.
+func (r *URLRepository) Count(ctx context.Context) (int64, error) {
+	return r.client.ZCount(ctx, r.key(expiriesKey), fmt.Sprintf("(%d", time.Now().Unix()), "+inf").Result()
+}
+
+// indexDestinationScript points a destination to a short code, unless it already points to a live one,
+// and records the entry next to the link so that it can be renewed and removed with it. Both expire with the link.
+//
+// KEYS: dest:<owner>:<hash>, destkey:<code>
+// ARGV: short code, TTL in milliseconds
+var indexDestinationScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	redis.call('SET', KEYS[2], KEYS[1], 'PX', ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// IndexDestination records the URL as the link of its original URL for the owner,
+// unless the owner already has a live link to the same original URL.
+func (r *URLRepository) IndexDestination(ctx context.Context, owner string, url domain.URL) error {
+	ttl := url.Expiry.Sub(time.Now())
+	if ttl <= 0 {
+		return fmt.Errorf("invalid expiry for URL %s", url.OriginalURL)
+	}
+	keys := []string{r.destinationKey(owner, url.OriginalURL), r.key("destkey:" + url.ShortCode)}
+	return indexDestinationScript.Run(ctx, r.client, keys, url.ShortCode, ttl.Milliseconds()).Err()
+}
+
+// FindByDestination retrieves the link of the owner that was indexed for the original URL.
+// It returns domain.ErrURLNotFound if there is none.
+func (r *URLRepository) FindByDestination(ctx context.Context, owner, originalURL string) (*domain.URL, error) {
+	shortCode, err := r.client.Get(ctx, r.destinationKey(owner, originalURL)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrURLNotFound, originalURL)
+	} else if err != nil {
+		return nil, err
+	}
+
+	url, err := r.FindByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	// Guard against a link whose original URL changed without going through Store
+	if url.OriginalURL != originalURL {
+		return nil, fmt.Errorf("%w: %s", domain.ErrURLNotFound, originalURL)
+	}
+	return url, nil
+}
+
+// destinationKey returns the key of the destination index entry of an original URL for an owner.
+func (r *URLRepository) destinationKey(owner, originalURL string) string {
+	return r.key("dest:" + owner + ":" + destinationHash(originalURL))
+}
+
+// destinationHash returns the hex encoded SHA-256 hash of an original URL, which keeps the index keys short.
+func destinationHash(originalURL string) string {
+	sum := sha256.Sum256([]byte(originalURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// setPageScript stores the page metadata of a link, unless its original URL changed since the page was fetched.
+// It returns 0 if the link does not exist, and -1 if it was not stored because the link changed
+// or is stored as a plain string by earlier versions.
+//
+// KEYS: short:<code>
+// ARGV: original URL the page was fetched from, page metadata as JSON
+var setPageScript = redis.NewScript(`
+local kind = redis.call('TYPE', KEYS[1])['ok']
+if kind == 'none' then
+	return 0
+end
+if kind ~= 'hash' or redis.call('HGET', KEYS[1], 'url') ~= ARGV[1] then
+	return -1
+end
+redis.call('HSET', KEYS[1], 'page', ARGV[2])
+return 1
+`)
+
+// SetPageMetadata stores the metadata fetched from the target page of a link, without touching its other fields.
+// It does nothing if the original URL of the link changed since, and returns domain.ErrURLNotFound if the link is gone.
+func (r *URLRepository) SetPageMetadata(ctx context.Context, shortCode, originalURL string, page domain.PageMetadata) error {
+	data, err := json.Marshal(page)
+	if err != nil {
+		return err
+	}
+	stored, err := setPageScript.Run(ctx, r.client, []string{r.key("short:" + shortCode)}, originalURL, data).Int()
+	if err != nil {
+		return err
+	}
+	if stored == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrURLNotFound, shortCode)
+	}
+	return nil
+}
+
+// IncrementClicks increments the click counter of a short code and returns the new count.
+func (r *URLRepository) IncrementClicks(ctx context.Context, shortCode string) (int64, error) {
+	return r.client.Incr(ctx, r.key("clicks:"+shortCode)).Result()
+}
+
+// incrementClicksUpToScript increments a click counter unless it already reached the limit, in which case it returns -1.
+// KEYS[1] is the counter and ARGV[1] the limit. Running as a script, the check and the increment cannot be interleaved with other clicks.
+var incrementClicksUpToScript = redis.NewScript(`
+local clicks = tonumber(redis.call('GET', KEYS[1]) or '0')
+if clicks >= tonumber(ARGV[1]) then
+	return -1
+end
+return redis.call('INCR', KEYS[1])
+`)
+
+// IncrementClicksUpTo increments the click counter of a short code unless it already reached maxClicks, and returns the new count.
+// It returns domain.ErrClickLimitReached once the limit is reached.
+func (r *URLRepository) IncrementClicksUpTo(ctx context.Context, shortCode string, maxClicks int64) (int64, error) {
+	clicks, err := incrementClicksUpToScript.Run(ctx, r.client, []string{r.key("clicks:" + shortCode)}, maxClicks).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if clicks < 0 {
+		return 0, fmt.Errorf("%w: %s", domain.ErrClickLimitReached, shortCode)
+	}
+	return clicks, nil
+}
+
+// GetClicks returns the number of clicks counted for a short code.
+func (r *URLRepository) GetClicks(ctx context.Context, shortCode string) (int64, error) {
+	clicks, err := r.client.Get(ctx, r.key("clicks:"+shortCode)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return clicks, err
+}
+
+// CountClickCountry counts a click on a short code in the hash of its clicks per country.
+func (r *URLRepository) CountClickCountry(ctx context.Context, shortCode, country string) error {
+	return r.client.HIncrBy(ctx, r.key("countries:"+shortCode), country, 1).Err()
+}
+
+// GetClickCountries returns the number of clicks counted per country for a short code.
+func (r *URLRepository) GetClickCountries(ctx context.Context, shortCode string) (map[string]int64, error) {
+	return r.getClickCounts(ctx, r.key("countries:"+shortCode))
+}
+
+// CountClickVariant counts a click on a short code in the hash of its clicks per variant URL.
+func (r *URLRepository) CountClickVariant(ctx context.Context, shortCode, variantURL string) error {
+	return r.client.HIncrBy(ctx, r.key("variants:"+shortCode), variantURL, 1).Err()
+}
+
+// GetClickVariants returns the number of clicks counted per variant URL for a short code.
+func (r *URLRepository) GetClickVariants(ctx context.Context, shortCode string) (map[string]int64, error) {
+	return r.getClickCounts(ctx, r.key("variants:"+shortCode))
+}
+
+// getClickCounts reads a hash of click counts.
+func (r *URLRepository) getClickCounts(ctx context.Context, key string) (map[string]int64, error) {
+	fields, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(fields))
+	for field, value := range fields {
+		clicks, err := strconv.ParseInt(value, 10, 64)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid click count %q for %s in %s: %w", value, field, key, err)
 		}
-		shortCode = strings.TrimPrefix(shortCode, "short:")
-		urls = append(urls, domain.URL{ShortCode: shortCode, OriginalURL: originalURL, Expiry: time.Now().Add(ttl)})
+		counts[field] = clicks
 	}
-	if err := iter.Err(); err != nil {
+	return counts, nil
+}
+
+// PopExpired returns the short codes whose expiry time is before the given time and removes them from the expiry index,
+// along with the short codes whose expired link was replaced by a new one.
+// Each short code is returned by exactly one caller, even when several instances sweep at the same time,
+// because only the caller whose ZREM succeeds claims it, and the list of replaced expiries is emptied atomically.
+func (r *URLRepository) PopExpired(ctx context.Context, before time.Time) ([]string, error) {
+	var replaced *redis.StringSliceCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		replaced = pipe.LRange(ctx, r.key(replacedExpiriesKey), 0, -1)
+		pipe.Del(ctx, r.key(replacedExpiriesKey))
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	expired := replaced.Val()
 
-	return urls, nil
+	candidates, err := r.client.ZRangeByScore(ctx, r.key(expiriesKey), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: fmt.Sprintf("%d", before.Unix()),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, shortCode := range candidates {
+		// Skip short codes whose key has not been dropped by Redis yet.
+		exists, err := r.client.Exists(ctx, r.key("short:"+shortCode)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if exists > 0 {
+			continue
+		}
+		removed, err := r.client.ZRem(ctx, r.key(expiriesKey), shortCode).Result()
+		if err != nil {
+			return nil, err
+		}
+		if removed == 0 {
+			continue
+		}
+		if err := r.client.Del(ctx, r.key("clicks:"+shortCode), r.key("countries:"+shortCode), r.key("variants:"+shortCode)).Err(); err != nil {
+			return nil, err
+		}
+		expired = append(expired, shortCode)
+	}
+
+	return expired, nil
 }
@@ -2,6 +2,10 @@ package redis
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -66,11 +70,8 @@ func TestURLRepository_Store(t *testing.T) {
 				assert.NoError(t, err, "Unexpected error for test case: %s", tt.name)
 
 				// Verify data is stored only if no error is expected
-				stored, err := mr.Get("short:" + tt.shortCode)
-				if !tt.wantErr { // Check only if we don't expect an error
-					assert.NoError(t, err, "Retrieving data from miniredis should not produce an error for test case: %s", tt.name)
-					assert.Equal(t, tt.originalURL, stored, "Stored URL should match for test case: %s", tt.name)
-				}
+				stored := mr.HGet("short:"+tt.shortCode, "url")
+				assert.Equal(t, tt.originalURL, stored, "Stored URL should match for test case: %s", tt.name)
 			}
 		})
 	}
@@ -236,3 +237,709 @@ func TestURLRepository_FetchAll(t *testing.T) {
 		})
 	}
 }
+
+// TestURLRepository_Delete tests the Delete method of URLRepository
+func TestURLRepository_Delete(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+	ctx := context.Background()
+
+	err = repo.Store(ctx, domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+	_, err = repo.IncrementClicks(ctx, "abc123")
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.Delete(ctx, "abc123"))
+	assert.False(t, mr.Exists("short:abc123"), "The URL should be removed")
+	assert.False(t, mr.Exists("clicks:abc123"), "The click counter should be removed")
+	assert.ErrorIs(t, repo.Delete(ctx, "abc123"), domain.ErrURLNotFound)
+}
+
+// TestURLRepository_IncrementClicksUpTo tests that concurrent clicks cannot exceed the maximum number of clicks
+func TestURLRepository_IncrementClicksUpTo(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var counted []int64
+	limited := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clicks, err := repo.IncrementClicksUpTo(ctx, "abc123", 5)
+			mu.Lock()
+			defer mu.Unlock()
+			if errors.Is(err, domain.ErrClickLimitReached) {
+				limited++
+				return
+			}
+			assert.NoError(t, err)
+			counted = append(counted, clicks)
+		}()
+	}
+	wg.Wait()
+
+	assert.ElementsMatch(t, []int64{1, 2, 3, 4, 5}, counted, "Every click up to the limit should be counted once")
+	assert.Equal(t, 15, limited)
+	clicks, err := repo.GetClicks(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), clicks)
+
+	// Raising the limit lets the link be followed again
+	clicks, err = repo.IncrementClicksUpTo(ctx, "abc123", 6)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), clicks)
+}
+
+// TestURLRepository_ClickCountries tests that clicks are counted per country, and removed with the link
+func TestURLRepository_ClickCountries(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+	ctx := context.Background()
+
+	countries, err := repo.GetClickCountries(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Empty(t, countries)
+
+	assert.NoError(t, repo.Store(ctx, domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}))
+	for _, country := range []string{"DE", "FR", "DE"} {
+		assert.NoError(t, repo.CountClickCountry(ctx, "abc123", country))
+	}
+	countries, err = repo.GetClickCountries(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"DE": 2, "FR": 1}, countries)
+
+	assert.NoError(t, repo.Delete(ctx, "abc123"))
+	assert.False(t, mr.Exists("countries:abc123"), "The clicks per country should be deleted with the link")
+}
+
+// TestURLRepository_ClickVariants tests that clicks are counted per variant, and removed with the link
+func TestURLRepository_ClickVariants(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+	ctx := context.Background()
+
+	assert.NoError(t, repo.Store(ctx, domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}))
+	for _, variant := range []string{"https://example.com/a", "https://example.com/b", "https://example.com/a"} {
+		assert.NoError(t, repo.CountClickVariant(ctx, "abc123", variant))
+	}
+	variants, err := repo.GetClickVariants(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"https://example.com/a": 2, "https://example.com/b": 1}, variants)
+
+	assert.NoError(t, repo.Delete(ctx, "abc123"))
+	assert.False(t, mr.Exists("variants:abc123"), "The clicks per variant should be deleted with the link")
+}
+
+// TestURLRepository_PopExpired tests the PopExpired method of URLRepository
+func TestURLRepository_PopExpired(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+	ctx := context.Background()
+
+	err = repo.Store(ctx, domain.URL{ShortCode: "short1", OriginalURL: "https://example1.com", Expiry: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+	err = repo.Store(ctx, domain.URL{ShortCode: "long1", OriginalURL: "https://example2.com", Expiry: time.Now().Add(48 * time.Hour)})
+	assert.NoError(t, err)
+
+	// Nothing has expired yet
+	expired, err := repo.PopExpired(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+
+	// Let miniredis drop the first key, then sweep as if two hours had passed
+	mr.FastForward(2 * time.Hour)
+	expired, err = repo.PopExpired(ctx, time.Now().Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"short1"}, expired)
+
+	// A short code is only claimed once
+	expired, err = repo.PopExpired(ctx, time.Now().Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+}
+
+// TestURLRepository_Expire tests that an expired URL stops resolving, and is popped with its click counters
+func TestURLRepository_Expire(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+	ctx := context.Background()
+
+	url := domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}
+	assert.NoError(t, repo.Store(ctx, url))
+	assert.NoError(t, repo.IndexDestination(ctx, "", url))
+	_, err = repo.IncrementClicks(ctx, "abc123")
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.Expire(ctx, "abc123"))
+	_, err = repo.FindByShortCode(ctx, "abc123")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	_, err = repo.FindByDestination(ctx, "", "https://example.com")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound, "The destination index entry should be dropped")
+	assert.True(t, mr.Exists("clicks:abc123"), "The clicks should be kept until the link is swept")
+	assert.ErrorIs(t, repo.Expire(ctx, "abc123"), domain.ErrURLNotFound)
+
+	expired, err := repo.PopExpired(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc123"}, expired)
+	assert.False(t, mr.Exists("clicks:abc123"))
+}
+
+// TestURLRepository_Recreate tests that a link created again after its short code expired starts without clicks,
+// even before the expired link is swept
+func TestURLRepository_Recreate(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+	ctx := context.Background()
+
+	url := domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour), MaxClicks: 1}
+	assert.NoError(t, repo.StoreNew(ctx, url))
+	_, err = repo.IncrementClicksUpTo(ctx, "abc123", 1)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.CountClickCountry(ctx, "abc123", "DE"))
+	assert.NoError(t, repo.Expire(ctx, "abc123"))
+
+	url.OriginalURL = "https://example.org"
+	assert.NoError(t, repo.StoreNew(ctx, url))
+	countries, err := repo.GetClickCountries(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Empty(t, countries)
+	clicks, err := repo.IncrementClicksUpTo(ctx, "abc123", 1)
+	assert.NoError(t, err, "The new link should not inherit the clicks of the expired one")
+	assert.Equal(t, int64(1), clicks)
+
+	// The expired link is still reported once, and the new one is not
+	expired, err := repo.PopExpired(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc123"}, expired)
+	expired, err = repo.PopExpired(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+
+	// Updating the live link keeps its clicks
+	url.Title = "Updated"
+	assert.NoError(t, repo.Store(ctx, url))
+	clicks, err = repo.GetClicks(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), clicks)
+}
+
+// TestURLRepository_Groups tests that the member set of each group follows the group of its links
+func TestURLRepository_Groups(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+	ctx := context.Background()
+	members := func(group string) []string {
+		urls, err := repo.FindByGroup(ctx, group)
+		assert.NoError(t, err)
+		var codes []string
+		for _, url := range urls {
+			codes = append(codes, url.ShortCode)
+		}
+		sort.Strings(codes)
+		return codes
+	}
+
+	for _, code := range []string{"a", "b", "c"} {
+		assert.NoError(t, repo.Store(ctx, domain.URL{ShortCode: code, OriginalURL: "https://example.com/" + code, Expiry: time.Now().Add(time.Hour), Group: "spring"}))
+	}
+	assert.NoError(t, repo.Store(ctx, domain.URL{ShortCode: "short", OriginalURL: "https://example.com/short", Expiry: time.Now().Add(time.Minute), Group: "spring"}))
+	assert.Equal(t, []string{"a", "b", "c", "short"}, members("spring"))
+
+	// Moving a link to another group, or out of any group, updates both sets
+	assert.NoError(t, repo.Store(ctx, domain.URL{ShortCode: "a", OriginalURL: "https://example.com/a", Expiry: time.Now().Add(time.Hour), Group: "autumn"}))
+	assert.NoError(t, repo.Store(ctx, domain.URL{ShortCode: "b", OriginalURL: "https://example.com/b", Expiry: time.Now().Add(time.Hour)}))
+	assert.Equal(t, []string{"a"}, members("autumn"))
+
+	// Deleted and expired links leave their group
+	assert.NoError(t, repo.Delete(ctx, "a"))
+	assert.Empty(t, members("autumn"))
+	mr.FastForward(2 * time.Minute)
+	assert.Equal(t, []string{"c"}, members("spring"))
+	isMember, err := rdb.SIsMember(ctx, "grouplinks:spring", "short").Result()
+	assert.NoError(t, err)
+	assert.False(t, isMember, "Members whose link expired should be removed when the group is listed")
+	assert.NoError(t, repo.Expire(ctx, "c"))
+	assert.Empty(t, members("spring"))
+	assert.False(t, mr.Exists("grouplinks:spring"))
+}
+
+// TestURLRepository_StoreBatch tests the StoreBatch method of URLRepository
+func TestURLRepository_StoreBatch(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+
+	// Prepopulate Redis with a short code that is already taken
+	mr.Set("short:taken", "https://existing.com")
+
+	urls := []domain.URL{
+		{ShortCode: "new1", OriginalURL: "https://example1.com", Expiry: time.Now().Add(time.Hour)},
+		{ShortCode: "taken", OriginalURL: "https://example2.com", Expiry: time.Now().Add(time.Hour)},
+		{ShortCode: "past", OriginalURL: "https://example3.com", Expiry: time.Now().Add(-time.Hour)},
+	}
+	errs, err := repo.StoreBatch(context.Background(), urls)
+	assert.NoError(t, err)
+	assert.NoError(t, errs[0], "A free short code should be stored")
+	assert.ErrorIs(t, errs[1], domain.ErrShortCodeTaken, "A taken short code should not be overwritten")
+	assert.Error(t, errs[2], "An expiry in the past should be rejected")
+
+	assert.Equal(t, "https://example1.com", mr.HGet("short:new1", "url"))
+	existing, _ := mr.Get("short:taken")
+	assert.Equal(t, "https://existing.com", existing)
+	assert.False(t, mr.Exists("short:past"))
+}
+
+// TestURLRepository_StoreNew tests that a new URL never overwrites an existing short code
+func TestURLRepository_StoreNew(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+	ctx := context.Background()
+
+	assert.NoError(t, repo.StoreNew(ctx, domain.URL{ShortCode: "abc123", OriginalURL: "https://first.com", Expiry: time.Now().Add(time.Hour)}))
+	err = repo.StoreNew(ctx, domain.URL{ShortCode: "abc123", OriginalURL: "https://second.com", Expiry: time.Now().Add(time.Hour)})
+	assert.ErrorIs(t, err, domain.ErrShortCodeTaken)
+	assert.Equal(t, "https://first.com", mr.HGet("short:abc123", "url"))
+
+	assert.Error(t, repo.StoreNew(ctx, domain.URL{ShortCode: "past", OriginalURL: "https://example.com", Expiry: time.Now().Add(-time.Hour)}))
+	assert.False(t, mr.Exists("short:past"))
+}
+
+// TestURLRepository_Iterate tests the Iterate method of URLRepository
+func TestURLRepository_Iterate(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+
+	// Prepopulate Redis with more URLs than a single SCAN batch returns
+	for i := 0; i < 2*iterateBatchSize+5; i++ {
+		mr.Set(fmt.Sprintf("short:code%d", i), fmt.Sprintf("https://example%d.com", i))
+		mr.SetTTL(fmt.Sprintf("short:code%d", i), time.Hour)
+	}
+
+	seen := map[string]bool{}
+	err = repo.Iterate(context.Background(), func(url domain.URL) error {
+		assert.False(t, seen[url.ShortCode], "Every URL should be visited once")
+		assert.NotEmpty(t, url.OriginalURL)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), url.Expiry, time.Minute)
+		seen[url.ShortCode] = true
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, seen, 2*iterateBatchSize+5)
+}
+
+// TestURLRepository_DestinationIndex tests that the destination index follows its link through renewals, changes, deletion and expiry
+func TestURLRepository_DestinationIndex(t *testing.T) {
+	tests := []struct {
+		name      string
+		change    func(t *testing.T, repo *URLRepository, mr *miniredis.Miniredis, url domain.URL)
+		wantFound bool
+	}{
+		{
+			name:      "indexed",
+			change:    func(t *testing.T, repo *URLRepository, mr *miniredis.Miniredis, url domain.URL) {},
+			wantFound: true,
+		},
+		{
+			name: "renewed",
+			change: func(t *testing.T, repo *URLRepository, mr *miniredis.Miniredis, url domain.URL) {
+				url.Expiry = url.Expiry.Add(2 * time.Hour)
+				assert.NoError(t, repo.Store(context.Background(), url))
+				mr.FastForward(90 * time.Minute)
+			},
+			wantFound: true,
+		},
+		{
+			name: "original URL changed",
+			change: func(t *testing.T, repo *URLRepository, mr *miniredis.Miniredis, url domain.URL) {
+				url.OriginalURL = "https://example.org"
+				assert.NoError(t, repo.Store(context.Background(), url))
+			},
+			wantFound: false,
+		},
+		{
+			name: "deleted",
+			change: func(t *testing.T, repo *URLRepository, mr *miniredis.Miniredis, url domain.URL) {
+				assert.NoError(t, repo.Delete(context.Background(), url.ShortCode))
+			},
+			wantFound: false,
+		},
+		{
+			name: "expired",
+			change: func(t *testing.T, repo *URLRepository, mr *miniredis.Miniredis, url domain.URL) {
+				mr.FastForward(2 * time.Hour)
+			},
+			wantFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup a mini Redis server
+			mr, err := miniredis.Run()
+			if err != nil {
+				t.Fatalf("an error '%s' occurred when starting miniredis", err)
+			}
+			defer mr.Close()
+
+			// Connect to mini Redis
+			rdb := redis.NewClient(&redis.Options{
+				Addr: mr.Addr(),
+			})
+
+			repo := NewURLRepository(rdb)
+			ctx := context.Background()
+
+			url := domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}
+			assert.NoError(t, repo.Store(ctx, url))
+			assert.NoError(t, repo.IndexDestination(ctx, "owner1", url))
+
+			// A second link to the same destination does not replace the first one
+			second := domain.URL{ShortCode: "def456", OriginalURL: "https://example.com", Expiry: time.Now().Add(time.Hour)}
+			assert.NoError(t, repo.Store(ctx, second))
+			assert.NoError(t, repo.IndexDestination(ctx, "owner1", second))
+
+			tt.change(t, repo, mr, url)
+
+			found, err := repo.FindByDestination(ctx, "owner1", "https://example.com")
+			if tt.wantFound {
+				assert.NoError(t, err)
+				assert.Equal(t, "abc123", found.ShortCode)
+			} else {
+				assert.ErrorIs(t, err, domain.ErrURLNotFound)
+			}
+
+			// The index is kept per owner
+			_, err = repo.FindByDestination(ctx, "owner2", "https://example.com")
+			assert.ErrorIs(t, err, domain.ErrURLNotFound)
+		})
+	}
+}
+
+// TestURLRepository_InputURL tests that the submitted input URL is kept next to the link and removed with it
+func TestURLRepository_InputURL(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+	ctx := context.Background()
+
+	url := domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com/a", InputURL: "HTTPS://Example.com:443/a", Expiry: time.Now().Add(time.Hour)}
+	assert.NoError(t, repo.Store(ctx, url))
+	batch := domain.URL{ShortCode: "def456", OriginalURL: "https://example.com/b", InputURL: "https://EXAMPLE.com/b", Expiry: time.Now().Add(time.Hour)}
+	_, err = repo.StoreBatch(ctx, []domain.URL{batch})
+	assert.NoError(t, err)
+
+	found, err := repo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, url.InputURL, found.InputURL)
+
+	inputs := map[string]string{}
+	assert.NoError(t, repo.Iterate(ctx, func(url domain.URL) error {
+		inputs[url.ShortCode] = url.InputURL
+		return nil
+	}))
+	assert.Equal(t, map[string]string{"abc123": url.InputURL, "def456": batch.InputURL}, inputs)
+
+	// A canonical URL has no input URL to keep
+	url.InputURL = ""
+	assert.NoError(t, repo.Store(ctx, url))
+	found, err = repo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Empty(t, found.InputURL)
+
+	assert.NoError(t, repo.Delete(ctx, "def456"))
+	assert.False(t, mr.Exists("input:def456"))
+}
+
+// TestURLRepository_Metadata tests that links are stored as hashes with their metadata, and that links stored
+// as plain strings by earlier versions can still be read and are converted when stored again
+func TestURLRepository_Metadata(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+	ctx := context.Background()
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 123, time.UTC)
+	url := domain.URL{
+		ShortCode:   "abc123",
+		OriginalURL: "https://example.com",
+		Expiry:      time.Now().Add(time.Hour),
+		Title:       "Example",
+		Description: "An example page",
+		Tags:        []string{"docs", "launch"},
+		Notes:       "Shared in the newsletter",
+		CreatedAt:   created,
+		UpdatedAt:   created.Add(time.Minute),
+		// Not a real bcrypt hash: the repository stores it as it is
+		PasswordHash: "$2a$10$hash",
+		MaxClicks:    10,
+		ActivatesAt:  created.Add(time.Hour),
+		Rules:        []domain.RoutingRule{{OS: domain.OSIOS, URL: "https://apps.apple.com/app/id1"}},
+		Variants:     []domain.Variant{{URL: "https://example.com/a", Weight: 1}, {URL: "https://example.com/b", Weight: 2}},
+		ForwardQuery: domain.ForwardQueryPreserve,
+		ForwardPath:  true,
+		RedirectType: domain.RedirectPermanent,
+	}
+	assert.NoError(t, repo.Store(ctx, url))
+	assert.Equal(t, `["docs","launch"]`, mr.HGet("short:abc123", "tags"))
+	assert.False(t, mr.Exists("input:abc123"))
+
+	found, err := repo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, url.Title, found.Title)
+	assert.Equal(t, url.Description, found.Description)
+	assert.Equal(t, url.Tags, found.Tags)
+	assert.Equal(t, url.Notes, found.Notes)
+	assert.Equal(t, url.PasswordHash, found.PasswordHash)
+	assert.Equal(t, url.MaxClicks, found.MaxClicks)
+	assert.True(t, url.ActivatesAt.Equal(found.ActivatesAt))
+	assert.Equal(t, url.Rules, found.Rules)
+	assert.Equal(t, url.Variants, found.Variants)
+	assert.Equal(t, url.ForwardQuery, found.ForwardQuery)
+	assert.True(t, found.ForwardPath)
+	assert.Equal(t, url.RedirectType, found.RedirectType)
+	assert.True(t, url.CreatedAt.Equal(found.CreatedAt))
+	assert.True(t, url.UpdatedAt.Equal(found.UpdatedAt))
+
+	// Cleared metadata is removed from the hash
+	url.Tags, url.Notes = nil, ""
+	assert.NoError(t, repo.Store(ctx, url))
+	found, err = repo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Empty(t, found.Tags)
+	assert.Empty(t, found.Notes)
+
+	// A link stored as a plain string, with its input URL in a key of its own
+	mr.Set("short:legacy", "https://example.com/legacy")
+	mr.SetTTL("short:legacy", time.Hour)
+	mr.Set("input:legacy", "HTTPS://EXAMPLE.com/legacy")
+
+	found, err = repo.FindByShortCode(ctx, "legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/legacy", found.OriginalURL)
+	assert.Equal(t, "HTTPS://EXAMPLE.com/legacy", found.InputURL)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), found.Expiry, time.Second)
+
+	codes := map[string]string{}
+	assert.NoError(t, repo.Iterate(ctx, func(url domain.URL) error {
+		codes[url.ShortCode] = url.OriginalURL
+		return nil
+	}))
+	assert.Equal(t, map[string]string{"abc123": "https://example.com", "legacy": "https://example.com/legacy"}, codes)
+
+	// Storing the link again converts it to a hash
+	found.Title = "Legacy"
+	assert.NoError(t, repo.Store(ctx, *found))
+	assert.Equal(t, "https://example.com/legacy", mr.HGet("short:legacy", "url"))
+	assert.Equal(t, "HTTPS://EXAMPLE.com/legacy", mr.HGet("short:legacy", "input_url"))
+	assert.False(t, mr.Exists("input:legacy"))
+}
+
+// TestURLRepository_SetPageMetadata tests that the fetched page metadata is only stored on the link it was fetched for
+func TestURLRepository_SetPageMetadata(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewURLRepository(rdb)
+	ctx := context.Background()
+	assert.NoError(t, repo.Store(ctx, domain.URL{ShortCode: "abc123", OriginalURL: "https://example.com", Title: "Mine", Expiry: time.Now().Add(time.Hour)}))
+	mr.Set("short:legacy", "https://example.com")
+	page := domain.PageMetadata{Title: "Example Domain", Image: "https://example.com/cover.png", FetchedAt: time.Now().UTC()}
+
+	tests := []struct {
+		name        string
+		shortCode   string
+		originalURL string
+		wantErr     error
+		wantStored  bool
+	}{
+		{name: "stored", shortCode: "abc123", originalURL: "https://example.com", wantStored: true},
+		{name: "original URL changed", shortCode: "abc123", originalURL: "https://example.org"},
+		{name: "plain string link", shortCode: "legacy", originalURL: "https://example.com"},
+		{name: "missing", shortCode: "missing", originalURL: "https://example.com", wantErr: domain.ErrURLNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr.HDel("short:abc123", "page")
+
+			err := repo.SetPageMetadata(ctx, tt.shortCode, tt.originalURL, page)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			found, err := repo.FindByShortCode(ctx, tt.shortCode)
+			assert.NoError(t, err)
+			if !tt.wantStored {
+				assert.Nil(t, found.Page)
+				return
+			}
+			assert.Equal(t, "Mine", found.Title, "The other fields should be kept")
+			if assert.NotNil(t, found.Page) {
+				assert.Equal(t, page.Title, found.Page.Title)
+				assert.Equal(t, page.Image, found.Page.Image)
+				assert.True(t, page.FetchedAt.Equal(found.Page.FetchedAt))
+			}
+
+			// The page is kept when the link is stored again
+			assert.NoError(t, repo.Store(ctx, *found))
+			found, err = repo.FindByShortCode(ctx, tt.shortCode)
+			assert.NoError(t, err)
+			assert.NotNil(t, found.Page)
+		})
+	}
+}
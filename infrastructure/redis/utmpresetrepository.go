@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// utmPresetsKey is the hash of the UTM presets, by name.
+const utmPresetsKey = "utm:presets"
+
+type UTMPresetRepository struct {
+	client *redis.Client
+	keyspace
+}
+
+// NewUTMPresetRepository creates a new instance of UTMPresetRepository.
+func NewUTMPresetRepository(client *redis.Client, opts ...Option) *UTMPresetRepository {
+	return &UTMPresetRepository{client: client, keyspace: newKeyspace(opts)}
+}
+
+// SavePreset stores a UTM preset, replacing any preset with the same name.
+func (r *UTMPresetRepository) SavePreset(ctx context.Context, preset domain.UTMPreset) error {
+	payload, err := json.Marshal(preset)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(ctx, r.key(utmPresetsKey), preset.Name, payload).Err()
+}
+
+// FindPreset retrieves a UTM preset by its name.
+func (r *UTMPresetRepository) FindPreset(ctx context.Context, name string) (*domain.UTMPreset, error) {
+	payload, err := r.client.HGet(ctx, r.key(utmPresetsKey), name).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrUTMPresetNotFound, name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var preset domain.UTMPreset
+	if err := json.Unmarshal([]byte(payload), &preset); err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// ListPresets retrieves all UTM presets, in no particular order.
+func (r *UTMPresetRepository) ListPresets(ctx context.Context) ([]domain.UTMPreset, error) {
+	payloads, err := r.client.HGetAll(ctx, r.key(utmPresetsKey)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	presets := make([]domain.UTMPreset, 0, len(payloads))
+	for _, payload := range payloads {
+		var preset domain.UTMPreset
+		if err := json.Unmarshal([]byte(payload), &preset); err != nil {
+			return nil, err
+		}
+		presets = append(presets, preset)
+	}
+	return presets, nil
+}
+
+// DeletePreset removes a UTM preset.
+func (r *UTMPresetRepository) DeletePreset(ctx context.Context, name string) error {
+	deleted, err := r.client.HDel(ctx, r.key(utmPresetsKey), name).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrUTMPresetNotFound, name)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// TestUTMPresetRepository tests saving, listing and deleting UTM presets
+func TestUTMPresetRepository(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewUTMPresetRepository(rdb)
+	ctx := context.Background()
+
+	_, err = repo.FindPreset(ctx, "spring")
+	assert.ErrorIs(t, err, domain.ErrUTMPresetNotFound)
+
+	preset := domain.UTMPreset{Name: "spring", UTM: domain.UTM{Source: "newsletter", Medium: "email", Campaign: "spring_sale"}}
+	assert.NoError(t, repo.SavePreset(ctx, preset))
+	found, err := repo.FindPreset(ctx, "spring")
+	assert.NoError(t, err)
+	assert.Equal(t, preset.UTM, found.UTM)
+
+	// Saving a preset again replaces it
+	preset.UTM.Medium = "social"
+	assert.NoError(t, repo.SavePreset(ctx, preset))
+	all, err := repo.ListPresets(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, all, 1) {
+		assert.Equal(t, "social", all[0].UTM.Medium)
+	}
+
+	assert.NoError(t, repo.DeletePreset(ctx, "spring"))
+	assert.ErrorIs(t, repo.DeletePreset(ctx, "spring"), domain.ErrUTMPresetNotFound)
+}
@@ -0,0 +1,229 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis keys used by the webhook repository.
+// Deliveries are stored once in a hash and only their IDs move between the lists,
+// so a delivery can be acknowledged by ID no matter which list it is in.
+// The processing list is shared by every instance; the time each delivery in it was reserved is kept in webhookReservedKey.
+const (
+	webhookSubscribersKey = "webhooks:subscribers"
+	webhookDeliveriesKey  = "webhooks:deliveries"
+	webhookQueueKey       = "webhooks:queue"
+	webhookProcessingKey  = "webhooks:processing"
+	webhookReservedKey    = "webhooks:reserved"
+	webhookRetryKey       = "webhooks:retry"
+	webhookDeadKey        = "webhooks:dead"
+)
+
+// promoteDueScript moves the scheduled retries that are due to the delivery queue, and returns how many were moved.
+// KEYS: retry set, queue; ARGV: now in Unix seconds
+var promoteDueScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('LPUSH', KEYS[2], id)
+end
+return #ids
+`)
+
+// recoverScript moves the deliveries of the processing list that were reserved before a time back to the queue,
+// and returns how many were moved. A delivery without a reservation time, left by a worker that stopped right after
+// reserving it, gets the current time, so that it is recovered once it is as old.
+// KEYS: processing list, reservation times, queue; ARGV: now and the time, in Unix milliseconds
+var recoverScript = redis.NewScript(`
+local recovered = 0
+for _, id in ipairs(redis.call('LRANGE', KEYS[1], 0, -1)) do
+	local reserved = redis.call('ZSCORE', KEYS[2], id)
+	if not reserved then
+		redis.call('ZADD', KEYS[2], ARGV[1], id)
+	elseif tonumber(reserved) < tonumber(ARGV[2]) then
+		redis.call('LREM', KEYS[1], 1, id)
+		redis.call('ZREM', KEYS[2], id)
+		redis.call('LPUSH', KEYS[3], id)
+		recovered = recovered + 1
+	end
+end
+return recovered
+`)
+
+type WebhookRepository struct {
+	client *redis.Client
+	keyspace
+}
+
+// NewWebhookRepository creates a new instance of WebhookRepository.
+func NewWebhookRepository(client *redis.Client, opts ...Option) *WebhookRepository {
+	return &WebhookRepository{client: client, keyspace: newKeyspace(opts)}
+}
+
+// SaveSubscriber stores a webhook subscriber, replacing any subscriber with the same ID.
+func (r *WebhookRepository) SaveSubscriber(ctx context.Context, subscriber domain.WebhookSubscriber) error {
+	payload, err := json.Marshal(subscriber)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(ctx, r.key(webhookSubscribersKey), subscriber.ID, payload).Err()
+}
+
+// FindSubscriber retrieves a webhook subscriber by its ID.
+func (r *WebhookRepository) FindSubscriber(ctx context.Context, id string) (*domain.WebhookSubscriber, error) {
+	payload, err := r.client.HGet(ctx, r.key(webhookSubscribersKey), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrWebhookNotFound, id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var subscriber domain.WebhookSubscriber
+	if err := json.Unmarshal([]byte(payload), &subscriber); err != nil {
+		return nil, err
+	}
+	return &subscriber, nil
+}
+
+// ListSubscribers retrieves all webhook subscribers.
+func (r *WebhookRepository) ListSubscribers(ctx context.Context) ([]domain.WebhookSubscriber, error) {
+	payloads, err := r.client.HGetAll(ctx, r.key(webhookSubscribersKey)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	subscribers := make([]domain.WebhookSubscriber, 0, len(payloads))
+	for _, payload := range payloads {
+		var subscriber domain.WebhookSubscriber
+		if err := json.Unmarshal([]byte(payload), &subscriber); err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, subscriber)
+	}
+	return subscribers, nil
+}
+
+// DeleteSubscriber removes a webhook subscriber.
+// Deliveries already queued for the subscriber are dropped by the dispatcher.
+func (r *WebhookRepository) DeleteSubscriber(ctx context.Context, id string) error {
+	deleted, err := r.client.HDel(ctx, r.key(webhookSubscribersKey), id).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrWebhookNotFound, id)
+	}
+	return nil
+}
+
+// Enqueue adds a delivery to the tail of the delivery queue.
+func (r *WebhookRepository) Enqueue(ctx context.Context, delivery domain.WebhookDelivery) error {
+	payload, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, r.key(webhookDeliveriesKey), delivery.ID, payload)
+		pipe.LPush(ctx, r.key(webhookQueueKey), delivery.ID)
+		return nil
+	})
+	return err
+}
+
+// Reserve waits up to the given timeout for the next delivery, moves it to the processing list and records when.
+// It returns nil without an error when no delivery became available in time.
+func (r *WebhookRepository) Reserve(ctx context.Context, timeout time.Duration) (*domain.WebhookDelivery, error) {
+	id, err := r.client.BRPopLPush(ctx, r.key(webhookQueueKey), r.key(webhookProcessingKey), timeout).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := r.client.ZAdd(ctx, r.key(webhookReservedKey), &redis.Z{Score: float64(time.Now().UnixMilli()), Member: id}).Err(); err != nil {
+		return nil, err
+	}
+
+	payload, err := r.client.HGet(ctx, r.key(webhookDeliveriesKey), id).Result()
+	if errors.Is(err, redis.Nil) {
+		// The delivery body is gone, so there is nothing left to send.
+		_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.LRem(ctx, r.key(webhookProcessingKey), 1, id)
+			pipe.ZRem(ctx, r.key(webhookReservedKey), id)
+			return nil
+		})
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	var delivery domain.WebhookDelivery
+	if err := json.Unmarshal([]byte(payload), &delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Ack removes a delivery that was sent successfully.
+func (r *WebhookRepository) Ack(ctx context.Context, delivery domain.WebhookDelivery) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, r.key(webhookProcessingKey), 1, delivery.ID)
+		pipe.ZRem(ctx, r.key(webhookReservedKey), delivery.ID)
+		pipe.HDel(ctx, r.key(webhookDeliveriesKey), delivery.ID)
+		return nil
+	})
+	return err
+}
+
+// Retry schedules a failed delivery to be queued again at the given time.
+func (r *WebhookRepository) Retry(ctx context.Context, delivery domain.WebhookDelivery, at time.Time) error {
+	payload, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, r.key(webhookDeliveriesKey), delivery.ID, payload)
+		pipe.LRem(ctx, r.key(webhookProcessingKey), 1, delivery.ID)
+		pipe.ZRem(ctx, r.key(webhookReservedKey), delivery.ID)
+		pipe.ZAdd(ctx, r.key(webhookRetryKey), &redis.Z{Score: float64(at.Unix()), Member: delivery.ID})
+		return nil
+	})
+	return err
+}
+
+// Bury moves a delivery that ran out of attempts to the dead letter list, where it is kept for inspection.
+func (r *WebhookRepository) Bury(ctx context.Context, delivery domain.WebhookDelivery) error {
+	payload, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, r.key(webhookDeliveriesKey), delivery.ID, payload)
+		pipe.LRem(ctx, r.key(webhookProcessingKey), 1, delivery.ID)
+		pipe.ZRem(ctx, r.key(webhookReservedKey), delivery.ID)
+		pipe.LPush(ctx, r.key(webhookDeadKey), delivery.ID)
+		return nil
+	})
+	return err
+}
+
+// PromoteDue moves the scheduled retries that are due back to the delivery queue and returns how many were moved.
+// The retries are moved in one script, so that none is lost if the connection fails, and each is queued only once.
+func (r *WebhookRepository) PromoteDue(ctx context.Context, now time.Time) (int, error) {
+	keys := []string{r.key(webhookRetryKey), r.key(webhookQueueKey)}
+	return promoteDueScript.Run(ctx, r.client, keys, now.Unix()).Int()
+}
+
+// RecoverInFlight moves the deliveries that were reserved before the given time and are still in the processing list
+// back to the queue, and returns how many were moved. Workers that stopped while sending them, on any instance,
+// never finish them; deliveries still being sent by a live worker are left alone as long as they are younger.
+func (r *WebhookRepository) RecoverInFlight(ctx context.Context, reservedBefore time.Time) (int, error) {
+	keys := []string{r.key(webhookProcessingKey), r.key(webhookReservedKey), r.key(webhookQueueKey)}
+	return recoverScript.Run(ctx, r.client, keys, time.Now().UnixMilli(), reservedBefore.UnixMilli()).Int()
+}
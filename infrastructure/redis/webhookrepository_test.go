@@ -0,0 +1,111 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// TestWebhookRepository_Subscribers tests saving, listing and deleting webhook subscribers
+func TestWebhookRepository_Subscribers(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewWebhookRepository(rdb)
+	ctx := context.Background()
+
+	subscriber := domain.WebhookSubscriber{ID: "sub1", URL: "https://example.com/hook", Secret: "s3cret", Events: []domain.EventType{domain.EventLinkCreated}}
+	assert.NoError(t, repo.SaveSubscriber(ctx, subscriber))
+
+	found, err := repo.FindSubscriber(ctx, "sub1")
+	assert.NoError(t, err)
+	assert.Equal(t, subscriber.URL, found.URL)
+	assert.Equal(t, subscriber.Events, found.Events)
+
+	all, err := repo.ListSubscribers(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+
+	assert.NoError(t, repo.DeleteSubscriber(ctx, "sub1"))
+	_, err = repo.FindSubscriber(ctx, "sub1")
+	assert.ErrorIs(t, err, domain.ErrWebhookNotFound)
+	assert.ErrorIs(t, repo.DeleteSubscriber(ctx, "sub1"), domain.ErrWebhookNotFound)
+}
+
+// TestWebhookRepository_Queue tests that deliveries survive until acknowledged
+func TestWebhookRepository_Queue(t *testing.T) {
+	// Setup a mini Redis server
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when starting miniredis", err)
+	}
+	defer mr.Close()
+
+	// Connect to mini Redis
+	rdb := redis.NewClient(&redis.Options{
+		Addr: mr.Addr(),
+	})
+
+	repo := NewWebhookRepository(rdb)
+	ctx := context.Background()
+
+	delivery := domain.WebhookDelivery{ID: "d1", SubscriberID: "sub1", Event: domain.Event{ID: "e1", Type: domain.EventLinkCreated}}
+	assert.NoError(t, repo.Enqueue(ctx, delivery))
+
+	// A reserved delivery stays in the processing list until it is acknowledged,
+	// and is only queued again once it was reserved for longer than another instance may still be sending it
+	reserved, err := repo.Reserve(ctx, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "d1", reserved.ID)
+	recovered, err := repo.RecoverInFlight(ctx, time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, recovered)
+	recovered, err = repo.RecoverInFlight(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+	assert.False(t, mr.Exists(webhookReservedKey))
+
+	// A delivery left in the processing list without a reservation time starts its clock when it is found
+	_, err = rdb.RPopLPush(ctx, webhookQueueKey, webhookProcessingKey).Result()
+	assert.NoError(t, err)
+	recovered, err = repo.RecoverInFlight(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, recovered)
+	recovered, err = repo.RecoverInFlight(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+
+	// A retried delivery is only queued again once it is due
+	reserved, err = repo.Reserve(ctx, time.Second)
+	assert.NoError(t, err)
+	reserved.Attempts++
+	assert.NoError(t, repo.Retry(ctx, *reserved, time.Now().Add(time.Minute)))
+	promoted, err := repo.PromoteDue(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, promoted)
+	promoted, err = repo.PromoteDue(ctx, time.Now().Add(2*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, promoted)
+
+	reserved, err = repo.Reserve(ctx, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reserved.Attempts)
+	assert.NoError(t, repo.Ack(ctx, *reserved))
+	assert.False(t, mr.Exists(webhookProcessingKey))
+	assert.False(t, mr.Exists(webhookReservedKey))
+	assert.False(t, mr.Exists(webhookDeliveriesKey))
+}
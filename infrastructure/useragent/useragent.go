@@ -0,0 +1,55 @@
+package useragent
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// botPattern matches the user agents of crawlers, link unfurlers and HTTP libraries.
+// Most of them name themselves bot, crawler or spider; the others are listed by name.
+var botPattern = regexp.MustCompile(`(?i)bot\b|bot/|crawl|spider|slurp|facebookexternalhit|facebookcatalog|embedly|whatsapp|bingpreview|skypeuripreview|headlesschrome|lighthouse|curl/|wget/|python-requests|python-urllib|go-http-client|java/|okhttp|axios/|node-fetch`)
+
+// Parse describes the visitor sending a User-Agent header: its operating system, its device class and whether it is a bot.
+// It only looks for the tokens that identify each platform, so unknown platforms are domain.OSOther on a desktop.
+// A missing User-Agent is treated as a bot, since browsers always send one.
+// iPads on iPadOS 13 and later identify as Macs by default, and are seen as such.
+func Parse(userAgent string) domain.Visitor {
+	visitor := domain.Visitor{OS: domain.OSOther, Device: domain.DeviceDesktop}
+	if strings.TrimSpace(userAgent) == "" {
+		visitor.Bot = true
+		return visitor
+	}
+	visitor.Bot = botPattern.MatchString(userAgent)
+
+	// The order matters: iOS user agents contain "like Mac OS X", Android and ChromeOS ones contain "Linux"
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPod"):
+		visitor.OS, visitor.Device = domain.OSIOS, domain.DeviceMobile
+	case strings.Contains(userAgent, "iPad"):
+		visitor.OS, visitor.Device = domain.OSIOS, domain.DeviceTablet
+	case strings.Contains(userAgent, "Android"):
+		visitor.OS = domain.OSAndroid
+		// Android phones add "Mobile" to their user agent, and tablets do not
+		if strings.Contains(userAgent, "Mobile") {
+			visitor.Device = domain.DeviceMobile
+		} else {
+			visitor.Device = domain.DeviceTablet
+		}
+	case strings.Contains(userAgent, "CrOS"):
+		visitor.OS = domain.OSChromeOS
+	case strings.Contains(userAgent, "Windows Phone"):
+		visitor.Device = domain.DeviceMobile
+	case strings.Contains(userAgent, "Windows"):
+		visitor.OS = domain.OSWindows
+	case strings.Contains(userAgent, "Macintosh"), strings.Contains(userAgent, "Mac OS X"):
+		visitor.OS = domain.OSMacOS
+	case strings.Contains(userAgent, "Linux"), strings.Contains(userAgent, "X11"):
+		visitor.OS = domain.OSLinux
+		if strings.Contains(userAgent, "Mobile") {
+			visitor.Device = domain.DeviceMobile
+		}
+	}
+	return visitor
+}
@@ -0,0 +1,94 @@
+package useragent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// TestParse tests that the user agents of common browsers, apps and bots are recognized.
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      domain.Visitor
+	}{
+		{
+			name:      "iPhone Safari",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			want:      domain.Visitor{OS: domain.OSIOS, Device: domain.DeviceMobile},
+		},
+		{
+			name:      "iPad Safari",
+			userAgent: "Mozilla/5.0 (iPad; CPU OS 12_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1 Mobile/15E148 Safari/604.1",
+			want:      domain.Visitor{OS: domain.OSIOS, Device: domain.DeviceTablet},
+		},
+		{
+			name:      "Android phone Chrome",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Mobile Safari/537.36",
+			want:      domain.Visitor{OS: domain.OSAndroid, Device: domain.DeviceMobile},
+		},
+		{
+			name:      "Android tablet Chrome",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
+			want:      domain.Visitor{OS: domain.OSAndroid, Device: domain.DeviceTablet},
+		},
+		{
+			name:      "Windows Edge",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36 Edg/123.0.0.0",
+			want:      domain.Visitor{OS: domain.OSWindows, Device: domain.DeviceDesktop},
+		},
+		{
+			name:      "macOS Firefox",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14.4; rv:124.0) Gecko/20100101 Firefox/124.0",
+			want:      domain.Visitor{OS: domain.OSMacOS, Device: domain.DeviceDesktop},
+		},
+		{
+			name:      "Linux Firefox",
+			userAgent: "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:124.0) Gecko/20100101 Firefox/124.0",
+			want:      domain.Visitor{OS: domain.OSLinux, Device: domain.DeviceDesktop},
+		},
+		{
+			name:      "ChromeOS",
+			userAgent: "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
+			want:      domain.Visitor{OS: domain.OSChromeOS, Device: domain.DeviceDesktop},
+		},
+		{
+			name:      "Googlebot smartphone",
+			userAgent: "Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want:      domain.Visitor{OS: domain.OSAndroid, Device: domain.DeviceMobile, Bot: true},
+		},
+		{
+			name:      "Slack unfurler",
+			userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			want:      domain.Visitor{OS: domain.OSOther, Device: domain.DeviceDesktop, Bot: true},
+		},
+		{
+			name:      "Facebook crawler",
+			userAgent: "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+			want:      domain.Visitor{OS: domain.OSOther, Device: domain.DeviceDesktop, Bot: true},
+		},
+		{
+			name:      "curl",
+			userAgent: "curl/8.4.0",
+			want:      domain.Visitor{OS: domain.OSOther, Device: domain.DeviceDesktop, Bot: true},
+		},
+		{
+			name:      "missing",
+			userAgent: "",
+			want:      domain.Visitor{OS: domain.OSOther, Device: domain.DeviceDesktop, Bot: true},
+		},
+		{
+			name:      "unknown",
+			userAgent: "SomeApp/2.0",
+			want:      domain.Visitor{OS: domain.OSOther, Device: domain.DeviceDesktop},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Parse(tt.userAgent))
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/terenzio/URL-Shortening-Service/domain"
+)
+
+// Headers sent with every webhook delivery.
+// Receivers verify a delivery by computing Sign(secret, timestamp, body) and comparing it to the signature header.
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+const (
+	// defaultMaxAttempts is the number of times a delivery is tried before it is buried.
+	defaultMaxAttempts = 8
+	// reserveTimeout is how long the dispatcher blocks waiting for a delivery before checking for due retries.
+	reserveTimeout = time.Second
+	// maxBackoff caps the delay between two attempts of the same delivery.
+	maxBackoff = time.Hour
+	// inFlightTimeout is how long a reserved delivery can go unfinished before it is queued again, for any instance
+	// to send it. It must be longer than the time it takes to send one, including the timeout of the HTTP client.
+	inFlightTimeout = 5 * time.Minute
+)
+
+// Dispatcher sends queued webhook deliveries to their subscribers.
+// A delivery is only removed from the queue after the subscriber answered with a 2xx status,
+// so every event is delivered at least once; receivers should use the delivery ID to drop duplicates.
+type Dispatcher struct {
+	repo        domain.WebhookRepository
+	client      *http.Client
+	maxAttempts int
+}
+
+// NewDispatcher creates a new instance of Dispatcher that sends deliveries with the given HTTP client.
+func NewDispatcher(repo domain.WebhookRepository, client *http.Client) *Dispatcher {
+	return &Dispatcher{repo: repo, client: client, maxAttempts: defaultMaxAttempts}
+}
+
+// Run processes deliveries until the context is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if _, err := d.ProcessNext(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error processing webhook delivery: %v", err)
+			time.Sleep(reserveTimeout)
+		}
+	}
+}
+
+// ProcessNext queues the retries that are due, and the deliveries left in flight for longer than inFlightTimeout
+// by a worker that stopped, then waits for one delivery and sends it.
+// It reports whether a delivery was processed.
+func (d *Dispatcher) ProcessNext(ctx context.Context) (bool, error) {
+	now := time.Now()
+	if _, err := d.repo.PromoteDue(ctx, now); err != nil {
+		return false, fmt.Errorf("failed to promote due retries: %w", err)
+	}
+	if recovered, err := d.repo.RecoverInFlight(ctx, now.Add(-inFlightTimeout)); err != nil {
+		return false, fmt.Errorf("failed to recover in-flight deliveries: %w", err)
+	} else if recovered > 0 {
+		log.Printf("Recovered %d in-flight webhook deliveries", recovered)
+	}
+
+	delivery, err := d.repo.Reserve(ctx, reserveTimeout)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve delivery: %w", err)
+	}
+	if delivery == nil {
+		return false, nil
+	}
+
+	subscriber, err := d.repo.FindSubscriber(ctx, delivery.SubscriberID)
+	if errors.Is(err, domain.ErrWebhookNotFound) {
+		// The subscriber was deleted after the event was queued.
+		return true, d.repo.Ack(ctx, *delivery)
+	} else if err != nil {
+		return false, err
+	}
+
+	if err := d.send(ctx, *subscriber, *delivery); err != nil {
+		delivery.Attempts++
+		if delivery.Attempts >= d.maxAttempts {
+			log.Printf("Giving up on webhook delivery %s to %s after %d attempts: %v", delivery.ID, subscriber.URL, delivery.Attempts, err)
+			return true, d.repo.Bury(ctx, *delivery)
+		}
+		return true, d.repo.Retry(ctx, *delivery, time.Now().Add(backoff(delivery.Attempts)))
+	}
+	return true, d.repo.Ack(ctx, *delivery)
+}
+
+// send posts the event of a delivery to the subscriber, signed with the subscriber's secret.
+func (d *Dispatcher) send(ctx context.Context, subscriber domain.WebhookSubscriber, delivery domain.WebhookDelivery) error {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscriber.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(delivery.Event.Type))
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(subscriber.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("subscriber responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign computes the signature of a webhook body.
+// The timestamp is part of the signed content, so that receivers can reject replayed deliveries.
+// The result has the form "sha256=<hex encoded HMAC-SHA256>".
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the delay before the given attempt, doubling from 2 seconds up to maxBackoff.
+func backoff(attempt int) time.Duration {
+	delay := time.Second << attempt
+	if delay <= 0 || delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/terenzio/URL-Shortening-Service/application"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+	redisRepo "github.com/terenzio/URL-Shortening-Service/infrastructure/redis"
+)
+
+// TestDispatcher_ProcessNext tests that queued events are signed, delivered and retried.
+func TestDispatcher_ProcessNext(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		events       []domain.EventType
+		wantCalls    int
+		wantRetrying bool
+	}{
+		{
+			name:      "Delivered and Signed",
+			status:    http.StatusOK,
+			wantCalls: 1,
+		},
+		{
+			name:         "Subscriber Error is Retried",
+			status:       http.StatusInternalServerError,
+			wantCalls:    1,
+			wantRetrying: true,
+		},
+		{
+			name:      "Filtered Event is Not Delivered",
+			status:    http.StatusOK,
+			events:    []domain.EventType{domain.EventLinkDeleted},
+			wantCalls: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup a mini Redis server
+			mr, err := miniredis.Run()
+			if err != nil {
+				t.Fatalf("an error '%s' occurred when starting miniredis", err)
+			}
+			defer mr.Close()
+			rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			repo := redisRepo.NewWebhookRepository(rdb)
+
+			// The receiver checks the signature the same way a subscriber would
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				body, _ := io.ReadAll(r.Body)
+				timestamp, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+				assert.Equal(t, Sign("s3cret", timestamp, body), r.Header.Get(SignatureHeader))
+				assert.Equal(t, string(domain.EventLinkCreated), r.Header.Get(EventHeader))
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			service := application.NewWebhookService(repo)
+			_, err = service.RegisterSubscriber(ctx, domain.AddWebhookRequest{URL: server.URL, Secret: "s3cret", Events: tt.events})
+			assert.NoError(t, err)
+			err = service.Publish(ctx, domain.Event{ID: "evt1", Type: domain.EventLinkCreated, ShortCode: "abc", OccurredAt: time.Now()})
+			assert.NoError(t, err)
+
+			dispatcher := NewDispatcher(repo, server.Client())
+			if tt.wantCalls > 0 {
+				processed, err := dispatcher.ProcessNext(ctx)
+				assert.NoError(t, err)
+				assert.True(t, processed)
+			}
+			assert.Equal(t, tt.wantCalls, calls)
+
+			// Nothing may be left in flight, and failed deliveries wait in the retry set
+			assert.False(t, mr.Exists("webhooks:processing"))
+			retrying, _ := rdb.ZCard(ctx, "webhooks:retry").Result()
+			assert.Equal(t, tt.wantRetrying, retrying == 1)
+		})
+	}
+}
+
+// TestBackoff tests that the retry delay doubles and is capped.
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 2*time.Second, backoff(1))
+	assert.Equal(t, 4*time.Second, backoff(2))
+	assert.Equal(t, maxBackoff, backoff(30))
+	assert.Equal(t, maxBackoff, backoff(100))
+}
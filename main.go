@@ -1,7 +1,13 @@
 package main
 
 import (
+	"context"
+	"expvar"
 	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
@@ -9,8 +15,15 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"github.com/terenzio/URL-Shortening-Service/application"
 	"github.com/terenzio/URL-Shortening-Service/docs"
+	"github.com/terenzio/URL-Shortening-Service/domain"
+	"github.com/terenzio/URL-Shortening-Service/infrastructure/bloom"
+	"github.com/terenzio/URL-Shortening-Service/infrastructure/cache"
+	"github.com/terenzio/URL-Shortening-Service/infrastructure/config"
+	"github.com/terenzio/URL-Shortening-Service/infrastructure/geoip"
 	urlHandler "github.com/terenzio/URL-Shortening-Service/infrastructure/http"
+	"github.com/terenzio/URL-Shortening-Service/infrastructure/metadata"
 	redisRepo "github.com/terenzio/URL-Shortening-Service/infrastructure/redis"
+	"github.com/terenzio/URL-Shortening-Service/infrastructure/webhook"
 )
 
 // @title          URL Shortening Service
@@ -24,54 +37,255 @@ import (
 // @license.url   http://www.apache.org/licenses/LICENSE-2.0.html
 // @host      localhost:9000
 // @BasePath  /api/v1
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description Required on the management endpoints when SHORTENER_API_KEYS is set.
 // @externalDocs.description  OpenAPI
 // @externalDocs.url          https://swagger.io/resources/open-api/
 func main() {
 
+	// Load the configuration from the environment
+	cfg := config.Load()
+
 	// Create a new Redis client
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
 	})
 
-	// Create a new URL repository
-	repo := redisRepo.NewURLRepository(rdb)
+	// Load the workspaces besides the default one, which share the deployment with keyspaces of their own
+	workspaces, err := config.LoadWorkspaces(cfg.WorkspacesFile)
+	if err != nil {
+		log.Fatalf("Failed to load the workspaces: %v", err)
+	}
+	if err := application.CheckWorkspaces(workspaces, cfg.APIKeys); err != nil {
+		log.Fatalf("Invalid workspaces: %v", err)
+	}
 
-	// Create a new URL service
-	service := application.NewURLService(repo)
+	// Create the handler options shared by every workspace
+	redirectType, err := application.NormalizeRedirectType(cfg.RedirectType)
+	if err != nil || redirectType == "" {
+		log.Fatalf("Invalid redirect type %q: use 301, 302, 307, 308 or interstitial", cfg.RedirectType)
+	}
+	handlerOptions := []urlHandler.HandlerOption{
+		urlHandler.WithPublicBaseURL(cfg.PublicBaseURL),
+		urlHandler.WithBulkLimit(cfg.BulkLimit),
+		urlHandler.WithLinkUnlock(cfg.LinkCookieSecret, cfg.LinkUnlockTTL),
+		urlHandler.WithPendingLinkPage(cfg.PendingLinkPage),
+		urlHandler.WithRedirectType(redirectType),
+	}
+	// Visitors are located in a local GeoIP database, which is reloaded when the file is replaced
+	ctx := context.Background()
+	if cfg.GeoIPDatabase != "" {
+		geoDB, err := geoip.Open(cfg.GeoIPDatabase)
+		if err != nil {
+			log.Fatalf("Failed to load the GeoIP database: %v", err)
+		}
+		go geoDB.Watch(ctx, cfg.GeoIPReloadInterval)
+		handlerOptions = append(handlerOptions, urlHandler.WithCountryLocator(geoDB))
+	}
 
-	// Create a new URL handler
-	handler := urlHandler.NewHandler(service)
+	// Custom domains are registered in a registry shared by every workspace,
+	// which must never take over the shared host of the service or the domains of the workspaces
+	publicURL, err := url.Parse(cfg.PublicBaseURL)
+	if err != nil {
+		log.Fatalf("Invalid public URL %q: %v", cfg.PublicBaseURL, err)
+	}
+	reservedDomains := []string{publicURL.Host}
+	for _, workspace := range workspaces {
+		reservedDomains = append(reservedDomains, workspace.Domain)
+	}
+	domainRepo := redisRepo.NewDomainRepository(rdb)
+	serviceOptions := []application.Option{
+		application.WithDomains(domainRepo, net.DefaultResolver),
+		application.WithReservedDomains(reservedDomains...),
+	}
+
+	// Create the handlers of every workspace, and the API keys that lead to them
+	handler, webhookHandler := startWorkspace(ctx, cfg, rdb, domain.Workspace{}, serviceOptions, handlerOptions)
+	router := urlHandler.NewWorkspaces(handler, webhookHandler)
+	// Redirects sent to the verified custom domains of a workspace go to it too
+	router.UseDomainRegistry(domainRepo, publicURL.Host)
+	apiKeys := make(map[string]string)
+	for _, key := range cfg.APIKeys {
+		apiKeys[key] = ""
+	}
+	for _, workspace := range workspaces {
+		handler, webhookHandler := startWorkspace(ctx, cfg, rdb, workspace, serviceOptions, handlerOptions)
+		router.Add(workspace.ID, workspace.Domain, handler, webhookHandler)
+		for _, key := range workspace.APIKeys {
+			apiKeys[key] = workspace.ID
+		}
+	}
 
 	// Initialize the Gin router
-	router := gin.Default()
-	router.ForwardedByClientIP = true
-	router.SetTrustedProxies([]string{"127.0.0.1"})
+	engine := gin.Default()
+	engine.ForwardedByClientIP = true
+	if err := engine.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
 
 	//testString := "Hello, World!"
 
 	// Register routes with their handlers
 	docs.SwaggerInfo.BasePath = "/api/v1"
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
-	v1 := router.Group("/api/v1")
+	engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
+	v1 := engine.Group("/api/v1")
 	{
-		urlPage := v1.Group("/url")
+		// The management routes require an API key when keys are configured, and go to the workspace of the key;
+		// the redirects stay public, and go to the workspace of the domain they were sent to
+		management := v1.Group("")
+		if len(apiKeys) > 0 {
+			management.Use(urlHandler.WorkspaceAPIKeyAuth(apiKeys))
+		}
+
+		urlPage := management.Group("/url")
 		{
 			//urlPage.GET("/display", handler.HandleHomePage(testString))
-			urlPage.GET("/display", handler.HandleHomePage)
+			urlPage.GET("/display", router.Manage((*urlHandler.Handler).HandleHomePage))
 
-			urlPage.POST("/add", handler.HandleAddLink)
+			urlPage.POST("/add", router.Manage((*urlHandler.Handler).HandleAddLink))
+			urlPage.POST("/bulk", router.Manage((*urlHandler.Handler).HandleBulkAddLinks))
+			urlPage.POST("/import", router.Manage((*urlHandler.Handler).HandleImportLinks))
+			urlPage.GET("/export", router.Manage((*urlHandler.Handler).HandleExportLinks))
+			urlPage.GET("/:shortcode", router.Manage((*urlHandler.Handler).HandleGetLink))
+			urlPage.GET("/:shortcode/stats", router.Manage((*urlHandler.Handler).HandleLinkStats))
+			urlPage.GET("/:shortcode/qr", router.Manage((*urlHandler.Handler).HandleLinkQRCode))
+			urlPage.PUT("/:shortcode", router.Manage((*urlHandler.Handler).HandleUpdateLink))
+			urlPage.DELETE("/:shortcode", router.Manage((*urlHandler.Handler).HandleDeleteLink))
 		}
 		urlRedirect := v1.Group("/redirect")
 		{
-			urlRedirect.GET("/:shortcode", handler.HandleRedirectToOriginalLink)
+			urlRedirect.GET("/:shortcode", router.Redirect((*urlHandler.Handler).HandleRedirectToOriginalLink))
+			urlRedirect.POST("/:shortcode", router.Redirect((*urlHandler.Handler).HandleUnlockLink))
+			// Links that forward paths are also followed with a path after the short code
+			urlRedirect.GET("/:shortcode/*path", router.Redirect((*urlHandler.Handler).HandleRedirectToOriginalLink))
+			urlRedirect.POST("/:shortcode/*path", router.Redirect((*urlHandler.Handler).HandleUnlockLink))
+		}
+		// The QR codes of the shortened URLs are public too, so that the qr_code_url of a link can be shared as is
+		v1.GET("/qr/:shortcode", router.Redirect((*urlHandler.Handler).HandleLinkQRCode))
+		management.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+		management.GET("/workspace", router.Manage((*urlHandler.Handler).HandleWorkspace))
+		utmPresets := management.Group("/utm-presets")
+		{
+			utmPresets.GET("", router.Manage((*urlHandler.Handler).HandleListUTMPresets))
+			utmPresets.GET("/:name", router.Manage((*urlHandler.Handler).HandleGetUTMPreset))
+			utmPresets.PUT("/:name", router.Manage((*urlHandler.Handler).HandleSaveUTMPreset))
+			utmPresets.DELETE("/:name", router.Manage((*urlHandler.Handler).HandleDeleteUTMPreset))
+		}
+		domains := management.Group("/domains")
+		{
+			domains.GET("", router.Manage((*urlHandler.Handler).HandleListDomains))
+			domains.POST("", router.Manage((*urlHandler.Handler).HandleAddDomain))
+			domains.GET("/:domain", router.Manage((*urlHandler.Handler).HandleGetDomain))
+			domains.DELETE("/:domain", router.Manage((*urlHandler.Handler).HandleDeleteDomain))
+			domains.POST("/:domain/verify", router.Manage((*urlHandler.Handler).HandleVerifyDomain))
+		}
+		groups := management.Group("/groups")
+		{
+			groups.GET("", router.Manage((*urlHandler.Handler).HandleListGroups))
+			groups.GET("/:id", router.Manage((*urlHandler.Handler).HandleGetGroup))
+			groups.PUT("/:id", router.Manage((*urlHandler.Handler).HandleSaveGroup))
+			groups.DELETE("/:id", router.Manage((*urlHandler.Handler).HandleDeleteGroup))
+			groups.GET("/:id/links", router.Manage((*urlHandler.Handler).HandleGroupLinks))
+			groups.GET("/:id/stats", router.Manage((*urlHandler.Handler).HandleGroupStats))
+			groups.POST("/:id/expire", router.Manage((*urlHandler.Handler).HandleExpireGroup))
+			groups.POST("/:id/renew", router.Manage((*urlHandler.Handler).HandleRenewGroup))
+		}
+		webhooks := management.Group("/webhooks")
+		{
+			webhooks.POST("", router.ManageWebhooks((*urlHandler.WebhookHandler).HandleAddWebhook))
+			webhooks.GET("", router.ManageWebhooks((*urlHandler.WebhookHandler).HandleListWebhooks))
+			webhooks.DELETE("/:id", router.ManageWebhooks((*urlHandler.WebhookHandler).HandleDeleteWebhook))
 		}
 	}
 
 	log.Println("\nThe URL Shortening Service is now running!")
-	if err := router.Run(":9000"); err != nil {
+	if err := engine.Run(cfg.ServerAddr); err != nil {
 		log.Fatalf("Failed to run server: %v", err)
 	}
 
 }
+
+// startWorkspace creates the repositories, the services and the handlers of a workspace, and starts its background workers.
+// The default workspace has an empty ID and keeps the Redis keys of earlier versions; the others have keys of their own.
+// The service and handler options given are shared by every workspace.
+func startWorkspace(ctx context.Context, cfg config.Config, rdb *redis.Client, workspace domain.Workspace, sharedOptions []application.Option, handlerOptions []urlHandler.HandlerOption) (*urlHandler.Handler, *urlHandler.WebhookHandler) {
+	keyspace := redisRepo.InWorkspace(workspace.ID)
+
+	// Create a new URL repository
+	// Uniqueness checks are answered from a Bloom filter of the existing short codes when possible
+	links := redisRepo.NewURLRepository(rdb, keyspace)
+	var repo domain.URLRepository = links
+	if cfg.BloomCapacity > 0 {
+		bloomRepo := bloom.NewURLRepository(repo, bloom.Options{
+			ExpectedItems:     cfg.BloomCapacity,
+			FalsePositiveRate: cfg.BloomFalsePositiveRate,
+			Store:             redisRepo.NewBloomStore(rdb, keyspace),
+			Subscriber:        redisRepo.NewInvalidator(rdb, keyspace),
+		})
+		go bloomRepo.Run(ctx, time.Second, time.Minute)
+		expvarName := "bloom"
+		if workspace.ID != "" {
+			expvarName += ":" + workspace.ID
+		}
+		expvar.Publish(expvarName, expvar.Func(func() any { return bloomRepo.Stats() }))
+		repo = bloomRepo
+	}
+	// Redirect lookups are cached in memory, and changes are shared with the other instances through Redis
+	if cfg.CacheSize > 0 {
+		cachedRepo := cache.NewURLRepository(repo, cache.Options{
+			Capacity:    cfg.CacheSize,
+			TTL:         cfg.CacheTTL,
+			NegativeTTL: cfg.CacheNegativeTTL,
+			Invalidator: redisRepo.NewInvalidator(rdb, keyspace),
+		})
+		go cachedRepo.Listen(ctx)
+		repo = cachedRepo
+	}
+
+	// Create the webhook service, which receives the link lifecycle events
+	webhookService := application.NewWebhookService(redisRepo.NewWebhookRepository(rdb, keyspace))
+
+	// Create a new URL service
+	serviceOptions := append([]application.Option{
+		application.WithWorkspace(workspace),
+		application.WithEventPublisher(webhookService),
+		application.WithCanonicalOptions(application.CanonicalOptions{
+			SortQuery:     cfg.CanonicalSortQuery,
+			StripParams:   cfg.CanonicalStripParams,
+			StripFragment: cfg.CanonicalStripFragment,
+		}),
+		application.WithUTMPresets(redisRepo.NewUTMPresetRepository(rdb, keyspace)),
+		application.WithGroups(redisRepo.NewGroupRepository(rdb, keyspace)),
+		// The clicks and the indexes of the links are kept next to them, past the caching decorators
+		application.WithClickCounter(links),
+		application.WithGroupIndex(links),
+		application.WithDestinationIndex(links),
+	}, sharedOptions...)
+	if cfg.Deduplicate {
+		serviceOptions = append(serviceOptions, application.WithDeduplication())
+	}
+	// Wrong passwords of protected links are counted in Redis, so that guessing is throttled across instances
+	if cfg.PasswordMaxAttempts > 0 {
+		serviceOptions = append(serviceOptions, application.WithPasswordThrottle(redisRepo.NewAttemptCounter(rdb, keyspace), cfg.PasswordMaxAttempts, cfg.PasswordAttemptWindow))
+	}
+	// The title and Open Graph tags of the target pages are fetched in the background, never from private addresses
+	if cfg.MetadataWorkers > 0 {
+		fetcher := metadata.NewFetcher(metadata.NewClient(cfg.MetadataTimeout), metadata.Options{MaxBytes: int64(cfg.MetadataMaxBytes)})
+		pageWorker := application.NewPageMetadataWorker(repo, fetcher, cfg.MetadataQueueSize)
+		go pageWorker.Run(ctx, cfg.MetadataWorkers)
+		serviceOptions = append(serviceOptions, application.WithPageMetadataWorker(pageWorker))
+	}
+	service := application.NewURLService(repo, serviceOptions...)
+
+	// Start the background workers: the expiry sweeper emits the expired events,
+	// and the dispatcher delivers the queued events to the webhook subscribers
+	go service.RunExpirySweeper(ctx, time.Minute)
+	dispatcher := webhook.NewDispatcher(redisRepo.NewWebhookRepository(rdb, keyspace), &http.Client{Timeout: 10 * time.Second})
+	go dispatcher.Run(ctx)
+
+	return urlHandler.NewHandler(service, handlerOptions...), urlHandler.NewWebhookHandler(webhookService)
+}